@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"tgbot/internal/access"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/pkg/types"
+)
+
+// isChatAllowed проверяет, что использование бота в чате разрешено
+func isChatAllowed(chatID int64) bool {
+	return policy.ChatAllowed(chatID)
+}
+
+// hasAccess проверяет, что у пользователя есть роль в разрешенном чате
+func hasAccess(userID, chatID int64) bool {
+	return isChatAllowed(chatID) && policy.Role(userID, chatID) != access.RoleNone
+}
+
+// can проверяет, что у пользователя есть право permission в чате. Общедоступные
+// действия разрешены везде, остальные — только в разрешенных чатах
+func can(userID, chatID int64, permission access.Permission) bool {
+	if permission == access.PermissionPublic {
+		return true
+	}
+	return isChatAllowed(chatID) && policy.Can(userID, chatID, permission)
+}
+
+// authorizeCommand проверяет право отправителя на выполнение команды
+func authorizeCommand(message *types.Message, permission access.Permission) bool {
+	return can(message.UserID, message.ChatID, permission)
+}
+
+// authorizeCallback проверяет право нажавшего кнопку пользователя на действие
+func authorizeCallback(callback *types.CallbackQuery, permission access.Permission) bool {
+	return can(callback.UserID, callback.ChatID, permission)
+}
+
+// accessDeniedText возвращает причину отказа в доступе
+func accessDeniedText(userID, chatID int64) string {
+	role := policy.Role(userID, chatID)
+	switch {
+	case role == access.RoleNone:
+		return "У вас нет доступа к этому боту."
+	case !isChatAllowed(chatID):
+		return "Этот чат не разрешен для использования бота."
+	default:
+		return fmt.Sprintf("Недостаточно прав для этого действия. Ваша роль: %s.", role.Title())
+	}
+}
+
+// denyAccess сообщает отправителю сообщения причину отказа в доступе
+func denyAccess(ctx context.Context, message *types.Message) {
+	text := accessDeniedText(message.UserID, message.ChatID)
+	if err := api.SendMessage(ctx, message.ChatID, render(format.Plain(text)), nil); err != nil {
+		bot.Logf(ctx, "Ошибка отправки сообщения: %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/pkg/types"
+)
+
+// targetArg аргумент команд управления доступом: пользователь или чат
+var targetArg = bot.Arg{
+	Name:        "кто",
+	Description: "@username, ID пользователя или chat (текущий чат); вместо пользователя можно ответить командой на его сообщение",
+}
+
+// registerAdminCommands регистрирует команды управления доступом
+func registerAdminCommands(router *bot.CommandRouter) {
+	router.Register(bot.Command{
+		Name:        "allow",
+		Description: "разрешить доступ пользователю или чату",
+		Descriptions: map[string]string{
+			"en": "grant access to a user or chat",
+		},
+		Args: []bot.Arg{
+			targetArg,
+			{Name: "роль", Description: "роль пользователя (" + roleNames() + ") или ID чата для chat"},
+		},
+		Permission: access.PermissionAdmin,
+		Handler:    handleAllowCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "revoke",
+		Description: "отозвать доступ пользователя или чата",
+		Descriptions: map[string]string{
+			"en": "revoke access of a user or chat",
+		},
+		Args: []bot.Arg{
+			targetArg,
+			{Name: "ID чата", Description: "ID чата для chat, если не текущий"},
+		},
+		Permission: access.PermissionAdmin,
+		Handler:    handleRevokeCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "role",
+		Description: "показать или изменить роль пользователя",
+		Descriptions: map[string]string{
+			"en": "show or change a user's role",
+		},
+		Args: []bot.Arg{
+			{Name: "кто", Description: targetArg.Description},
+			{Name: "роль", Description: "новая роль: " + roleNames()},
+			{Name: "чат", Description: "«здесь» или ID чата, если роль действует только в одном чате"},
+		},
+		Permission: access.PermissionAdmin,
+		Handler:    handleRoleCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "invite",
+		Description: "создать одноразовую ссылку-приглашение",
+		Descriptions: map[string]string{
+			"en": "create a single-use invite link",
+		},
+		Args: []bot.Arg{
+			{Name: "роль", Description: "роль приглашенного: " + roleNames()},
+			{Name: "часы", Description: "срок действия ссылки в часах", Validate: validateInviteHours},
+		},
+		Permission: access.PermissionAdmin,
+		Handler:    handleInviteCommand,
+	})
+}
+
+// maxInviteHours максимальный срок действия приглашения
+const maxInviteHours = 30 * 24
+
+// validateInviteHours проверяет срок действия приглашения
+func validateInviteHours(value string) error {
+	hours, err := strconv.Atoi(value)
+	if err != nil || hours <= 0 || hours > maxInviteHours {
+		return fmt.Errorf("укажите число часов от 1 до %d", maxInviteHours)
+	}
+	return nil
+}
+
+// roleNames возвращает имена ролей через запятую
+func roleNames() string {
+	var names []string
+	for _, role := range access.Roles() {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, ", ")
+}
+
+// accessTarget пользователь или чат, к которому применяется команда управления доступом
+type accessTarget struct {
+	userID int64
+	chatID int64
+	isChat bool
+}
+
+// resolveTarget определяет пользователя или чат команды и возвращает остальные аргументы.
+// Пользователь берется из сообщения, на которое отвечает команда (для пересланного
+// сообщения — его автор; ответы на сообщения ботов не учитываются), или из первого аргумента
+func resolveTarget(ctx context.Context, request *bot.CommandRequest) (accessTarget, []string, error) {
+	values, err := bot.SplitArgs(request.RawArgs)
+	if err != nil {
+		return accessTarget{}, nil, &bot.UsageError{Command: request.Command, Reason: "Ошибка в аргументах: " + err.Error()}
+	}
+
+	if reply := request.Message.ReplyTo; reply != nil && (reply.Forwarded || reply.From != nil && !reply.From.IsBot) {
+		user := reply.From
+		if reply.Forwarded {
+			user = reply.ForwardFrom
+		}
+		if user == nil {
+			return accessTarget{}, nil, &bot.UsageError{Command: request.Command, Reason: "Автор пересланного сообщения скрыл свой аккаунт, укажите его ID"}
+		}
+		rememberUser(ctx, user)
+		return accessTarget{userID: user.ID}, values, nil
+	}
+
+	if len(values) == 0 {
+		return accessTarget{}, nil, &bot.UsageError{Command: request.Command, Reason: "Укажите пользователя или ответьте командой на его сообщение"}
+	}
+
+	first, rest := values[0], values[1:]
+	switch {
+	case first == "chat" || first == "чат":
+		target := accessTarget{chatID: request.Message.ChatID, isChat: true}
+		if len(rest) > 0 {
+			chatID, err := strconv.ParseInt(rest[0], 10, 64)
+			if err != nil {
+				return accessTarget{}, nil, &bot.UsageError{Command: request.Command, Reason: "Некорректный ID чата: " + rest[0]}
+			}
+			target.chatID, rest = chatID, rest[1:]
+		}
+		return target, rest, nil
+	case strings.HasPrefix(first, "@"):
+		userID, ok := accessStore.LookupUsername(first)
+		if !ok {
+			return accessTarget{}, nil, &bot.UsageError{
+				Command: request.Command,
+				Reason:  fmt.Sprintf("Пользователь %s еще не писал боту: попросите его отправить боту любое сообщение или укажите ID", first),
+			}
+		}
+		return accessTarget{userID: userID}, rest, nil
+	default:
+		userID, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || userID <= 0 {
+			return accessTarget{}, nil, &bot.UsageError{Command: request.Command, Reason: "Некорректный пользователь: " + first}
+		}
+		return accessTarget{userID: userID}, rest, nil
+	}
+}
+
+// userTitle возвращает имя пользователя для сообщений: @username и ID, если username известен
+func userTitle(userID int64) string {
+	if username := accessStore.Username(userID); username != "" {
+		return fmt.Sprintf("@%s (%d)", username, userID)
+	}
+	return strconv.FormatInt(userID, 10)
+}
+
+func handleAllowCommand(ctx context.Context, request *bot.CommandRequest) error {
+	target, rest, err := resolveTarget(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	if target.isChat {
+		if len(rest) > 0 {
+			return &bot.UsageError{Command: request.Command, Reason: "Роль назначается пользователю, а не чату"}
+		}
+		if err := accessStore.SetChat(target.chatID, true); err != nil {
+			return err
+		}
+		event := audit.Event{Action: audit.ActionChatAllow, Target: chatTarget(target.chatID)}
+		return replyAccessChanged(ctx, request, event, fmt.Sprintf("✅ Чат %d разрешен", target.chatID))
+	}
+
+	role := policy.DefaultRole()
+	if len(rest) > 0 {
+		if role, err = access.ParseRole(rest[0]); err != nil {
+			return &bot.UsageError{Command: request.Command, Reason: err.Error()}
+		}
+	}
+	if len(rest) > 1 {
+		return &bot.UsageError{Command: request.Command, Reason: "Слишком много аргументов"}
+	}
+	if target.userID == request.Message.UserID {
+		return &bot.UsageError{Command: request.Command, Reason: "Нельзя изменить собственный доступ"}
+	}
+
+	if err := accessStore.SetRole(target.userID, 0, role); err != nil {
+		return err
+	}
+	event := audit.Event{Action: audit.ActionAccessAllow, Target: userTitle(target.userID), Details: string(role)}
+	return replyAccessChanged(ctx, request, event, fmt.Sprintf("✅ Пользователю %s разрешен доступ, роль: %s", userTitle(target.userID), role.Title()))
+}
+
+func handleRevokeCommand(ctx context.Context, request *bot.CommandRequest) error {
+	target, rest, err := resolveTarget(ctx, request)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return &bot.UsageError{Command: request.Command, Reason: "Слишком много аргументов"}
+	}
+
+	if target.isChat {
+		if err := accessStore.SetChat(target.chatID, false); err != nil {
+			return err
+		}
+		event := audit.Event{Action: audit.ActionChatRevoke, Target: chatTarget(target.chatID)}
+		return replyAccessChanged(ctx, request, event, fmt.Sprintf("🚫 Доступ чата %d отозван", target.chatID))
+	}
+
+	if target.userID == request.Message.UserID {
+		return &bot.UsageError{Command: request.Command, Reason: "Нельзя отозвать собственный доступ"}
+	}
+	if err := accessStore.Revoke(target.userID); err != nil {
+		return err
+	}
+	event := audit.Event{Action: audit.ActionAccessRevoke, Target: userTitle(target.userID)}
+	return replyAccessChanged(ctx, request, event, fmt.Sprintf("🚫 Доступ пользователя %s отозван", userTitle(target.userID)))
+}
+
+func handleRoleCommand(ctx context.Context, request *bot.CommandRequest) error {
+	target, rest, err := resolveTarget(ctx, request)
+	if err != nil {
+		return err
+	}
+	if target.isChat {
+		return &bot.UsageError{Command: request.Command, Reason: "Роль назначается пользователю, а не чату"}
+	}
+
+	// Без роли показываем текущую роль пользователя в этом чате
+	if len(rest) == 0 {
+		role := policy.Role(target.userID, request.Message.ChatID)
+		text := format.New().Line(format.Textf("Роль пользователя %s в этом чате: %s", userTitle(target.userID), role.Title()))
+		if invitation, ok := accessStore.InvitedBy(target.userID); ok {
+			text.Line(format.Textf("Приглашен пользователем %s %s", userTitle(invitation.InvitedBy), invitation.At.Local().Format("02.01.2006 15:04")))
+		}
+		return api.SendMessage(ctx, request.Message.ChatID, render(text), nil)
+	}
+	if len(rest) > 2 {
+		return &bot.UsageError{Command: request.Command, Reason: "Слишком много аргументов"}
+	}
+
+	role, err := access.ParseRole(rest[0])
+	if err != nil {
+		return &bot.UsageError{Command: request.Command, Reason: err.Error()}
+	}
+
+	var chatID int64
+	if len(rest) > 1 {
+		if rest[1] == "здесь" || rest[1] == "here" {
+			chatID = request.Message.ChatID
+		} else if chatID, err = strconv.ParseInt(rest[1], 10, 64); err != nil || chatID == 0 {
+			return &bot.UsageError{Command: request.Command, Reason: "Некорректный ID чата: " + rest[1]}
+		}
+	}
+	if target.userID == request.Message.UserID {
+		return &bot.UsageError{Command: request.Command, Reason: "Нельзя изменить собственную роль"}
+	}
+
+	if err := accessStore.SetRole(target.userID, chatID, role); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("✅ Пользователю %s назначена роль: %s", userTitle(target.userID), role.Title())
+	event := audit.Event{Action: audit.ActionAccessRole, Target: userTitle(target.userID), Details: string(role)}
+	if chatID != 0 {
+		text += fmt.Sprintf(" (в чате %d)", chatID)
+		event.Details += fmt.Sprintf(" в чате %d", chatID)
+	}
+	return replyAccessChanged(ctx, request, event, text)
+}
+
+// replyAccessChanged записывает изменение доступа в журнал аудита, сообщает о нем
+// и обновляет меню команд, которое зависит от ролей пользователей
+func replyAccessChanged(ctx context.Context, request *bot.CommandRequest, event audit.Event, text string) error {
+	bot.Logf(ctx, "Пользователь %d изменил доступ: %s", request.Message.UserID, text)
+	event.UserID, event.ChatID = request.Message.UserID, request.Message.ChatID
+	recordAudit(ctx, event)
+	go syncCommands(context.WithoutCancel(ctx))
+	return api.SendMessage(ctx, request.Message.ChatID, render(format.Plain(text)), nil)
+}
+
+// chatTarget возвращает объект записи журнала аудита для чата
+func chatTarget(chatID int64) string {
+	return fmt.Sprintf("чат %d", chatID)
+}
+
+// rememberUser запоминает username пользователя для команд управления доступом
+func rememberUser(ctx context.Context, user *types.User) {
+	if err := accessStore.RememberUser(user); err != nil {
+		bot.Logf(ctx, "Ошибка сохранения имени пользователя: %v", err)
+	}
+}
+
+// handleForwardedMessage показывает администратору автора пересланного сообщения
+// и его роль, чтобы ответом на сообщение можно было изменить доступ
+func handleForwardedMessage(ctx context.Context, message *types.Message) bool {
+	if !message.Forwarded || !can(message.UserID, message.ChatID, access.PermissionAdmin) {
+		return false
+	}
+
+	text := format.New()
+	if user := message.ForwardFrom; user != nil {
+		rememberUser(ctx, user)
+		text.Line(format.Textf("Автор сообщения: %s", userTitle(user.ID))).
+			Line(format.Textf("Роль в этом чате: %s", policy.Role(user.ID, message.ChatID).Title())).
+			Blank().
+			Line(format.Italic("Ответьте на пересланное сообщение командой /allow, /revoke или /role, чтобы изменить доступ."))
+	} else {
+		text.Line(format.Text("Автор сообщения скрыл свой аккаунт, укажите его ID в команде /allow."))
+	}
+
+	if err := api.SendMessage(ctx, message.ChatID, render(text), nil); err != nil {
+		bot.Logf(ctx, "Ошибка отправки сообщения: %v", err)
+	}
+	return true
+}
+
+func handleInviteCommand(ctx context.Context, request *bot.CommandRequest) error {
+	if botUsername == "" {
+		return fmt.Errorf("имя бота неизвестно, ссылку-приглашение создать нельзя")
+	}
+
+	role := policy.DefaultRole()
+	if name := request.Arg("роль"); name != "" {
+		var err error
+		if role, err = access.ParseRole(name); err != nil {
+			return &bot.UsageError{Command: request.Command, Reason: err.Error()}
+		}
+	}
+	ttl := access.DefaultInviteTTL
+	if hours := request.Arg("часы"); hours != "" {
+		value, _ := strconv.Atoi(hours)
+		ttl = time.Duration(value) * time.Hour
+	}
+
+	// Приглашение, созданное в группе, дает роль только в этой группе
+	var chatID int64
+	if request.Message.ChatID != request.Message.UserID {
+		chatID = request.Message.ChatID
+	}
+
+	invite, err := accessStore.CreateInvite(role, request.Message.UserID, chatID, ttl)
+	if err != nil {
+		return err
+	}
+	bot.Logf(ctx, "Пользователь %d создал приглашение с ролью %s", request.Message.UserID, role)
+	details := fmt.Sprintf("%s до %s", role, invite.ExpiresAt.Local().Format("02.01.2006 15:04"))
+	if chatID != 0 {
+		details += fmt.Sprintf(" в чате %d", chatID)
+	}
+	recordAudit(ctx, audit.Event{
+		UserID:  request.Message.UserID,
+		ChatID:  request.Message.ChatID,
+		Action:  audit.ActionInviteCreate,
+		Details: details,
+	})
+
+	scope := "во всех разрешенных чатах"
+	if chatID != 0 {
+		scope = "в этой группе и в личном чате с ботом"
+	}
+	link := fmt.Sprintf("https://t.me/%s?start=%s", botUsername, invite.Token)
+	text := format.New().
+		Line(format.Bold("✉️ Приглашение")).
+		Blank().
+		Item(format.Textf("Роль: %s, %s", role.Title(), scope)).
+		Item(format.Textf("Действует до: %s", invite.ExpiresAt.Local().Format("02.01.2006 15:04"))).
+		Blank().
+		Line(format.Link(link, link)).
+		Line(format.Italic("Ссылка одноразовая: передайте ее только приглашаемому."))
+	return api.SendMessage(ctx, request.Message.ChatID, render(text), nil)
+}
+
+// redeemInvite выдает отправителю /start роль из приглашения и разрешает его личный чат с ботом
+func redeemInvite(ctx context.Context, message *types.Message, token string) error {
+	reply := func(text string) error {
+		return api.SendMessage(ctx, message.ChatID, render(format.Plain(text)), nil)
+	}
+
+	// Ссылка t.me/<бот>?start= открывает личный чат; в группе токен увидели бы все участники
+	if message.ChatID != message.UserID {
+		return reply("Откройте ссылку-приглашение в личном чате с ботом.")
+	}
+	// Приглашение не должно понижать роль пользователя, у которого уже есть доступ
+	if role := policy.Role(message.UserID, message.ChatID); role != access.RoleNone {
+		if err := reply(fmt.Sprintf("У вас уже есть доступ, ваша роль: %s.", role.Title())); err != nil {
+			return err
+		}
+		if isChatAllowed(message.ChatID) {
+			return sendMainMenu(ctx, message)
+		}
+		return nil
+	}
+
+	invite, err := accessStore.RedeemInvite(token, message.UserID)
+	if errors.Is(err, access.ErrInviteInvalid) {
+		bot.Logf(ctx, "Пользователь %d использовал недействительное приглашение", message.UserID)
+		return reply("Приглашение недействительно: срок его действия истек или оно уже использовано.")
+	}
+	if err != nil {
+		return err
+	}
+	if err := accessStore.SetChat(message.ChatID, true); err != nil {
+		return err
+	}
+	rememberUser(ctx, message.From)
+
+	bot.Logf(ctx, "Пользователь %d получил роль %s по приглашению пользователя %d", message.UserID, invite.Role, invite.CreatedBy)
+	details := string(invite.Role)
+	if invite.ChatID != 0 {
+		details += fmt.Sprintf(" в чате %d", invite.ChatID)
+	}
+	recordAudit(ctx, audit.Event{
+		UserID:  message.UserID,
+		ChatID:  message.ChatID,
+		Action:  audit.ActionInviteRedeem,
+		Target:  fmt.Sprintf("приглашение %s", userTitle(invite.CreatedBy)),
+		Details: details,
+	})
+	go syncCommands(context.WithoutCancel(ctx))
+
+	// Сообщаем пригласившему, что приглашение принято
+	notice := fmt.Sprintf("✅ Пользователь %s принял приглашение, роль: %s", userTitle(message.UserID), invite.Role.Title())
+	if err := api.SendMessage(ctx, invite.CreatedBy, render(format.Plain(notice)), nil); err != nil {
+		bot.Logf(ctx, "Ошибка уведомления пригласившего пользователя: %v", err)
+	}
+
+	if err := reply(fmt.Sprintf("👋 Добро пожаловать! Ваша роль: %s.", invite.Role.Title())); err != nil {
+		return err
+	}
+	return sendMainMenu(ctx, message)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/internal/storage"
+	"tgbot/pkg/types"
+)
+
+// releasePlansBucket бакет, в котором хранятся описания релизов, ожидающих одобрения
+const releasePlansBucket = "release_plans"
+
+// releasePlans описания релизов, ожидающих одобрения, по идентификатору запроса.
+// Хранятся вместе с запросами, чтобы карточка после перезапуска бота не теряла описание
+var releasePlans *storage.Repository[savedReleasePlan]
+
+// savedReleasePlan описание релиза, которое показывается в карточке запроса
+type savedReleasePlan struct {
+	Version    string `json:"version"`
+	AheadBy    int    `json:"ahead_by"`
+	CompareURL string `json:"compare_url"`
+}
+
+// releaseChatID возвращает чат для запросов на одобрение релиза
+func releaseChatID(initiatorChatID int64) int64 {
+	if config().ReleaseChatID != 0 {
+		return config().ReleaseChatID
+	}
+	return initiatorChatID
+}
+
+// requestReleaseApproval отправляет в чат релизов карточку запроса на одобрение релиза.
+// Пайплайн запускается, когда запрос одобрят config().ReleaseApprovals релиз-менеджеров
+func requestReleaseApproval(ctx context.Context, target releaseTarget) {
+	keyboard := [][]types.InlineKeyboardButton{backToMainRow()}
+
+	plan, err := loadReleasePlan(ctx)
+	if err != nil {
+		target.edit(ctx, format.Plain(fmt.Sprintf("❌ Ошибка получения изменений для релиза: %v", err)), keyboard)
+		return
+	}
+
+	approval, err := approvals.Start(target.userID, config().ReleaseApprovals)
+	if err != nil {
+		bot.Logf(ctx, "Ошибка создания запроса на одобрение релиза: %v", err)
+		target.edit(ctx, format.Plain("❌ Не удалось создать запрос на одобрение релиза."), keyboard)
+		return
+	}
+	saved := savedReleasePlan{Version: plan.version, AheadBy: plan.comparison.AheadBy, CompareURL: plan.comparison.HTMLURL}
+	if err := releasePlans.Put(approval.ID, saved); err != nil {
+		bot.Logf(ctx, "Ошибка сохранения описания релиза %s: %v", approval.ID, err)
+	}
+
+	card, cardKeyboard := approvalCard(approval, plan, "")
+	chatID := releaseChatID(target.chatID)
+	if chatID == target.chatID {
+		// Диалог подтверждения превращается в карточку запроса
+		target.edit(ctx, card, cardKeyboard)
+		if err := approvals.SetMessage(approval.ID, target.chatID, target.messageID); err != nil {
+			bot.Logf(ctx, "%v", err)
+		}
+	} else {
+		messageID, err := api.SendMessageID(ctx, chatID, render(card), cardKeyboard)
+		if err != nil {
+			bot.Logf(ctx, "Ошибка отправки запроса на одобрение релиза: %v", err)
+			approvals.Expire(approval.ID)
+			forgetReleasePlan(approval.ID)
+			recordAudit(ctx, audit.Event{
+				UserID: target.userID,
+				ChatID: target.chatID,
+				Action: audit.ActionReleaseRequest,
+				Target: approval.ID,
+				Error:  auditError(err),
+			})
+			target.edit(ctx, format.Plain("❌ Не удалось отправить запрос в чат релизов."), keyboard)
+			return
+		}
+		if err := approvals.SetMessage(approval.ID, chatID, messageID); err != nil {
+			bot.Logf(ctx, "%v", err)
+		}
+		target.edit(ctx, format.Plain("🗳 Запрос на одобрение релиза отправлен в чат релизов."), keyboard)
+	}
+	bot.Logf(ctx, "Пользователь %d запросил одобрение релиза %s (нужно одобрений: %d)", target.userID, approval.ID, approval.Required)
+	recordAudit(ctx, audit.Event{
+		UserID:  target.userID,
+		ChatID:  target.chatID,
+		Action:  audit.ActionReleaseRequest,
+		Target:  approval.ID,
+		Details: fmt.Sprintf("версия %s, нужно одобрений: %d", plan.version, approval.Required),
+	})
+
+	scheduleApprovalExpiry(ctx, approval)
+}
+
+// scheduleApprovalExpiry отменяет запрос по истечении срока и обновляет карточку
+func scheduleApprovalExpiry(ctx context.Context, approval bot.Approval) {
+	background := context.WithoutCancel(ctx)
+	time.AfterFunc(time.Until(approval.ExpiresAt), func() {
+		expireReleaseApproval(background, approval.ID)
+	})
+}
+
+// resumeReleaseApprovals продолжает запросы на одобрение, ожидавшие голосов до
+// перезапуска бота: назначает их отмену, а истекшие отменяет сразу
+func resumeReleaseApprovals(ctx context.Context) error {
+	pending, err := approvals.Load()
+	if err != nil {
+		return err
+	}
+	for _, approval := range pending {
+		scheduleApprovalExpiry(ctx, approval)
+	}
+	if len(pending) > 0 {
+		log.Printf("Восстановлено запросов на одобрение релиза: %d", len(pending))
+	}
+	return nil
+}
+
+// approvalCard формирует карточку запроса на одобрение релиза; result — итог запуска
+// пайплайна для одобренного запроса
+func approvalCard(approval bot.Approval, plan *releasePlan, result string) (*format.Message, [][]types.InlineKeyboardButton) {
+	message := format.New().Line(format.Bold("🗳 Запрос на создание релиза")).Blank()
+	if plan != nil {
+		plan.writeSummary(message)
+	}
+	message.
+		Item(format.Textf("Инициатор: %s", userTitle(approval.InitiatorID))).
+		Item(format.Textf("Нужно одобрений: %d", approval.Required)).
+		Item(format.Textf("Срок рассмотрения: до %s", approval.ExpiresAt.Format("02.01.2006 15:04")))
+
+	message.Blank().Line(format.Bold("Голоса:"))
+	if len(approval.Votes) == 0 {
+		message.Line(format.Italic("пока нет"))
+	}
+	for _, vote := range approval.Votes {
+		mark := "✅"
+		if !vote.Approve {
+			mark = "❌"
+		}
+		message.Line(format.Textf("%s %s — %s", mark, userTitle(vote.UserID), vote.At.Format("15:04")))
+	}
+
+	message.Blank()
+	var keyboard [][]types.InlineKeyboardButton
+	switch approval.State {
+	case bot.ApprovalPending:
+		message.Line(format.Boldf("Одобрено: %d из %d", approval.Approved(), approval.Required))
+		keyboard = [][]types.InlineKeyboardButton{{
+			callbacks.Button("✅ Одобрить", actionReleaseApprove, approval.ID),
+			callbacks.Button("❌ Отклонить", actionReleaseReject, approval.ID),
+		}}
+		if plan != nil {
+			keyboard = append(keyboard, []types.InlineKeyboardButton{{Text: "🔗 Изменения на GitHub", URL: plan.comparison.HTMLURL}})
+		}
+	case bot.ApprovalApproved:
+		message.Line(format.Bold("✅ Релиз одобрен"))
+	case bot.ApprovalRejected:
+		message.Line(format.Bold("❌ Релиз отклонен"))
+	case bot.ApprovalExpired:
+		message.Line(format.Bold("⌛ Время на одобрение истекло, релиз отменен"))
+	}
+	if result != "" {
+		message.Line(format.Text(result))
+	}
+	return message, keyboard
+}
+
+func handleReleaseApprove(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	handleReleaseVote(ctx, callback, data.Arg(0), true)
+}
+
+func handleReleaseReject(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	handleReleaseVote(ctx, callback, data.Arg(0), false)
+}
+
+// handleReleaseVote учитывает голос релиз-менеджера, обновляет карточку и запускает
+// пайплайн, когда набрано нужное число одобрений
+func handleReleaseVote(ctx context.Context, callback *types.CallbackQuery, id string, approve bool) {
+	approval, err := approvals.Vote(id, callback.UserID, approve)
+	if err != nil {
+		if err := api.ShowAlert(ctx, callback.ID, "⛔ "+err.Error()); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		return
+	}
+	bot.Logf(ctx, "Пользователь %d %s релиз %s (одобрено %d из %d)", callback.UserID, voteVerb(approve), id, approval.Approved(), approval.Required)
+	recordAudit(ctx, audit.Event{
+		UserID:  callback.UserID,
+		ChatID:  callback.ChatID,
+		Action:  audit.ActionReleaseVote,
+		Target:  id,
+		Details: fmt.Sprintf("%s (одобрено %d из %d)", voteVerb(approve), approval.Approved(), approval.Required),
+	})
+
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Голос учтен"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+
+	plan := releasePlanFor(id)
+	result := ""
+	switch approval.State {
+	case bot.ApprovalPending:
+		card, keyboard := approvalCard(approval, plan, "")
+		editMessage(ctx, callback, card, keyboard)
+		return
+	case bot.ApprovalApproved:
+		result = releaseStartedText
+		err := triggerRelease()
+		recordAudit(ctx, audit.Event{
+			UserID:  callback.UserID,
+			ChatID:  callback.ChatID,
+			Action:  audit.ActionReleaseDispatch,
+			Target:  releaseWorkflow,
+			Details: fmt.Sprintf("запрос %s пользователя %d одобрен", id, approval.InitiatorID),
+			Error:   auditError(err),
+		})
+		if err != nil {
+			bot.Logf(ctx, "Ошибка запуска пайплайна: %v", err)
+			result = "❌ Ошибка: пайплайн не настроен для ручного запуска"
+		} else {
+			bot.Logf(ctx, "Релиз %s одобрен, пайплайн запущен", id)
+		}
+	}
+
+	forgetReleasePlan(id)
+	card, keyboard := approvalCard(approval, plan, result)
+	editMessage(ctx, callback, card, keyboard)
+}
+
+// expireReleaseApproval отменяет запрос, не набравший одобрений за отведенное время
+func expireReleaseApproval(ctx context.Context, id string) {
+	approval, ok := approvals.Expire(id)
+	if !ok {
+		return
+	}
+	plan := releasePlanFor(id)
+	forgetReleasePlan(id)
+	log.Printf("Время на одобрение релиза %s истекло (одобрено %d из %d)", id, approval.Approved(), approval.Required)
+	recordAudit(ctx, audit.Event{
+		ChatID:  approval.ChatID,
+		Action:  audit.ActionReleaseExpire,
+		Target:  id,
+		Details: fmt.Sprintf("одобрено %d из %d", approval.Approved(), approval.Required),
+	})
+
+	card, keyboard := approvalCard(approval, plan, "")
+	if err := api.EditMessageText(ctx, approval.ChatID, approval.MessageID, render(card), keyboard); err != nil {
+		log.Printf("Ошибка обновления карточки запроса на одобрение: %v", err)
+	}
+}
+
+// voteVerb возвращает глагол для записи голоса в лог
+func voteVerb(approve bool) string {
+	if approve {
+		return "одобрил"
+	}
+	return "отклонил"
+}
+
+// releasePlanFor возвращает описание релиза, ожидающего одобрения
+func releasePlanFor(id string) *releasePlan {
+	saved, err := releasePlans.Get(id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Ошибка чтения описания релиза %s: %v", id, err)
+		}
+		return nil
+	}
+	return &releasePlan{
+		version:    saved.Version,
+		comparison: &types.Comparison{AheadBy: saved.AheadBy, HTMLURL: saved.CompareURL},
+	}
+}
+
+// forgetReleasePlan удаляет описание рассмотренного релиза
+func forgetReleasePlan(id string) {
+	if err := releasePlans.Delete(id); err != nil {
+		log.Printf("Ошибка удаления описания релиза %s: %v", id, err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/internal/telegram"
+)
+
+// auditPageSize сколько последних записей показывает /audit
+const auditPageSize = 20
+
+// auditDateLayouts форматы дат в фильтрах /audit
+var auditDateLayouts = []string{"2006-01-02", "02.01.2006"}
+
+// registerAuditCommands регистрирует команду просмотра журнала аудита
+func registerAuditCommands(router *bot.CommandRouter) {
+	router.Register(bot.Command{
+		Name:        "audit",
+		Description: "журнал действий: релизы, удаление веток, закрытие PR, изменения доступа",
+		Descriptions: map[string]string{
+			"en": "audit log of privileged actions",
+		},
+		Args: []bot.Arg{{
+			Name:        "фильтры",
+			Description: "user=@username|ID, action=release|pr.close|…, from=ГГГГ-ММ-ДД, to=ГГГГ-ММ-ДД; csv — выгрузить файлом, verify — проверить целостность",
+			Rest:        true,
+		}},
+		Permission: access.PermissionAdmin,
+		Handler:    handleAuditCommand,
+	})
+}
+
+// recordAudit записывает действие в журнал аудита; ошибка записи попадает в лог
+func recordAudit(ctx context.Context, event audit.Event) {
+	if _, err := auditLog.Append(event); err != nil {
+		bot.Logf(ctx, "Ошибка записи в журнал аудита: %v (%s пользователя %d)", err, event.Action, event.UserID)
+	}
+}
+
+// auditError возвращает текст ошибки для записи журнала; nil — пустая строка
+func auditError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// auditQuery разобранные аргументы /audit
+type auditQuery struct {
+	filter audit.Filter
+	csv    bool
+	verify bool
+}
+
+// parseAuditQuery разбирает фильтры /audit
+func parseAuditQuery(request *bot.CommandRequest) (auditQuery, error) {
+	usage := func(reason string) error {
+		return &bot.UsageError{Command: request.Command, Reason: reason}
+	}
+
+	values, err := bot.SplitArgs(request.RawArgs)
+	if err != nil {
+		return auditQuery{}, usage("Ошибка в аргументах: " + err.Error())
+	}
+
+	var query auditQuery
+	for _, value := range values {
+		switch value {
+		case "csv":
+			query.csv = true
+			continue
+		case "verify":
+			query.verify = true
+			continue
+		}
+
+		key, arg, ok := strings.Cut(value, "=")
+		if !ok || arg == "" {
+			return auditQuery{}, usage("Неизвестный фильтр: " + value)
+		}
+		switch key {
+		case "user":
+			userID, ok := lookupUser(arg)
+			if !ok {
+				return auditQuery{}, usage("Неизвестный пользователь: " + arg)
+			}
+			query.filter.UserID = userID
+		case "action":
+			query.filter.Action = strings.ToLower(arg)
+		case "from":
+			if query.filter.From, err = parseAuditDate(arg); err != nil {
+				return auditQuery{}, usage(err.Error())
+			}
+		case "to":
+			to, err := parseAuditDate(arg)
+			if err != nil {
+				return auditQuery{}, usage(err.Error())
+			}
+			// Дата окончания включается целиком
+			query.filter.To = to.AddDate(0, 0, 1)
+		default:
+			return auditQuery{}, usage("Неизвестный фильтр: " + key)
+		}
+	}
+	if !query.filter.From.IsZero() && !query.filter.To.IsZero() && !query.filter.From.Before(query.filter.To) {
+		return auditQuery{}, usage("Дата from должна быть не позже даты to")
+	}
+	return query, nil
+}
+
+// lookupUser находит пользователя по @username или ID
+func lookupUser(value string) (int64, bool) {
+	if strings.HasPrefix(value, "@") {
+		return accessStore.LookupUsername(value)
+	}
+	userID, err := strconv.ParseInt(value, 10, 64)
+	return userID, err == nil && userID > 0
+}
+
+// parseAuditDate разбирает дату фильтра в местном времени
+func parseAuditDate(value string) (time.Time, error) {
+	for _, layout := range auditDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Некорректная дата %s, используйте формат ГГГГ-ММ-ДД", value)
+}
+
+func handleAuditCommand(ctx context.Context, request *bot.CommandRequest) error {
+	query, err := parseAuditQuery(request)
+	if err != nil {
+		return err
+	}
+	chatID := request.Message.ChatID
+
+	if query.verify {
+		count, err := auditLog.Verify()
+		var chainErr *audit.ChainError
+		switch {
+		case errors.As(err, &chainErr):
+			bot.Logf(ctx, "Проверка журнала аудита: %v", err)
+			return api.SendMessage(ctx, chatID, render(format.Plain("⚠️ "+err.Error())), nil)
+		case err != nil:
+			return err
+		}
+		text := fmt.Sprintf("✅ Журнал аудита не изменялся: записей %d, цепочка хешей не нарушена.", count)
+		if err := api.SendMessage(ctx, chatID, render(format.Plain(text)), nil); err != nil {
+			return err
+		}
+		if !query.csv && query.filter == (audit.Filter{}) {
+			return nil
+		}
+	}
+
+	events, err := auditLog.Query(query.filter)
+	if err != nil {
+		return err
+	}
+
+	if query.csv {
+		var buf bytes.Buffer
+		if err := audit.WriteCSV(&buf, events); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("audit-%s.csv", time.Now().Format("2006-01-02-150405"))
+		caption := format.New().Line(format.Textf("Журнал аудита: записей %d", len(events)))
+		return api.SendDocument(ctx, chatID, telegram.InputFile{Name: name, Data: buf.Bytes()}, render(caption))
+	}
+
+	message := format.New().Line(format.Bold("📜 Журнал аудита")).Blank()
+	if len(events) == 0 {
+		message.Line(format.Italic("Записей не найдено."))
+		return api.SendMessage(ctx, chatID, render(message), nil)
+	}
+
+	// Показываем последние записи, новые сверху
+	for i := len(events) - 1; i >= 0 && i >= len(events)-auditPageSize; i-- {
+		writeAuditEvent(message, events[i])
+	}
+	if hidden := len(events) - auditPageSize; hidden > 0 {
+		message.Blank().Line(format.Italic(fmt.Sprintf("…и еще %d. Уточните фильтры или выгрузите записи: /audit csv", hidden)))
+	}
+	return api.SendLongMessage(ctx, chatID, render(message), nil)
+}
+
+// writeAuditEvent добавляет в сообщение строку с записью журнала
+func writeAuditEvent(message *format.Message, event audit.Event) {
+	who := "бот"
+	if event.UserID != 0 {
+		who = userTitle(event.UserID)
+	}
+
+	parts := []format.Node{
+		format.Textf("#%d %s %s ", event.Seq, event.Time.Local().Format("02.01.2006 15:04"), who),
+		format.Code(event.Action),
+	}
+	if event.Target != "" {
+		parts = append(parts, format.Textf(" %s", event.Target))
+	}
+	if event.Details != "" {
+		parts = append(parts, format.Textf(" — %s", event.Details))
+	}
+	if event.Failed() {
+		parts = append(parts, format.Textf(" ❌ %s", event.Error))
+	}
+	message.Item(parts...)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/pkg/types"
+)
+
+// Действия inline-кнопок
+const (
+	actionCreateRelease     = "create_release"
+	actionReleaseConfirm    = "release_confirm"
+	actionReleaseCancel     = "release_cancel"
+	actionReleaseApprove    = "release_approve"
+	actionReleaseReject     = "release_reject"
+	actionReleaseCodeCancel = "release_code_cancel"
+	actionMainMenu          = "back_to_main"
+	actionBranches          = "branches"
+	actionBranch            = "branch"
+	actionPRs               = "prs"
+	actionPR                = "pr"
+	actionReleases          = "releases"
+	actionRelease           = "release"
+	actionLatestRelease     = "show_latest_release"
+
+	actionDeleteBranch        = "branch_delete"
+	actionDeleteBranchConfirm = "branch_delete_confirm"
+	actionClosePR             = "pr_close"
+	actionClosePRConfirm      = "pr_close_confirm"
+)
+
+// sensitiveCallbackTTL срок действия кнопок, запускающих необратимые действия
+const sensitiveCallbackTTL = 15 * time.Minute
+
+// newCallbackRouter регистрирует обработчики inline-кнопок. Данные кнопок подписываются
+// секретом из конфигурации, поэтому подделанные нажатия отклоняются
+func newCallbackRouter() (*bot.CallbackRouter, error) {
+	secret := []byte(config().CallbackSecret)
+	if len(secret) == 0 {
+		log.Printf("Секрет callback_secret не задан, кнопки отправленных сообщений перестанут работать после перезапуска")
+		var err error
+		if secret, err = bot.NewRandomSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := time.Duration(config().CallbackTTLHours) * time.Hour
+	signer := bot.NewCallbackSigner(secret, ttl)
+	router := bot.NewCallbackRouter(bot.NewCallbackStore(signer.TTL()), signer, authorizeCallback)
+
+	router.Handle(access.PermissionView, handleMainMenu, actionMainMenu)
+	router.Handle(access.PermissionView, handleShowBranches, actionBranches)
+	router.Handle(access.PermissionView, handleShowBranch, actionBranch)
+	router.Handle(access.PermissionView, handleShowPRs, actionPRs)
+	router.Handle(access.PermissionView, handleShowPR, actionPR)
+	router.Handle(access.PermissionView, handleShowReleases, actionReleases)
+	router.Handle(access.PermissionView, handleShowRelease, actionRelease)
+	router.Handle(access.PermissionView, handleShowLatestRelease, actionLatestRelease)
+	router.Handle(access.PermissionDevelop, handleDeleteBranch, actionDeleteBranch)
+	router.Handle(access.PermissionDevelop, handleDeleteBranchConfirm, actionDeleteBranchConfirm)
+	router.Handle(access.PermissionDevelop, handleClosePR, actionClosePR)
+	router.Handle(access.PermissionDevelop, handleClosePRConfirm, actionClosePRConfirm)
+	router.Handle(access.PermissionRelease, handleReleaseCommand, actionCreateRelease)
+	router.Handle(access.PermissionRelease, handleReleaseConfirm, actionReleaseConfirm)
+	router.Handle(access.PermissionRelease, handleReleaseCancel, actionReleaseCancel)
+	router.Handle(access.PermissionRelease, handleReleaseApprove, actionReleaseApprove)
+	router.Handle(access.PermissionRelease, handleReleaseReject, actionReleaseReject)
+	router.Handle(access.PermissionRelease, handleReleaseCodeCancel, actionReleaseCodeCancel)
+
+	router.Expire(sensitiveCallbackTTL, actionCreateRelease)
+	router.Expire(confirmations.TTL(), actionReleaseConfirm, actionReleaseCodeCancel, actionDeleteBranchConfirm, actionClosePRConfirm)
+	router.Expire(approvals.TTL(), actionReleaseApprove, actionReleaseReject)
+
+	return router, nil
+}
+
+func handleMainMenu(ctx context.Context, callback *types.CallbackQuery, _ bot.CallbackData) {
+	if err := api.EditMessageText(ctx, callback.ChatID, callback.MessageID, render(format.Plain("Выберите действие:")), mainMenuKeyboard(callback.UserID, callback.ChatID)); err != nil {
+		bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/internal/storage"
+	"tgbot/internal/telegram"
+	"tgbot/pkg/types"
+)
+
+// newCommandRouter регистрирует команды бота
+func newCommandRouter() *bot.CommandRouter {
+	router := bot.NewCommandRouter(authorizeCommand)
+
+	router.Register(bot.Command{
+		Name:        "start",
+		Description: "показать главное меню",
+		Descriptions: map[string]string{
+			"en": "show the main menu",
+		},
+		Args: []bot.Arg{
+			{Name: "параметр", Description: "параметр из ссылки t.me/<бот>?start=<параметр>, например приглашение"},
+		},
+		Permission: access.PermissionPublic,
+		Handler:    handleStartCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "help",
+		Description: "показать это сообщение или справку по команде",
+		Descriptions: map[string]string{
+			"en": "show available commands or help for a command",
+		},
+		Args: []bot.Arg{
+			{Name: "команда", Description: "команда, по которой нужна справка"},
+		},
+		Permission: access.PermissionView,
+		Handler:    handleHelpCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "release",
+		Description: "запустить создание нового релиза (с подтверждением)",
+		Descriptions: map[string]string{
+			"en": "start a new release",
+		},
+		Permission: access.PermissionRelease,
+		Handler:    handleReleaseTextCommand,
+	})
+
+	router.Register(bot.Command{
+		Name:        "stats",
+		Description: "показать статистику обработки обновлений",
+		Descriptions: map[string]string{
+			"en": "show update processing statistics",
+		},
+		Permission: access.PermissionView,
+		Handler:    handleStatsCommand,
+	})
+
+	registerTOTPCommands(router)
+	registerAuditCommands(router)
+	registerAdminCommands(router)
+
+	return router
+}
+
+// commandLanguages языки меню команд; пустой код — список по умолчанию (на русском)
+var commandLanguages = []string{"", "ru", "en"}
+
+const (
+	// commandMembersBucket бакет, в котором хранятся участники групп с отдельным меню команд
+	commandMembersBucket = "commands"
+	// commandMembersKey ключ списка участников в бакете
+	commandMembersKey = "members"
+)
+
+// commandMembers участники групп, для которых опубликовано отдельное меню команд.
+// Хранятся, чтобы после понижения роли удалить ставшее ненужным меню, в том числе
+// если роль изменилась, пока бот был остановлен
+var commandMembers *storage.Repository[[]commandMember]
+
+// commandSyncMu не дает синхронизациям меню, запущенным после изменений доступа, выполняться одновременно
+var commandSyncMu sync.Mutex
+
+// commandMember участник группы с отдельным меню команд
+type commandMember struct {
+	ChatID int64 `json:"chat_id"`
+	UserID int64 `json:"user_id"`
+}
+
+// commandScope область меню команд и роль, команды которой в нее попадают
+type commandScope struct {
+	scope telegram.BotCommandScope
+	role  access.Role
+}
+
+// commandScopes возвращает области меню команд. Telegram показывает пользователю
+// меню самой узкой подходящей области, поэтому отдельное меню участника группы
+// публикуется, только если его роль дает больше команд, чем меню группы:
+//   - по умолчанию, во всех личных чатах и во всех группах — только общедоступные команды;
+//   - разрешенный личный чат — команды, доступные его владельцу;
+//   - разрешенная группа — команды младшей из ролей ее участников;
+//   - участник разрешенной группы со старшей ролью — команды его роли
+func commandScopes() []commandScope {
+	scopes := []commandScope{
+		{scope: telegram.ScopeDefault()},
+		{scope: telegram.ScopeAllPrivateChats()},
+		{scope: telegram.ScopeAllGroupChats()},
+	}
+
+	users := policy.Users()
+	for _, chatID := range policy.Chats() {
+		if chatID > 0 {
+			scopes = append(scopes, commandScope{
+				scope: telegram.ScopeChat(chatID),
+				role:  policy.Role(chatID, chatID),
+			})
+			continue
+		}
+
+		roles := make(map[int64]access.Role)
+		base := access.RoleNone
+		for _, userID := range users {
+			role := policy.Role(userID, chatID)
+			if role == access.RoleNone {
+				continue
+			}
+			roles[userID] = role
+			if base == access.RoleNone || role.Rank() < base.Rank() {
+				base = role
+			}
+		}
+
+		scopes = append(scopes, commandScope{scope: telegram.ScopeChat(chatID), role: base})
+		for _, userID := range users {
+			if role, ok := roles[userID]; ok && role.Rank() > base.Rank() {
+				scopes = append(scopes, commandScope{
+					scope: telegram.ScopeChatMember(chatID, userID),
+					role:  role,
+				})
+			}
+		}
+	}
+
+	return scopes
+}
+
+// publishedCommandMembers возвращает участников, меню которых опубликовала предыдущая
+// синхронизация. До первой синхронизации считается, что меню есть у каждого
+// пользователя с ролью в каждой разрешенной группе, как публиковали прежние версии бота
+func publishedCommandMembers() []commandMember {
+	members, err := commandMembers.Get(commandMembersKey)
+	if err == nil {
+		return members
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Ошибка чтения опубликованных меню команд: %v", err)
+		return nil
+	}
+
+	for _, chatID := range policy.Chats() {
+		if chatID > 0 {
+			continue
+		}
+		for _, userID := range policy.Users() {
+			members = append(members, commandMember{ChatID: chatID, UserID: userID})
+		}
+	}
+	return members
+}
+
+// syncCommands публикует меню команд в Telegram для каждой области и языка и удаляет
+// меню участников, роль которых больше не дает дополнительных команд. Ошибки отдельных
+// областей (например, пользователь не состоит в группе) не прерывают синхронизацию
+func syncCommands(ctx context.Context) {
+	commandSyncMu.Lock()
+	defer commandSyncMu.Unlock()
+
+	failed := 0
+	scopes := commandScopes()
+	published := make(map[commandMember]bool)
+	var members []commandMember
+	for _, scope := range scopes {
+		if scope.scope.Type == telegram.ScopeTypeChatMember {
+			member := commandMember{ChatID: scope.scope.ChatID, UserID: scope.scope.UserID}
+			published[member] = true
+			members = append(members, member)
+		}
+		for _, language := range commandLanguages {
+			botCommands := commands.RoleBotCommands(scope.role, language)
+			if err := api.SetMyCommands(ctx, botCommands, scope.scope, language); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Ошибка синхронизации меню команд: %v", err)
+				failed++
+			}
+		}
+	}
+
+	removed := 0
+	for _, member := range publishedCommandMembers() {
+		if published[member] {
+			continue
+		}
+		removed++
+		for _, language := range commandLanguages {
+			if err := api.DeleteMyCommands(ctx, telegram.ScopeChatMember(member.ChatID, member.UserID), language); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Ошибка удаления меню команд: %v", err)
+				failed++
+			}
+		}
+	}
+	if err := commandMembers.Put(commandMembersKey, members); err != nil {
+		log.Printf("Ошибка сохранения опубликованных меню команд: %v", err)
+	}
+
+	log.Printf("Меню команд синхронизировано: областей %d, удалено меню участников %d, ошибок %d", len(scopes), removed, failed)
+}
+
+// replyCommandError сообщает пользователю об ошибке выполнения команды
+func replyCommandError(ctx context.Context, message *types.Message, err error) {
+	var usageErr *bot.UsageError
+	var text string
+
+	switch {
+	case errors.As(err, &usageErr):
+		text = "⚠️ " + usageErr.Error()
+	case errors.Is(err, bot.ErrForbidden):
+		denyAccess(ctx, message)
+		return
+	case errors.Is(err, bot.ErrUnknownCommand):
+		// Не подсказываем список команд тем, кому бот недоступен
+		if !hasAccess(message.UserID, message.ChatID) {
+			denyAccess(ctx, message)
+			return
+		}
+		text = "Неизвестная команда. Список команд: /help"
+	default:
+		bot.Logf(ctx, "Ошибка выполнения команды: %v", err)
+		text = "❌ Не удалось выполнить команду"
+	}
+
+	if err := api.SendMessage(ctx, message.ChatID, render(format.Plain(text)), nil); err != nil {
+		bot.Logf(ctx, "Ошибка отправки сообщения: %v", err)
+	}
+}
+
+// handleStartCommand показывает главное меню, а если команда пришла по ссылке-приглашению —
+// сначала выдает пользователю роль из приглашения
+func handleStartCommand(ctx context.Context, request *bot.CommandRequest) error {
+	message := request.Message
+	if token := request.Arg("параметр"); token != "" {
+		return redeemInvite(ctx, message, token)
+	}
+
+	if !hasAccess(message.UserID, message.ChatID) {
+		denyAccess(ctx, message)
+		return nil
+	}
+	return sendMainMenu(ctx, message)
+}
+
+func handleHelpCommand(ctx context.Context, request *bot.CommandRequest) error {
+	keyboard := [][]types.InlineKeyboardButton{
+		{callbacks.Button("📋 Главное меню", actionMainMenu)},
+	}
+
+	// Справка по отдельной команде
+	if name := request.Arg("команда"); name != "" {
+		command, ok := commands.Lookup(name)
+		if !ok || command.Hidden || !commands.Allowed(request.Message, command) {
+			return &bot.UsageError{Command: request.Command, Reason: "Команда /" + name + " не найдена"}
+		}
+		return api.SendMessage(ctx, request.Message.ChatID, render(bot.CommandHelp(command)), keyboard)
+	}
+
+	helpText := format.New().
+		Line(format.Text("🤖 "), format.Bold("Бот управления релизами")).
+		Blank().
+		Line(format.Bold("Доступные команды:")).
+		Append(commands.Help(request.Message)).
+		Blank().
+		Line(format.Bold("Функции бота:")).
+		Line(format.Text("📦 Создание релиза - запускает пайплайн сборки релизной версии")).
+		Line(format.Text("🌿 Просмотр веток - показывает список всех веток репозитория")).
+		Line(format.Text("🔀 Pull Requests - отображает активные PR с информацией")).
+		Line(format.Text("🏷 Все релизы - показывает список релизов с подробностями")).
+		Line(format.Text("⬇️ Последний релиз - показывает информацию о последнем релизе")).
+		Blank().
+		Line(format.Bold("Примечание:"), format.Text(" Бот работает только с разрешенными пользователями и чатами."))
+
+	return api.SendMessage(ctx, request.Message.ChatID, render(helpText), keyboard)
+}
+
+func handleReleaseTextCommand(ctx context.Context, request *bot.CommandRequest) error {
+	message, keyboard := releaseDialog(ctx, request.Message.UserID)
+	return api.SendMessage(ctx, request.Message.ChatID, render(message), keyboard)
+}
+
+func handleStatsCommand(ctx context.Context, request *bot.CommandRequest) error {
+	snapshot := metrics.Snapshot()
+
+	message := format.New().
+		Line(format.Bold("📊 Статистика обработки обновлений")).
+		Blank().
+		Item(format.Textf("Время работы: %s", snapshot.Uptime.Round(time.Second))).
+		Item(format.Textf("Обработано обновлений: %d", snapshot.Updates)).
+		Item(format.Textf("Среднее время обработки: %s", snapshot.Average.Round(time.Millisecond))).
+		Item(format.Textf("Максимальное время обработки: %s", snapshot.Max.Round(time.Millisecond))).
+		Item(format.Textf("Медленных обработок: %d", snapshot.Slow)).
+		Item(format.Textf("Отброшено из-за ограничения частоты: %d", snapshot.RateLimited))
+
+	return api.SendMessage(ctx, request.Message.ChatID, render(message), nil)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"tgbot/internal/access"
+	"tgbot/internal/storage"
+)
+
+// usePolicy подменяет политику доступа до конца теста
+func usePolicy(t *testing.T, p *access.Policy) {
+	t.Helper()
+	previous := policy
+	policy = p
+	t.Cleanup(func() { policy = previous })
+}
+
+// scopeRoles возвращает роли областей меню по их описанию
+func scopeRoles(scopes []commandScope) map[string]access.Role {
+	roles := make(map[string]access.Role, len(scopes))
+	for _, scope := range scopes {
+		roles[scope.scope.String()] = scope.role
+	}
+	return roles
+}
+
+func TestCommandScopes(t *testing.T) {
+	broad := map[string]access.Role{
+		"default":           access.RoleNone,
+		"all_private_chats": access.RoleNone,
+		"all_group_chats":   access.RoleNone,
+	}
+	with := func(extra map[string]access.Role) map[string]access.Role {
+		roles := make(map[string]access.Role)
+		for scope, role := range broad {
+			roles[scope] = role
+		}
+		for scope, role := range extra {
+			roles[scope] = role
+		}
+		return roles
+	}
+
+	tests := []struct {
+		name  string
+		setup func(p *access.Policy)
+		want  map[string]access.Role
+	}{
+		{
+			name: "нет разрешенных чатов",
+			setup: func(p *access.Policy) {
+				p.Allow(1)
+			},
+			want: broad,
+		},
+		{
+			name: "личный чат",
+			setup: func(p *access.Policy) {
+				p.AllowChat(5)
+				p.Assign(5, 0, access.RoleDeveloper)
+			},
+			want: with(map[string]access.Role{"chat(5)": access.RoleDeveloper}),
+		},
+		{
+			name: "группа с одинаковыми ролями",
+			setup: func(p *access.Policy) {
+				p.AllowChat(-100)
+				for userID := int64(1); userID <= 50; userID++ {
+					p.Allow(userID)
+				}
+			},
+			want: with(map[string]access.Role{"chat(-100)": access.RoleViewer}),
+		},
+		{
+			name: "отдельные меню только для старших ролей",
+			setup: func(p *access.Policy) {
+				p.AllowChat(-100)
+				p.Allow(1)
+				p.Allow(2)
+				p.Assign(3, 0, access.RoleAdmin)
+				p.Assign(4, -100, access.RoleReleaseManager)
+				// Отозванный в группе пользователь не влияет на меню группы
+				p.Assign(2, -100, access.RoleNone)
+			},
+			want: with(map[string]access.Role{
+				"chat(-100)":           access.RoleViewer,
+				"chat_member(-100, 3)": access.RoleAdmin,
+				"chat_member(-100, 4)": access.RoleReleaseManager,
+			}),
+		},
+		{
+			name: "роль в одной группе",
+			setup: func(p *access.Policy) {
+				p.AllowChat(-100)
+				p.AllowChat(-200)
+				p.Assign(1, -100, access.RoleDeveloper)
+				p.Assign(2, 0, access.RoleDeveloper)
+				p.Assign(2, -200, access.RoleAdmin)
+			},
+			want: with(map[string]access.Role{
+				"chat(-100)": access.RoleDeveloper,
+				"chat(-200)": access.RoleAdmin,
+			}),
+		},
+		{
+			name: "группа без пользователей",
+			setup: func(p *access.Policy) {
+				p.AllowChat(-100)
+			},
+			want: with(map[string]access.Role{"chat(-100)": access.RoleNone}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := access.NewPolicy(access.RoleViewer)
+			tt.setup(p)
+			usePolicy(t, p)
+
+			if got := scopeRoles(commandScopes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("области меню %v, ожидаются %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishedCommandMembers(t *testing.T) {
+	p := access.NewPolicy(access.RoleViewer)
+	p.AllowChat(5)
+	p.AllowChat(-100)
+	p.Allow(1)
+	p.Allow(2)
+	usePolicy(t, p)
+
+	previous := commandMembers
+	commandMembers = storage.NewRepository[[]commandMember](storage.NewMemory(), commandMembersBucket)
+	t.Cleanup(func() { commandMembers = previous })
+
+	// До первой синхронизации считаются опубликованными меню всех пользователей групп
+	want := []commandMember{{ChatID: -100, UserID: 1}, {ChatID: -100, UserID: 2}}
+	if got := publishedCommandMembers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("до синхронизации %v, ожидается %v", got, want)
+	}
+
+	// После синхронизации используется сохраненный список, даже пустой
+	if err := commandMembers.Put(commandMembersKey, []commandMember{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := publishedCommandMembers(); len(got) != 0 {
+		t.Errorf("после синхронизации %v, ожидается пустой список", got)
+	}
+}
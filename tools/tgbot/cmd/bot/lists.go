@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/pkg/types"
+)
+
+// backToListRow возвращает ряд с кнопками возврата к списку и в главное меню
+func backToListRow(pager bot.Paginator, page int) []types.InlineKeyboardButton {
+	return []types.InlineKeyboardButton{
+		{Text: "◀️ К списку", CallbackData: pager.PageData(page)},
+		callbacks.Button("📋 Главное меню", actionMainMenu),
+	}
+}
+
+// backToMainRow возвращает ряд с кнопкой возврата в главное меню
+func backToMainRow() []types.InlineKeyboardButton {
+	return []types.InlineKeyboardButton{callbacks.Button("◀️ Назад", actionMainMenu)}
+}
+
+// itemPage возвращает номер страницы списка, с которой открыта карточка элемента
+func itemPage(data bot.CallbackData) int {
+	page, err := data.Int(0)
+	if err != nil {
+		return 0
+	}
+	return page
+}
+
+// githubURL возвращает ссылку на страницу репозитория на GitHub
+func githubURL(path string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/%s", config().GitHubOwner, config().GitHubRepo, path)
+}
+
+func handleShowBranches(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Получение списка веток..."); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	branches, err := githubAPI().GetBranches()
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения списка веток: %v", err)), nil)
+		return
+	}
+
+	pager := callbacks.Paginator(actionBranches, len(branches))
+	page, _ := pager.ParsePage(data)
+	start, end := pager.Bounds(page)
+
+	message := format.New().
+		Line(format.Bold("🌿 Список веток")).
+		Line(format.Italic(fmt.Sprintf("Всего: %d, страница %d из %d", len(branches), page+1, pager.Pages())))
+
+	var items []types.InlineKeyboardButton
+	for i := start; i < end; i++ {
+		items = append(items, callbacks.Button("🌿 "+branches[i].Name, actionBranch, page, branches[i].Name))
+	}
+
+	editMessage(ctx, callback, message, pager.Keyboard(page, items, backToMainRow()))
+}
+
+func handleShowBranch(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	page := itemPage(data)
+	pager := callbacks.Paginator(actionBranches, 0)
+
+	branch, err := githubAPI().GetBranch(data.Arg(1))
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения ветки: %v", err)), [][]types.InlineKeyboardButton{backToListRow(pager, page)})
+		return
+	}
+
+	commit := branch.Commit
+	title, _, _ := strings.Cut(commit.Commit.Message, "\n")
+
+	message := format.New().
+		Line(format.Text("🌿 "), format.Code(branch.Name)).
+		Blank().
+		Item(format.Text("Последний коммит: "), format.Code(shortSHA(commit.SHA))).
+		Item(format.Textf("Сообщение: %s", title)).
+		Item(format.Textf("Автор: %s", commit.Commit.Author.Name)).
+		Item(format.Textf("Дата: %s", formatDate(commit.Commit.Author.Date)))
+	if branch.Protected {
+		message.Item(format.Text("🔒 Защищенная ветка"))
+	}
+
+	keyboard := [][]types.InlineKeyboardButton{
+		{{Text: "🔗 Открыть на GitHub", URL: githubURL("tree/" + branch.Name)}},
+	}
+	if canDeleteBranch(branch) && can(callback.UserID, callback.ChatID, access.PermissionDevelop) {
+		keyboard = append(keyboard, []types.InlineKeyboardButton{callbacks.Button("🗑 Удалить ветку", actionDeleteBranch, page, branch.Name)})
+	}
+	keyboard = append(keyboard, backToListRow(pager, page))
+
+	editMessage(ctx, callback, message, keyboard)
+}
+
+// canDeleteBranch сообщает, можно ли удалить ветку из бота: защищенные и релизные
+// ветки удалять нельзя
+func canDeleteBranch(branch *types.Branch) bool {
+	return !branch.Protected && branch.Name != "main" && branch.Name != "develop"
+}
+
+// branchDeleteTarget объект подтверждения удаления ветки
+func branchDeleteTarget(name string) string {
+	return "branch_delete|" + name
+}
+
+// prCloseTarget объект подтверждения закрытия PR
+func prCloseTarget(number string) string {
+	return "pr_close|" + number
+}
+
+// handleDeleteBranch просит подтвердить удаление ветки
+func handleDeleteBranch(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	page, name := itemPage(data), data.Arg(1)
+	confirmation := confirmations.Start(callback.UserID, branchDeleteTarget(name))
+	message := format.New().
+		Line(format.Text("Удалить ветку "), format.Code(name), format.Text("?")).
+		Line(format.Italic(fmt.Sprintf("Действие нельзя отменить. Подтвердите в течение %s.", formatTTL(confirmations.TTL()))))
+	keyboard := [][]types.InlineKeyboardButton{
+		{
+			callbacks.Button("✅ Удалить", actionDeleteBranchConfirm, page, name, confirmation.ID),
+			callbacks.Button("❌ Отмена", actionBranch, page, name),
+		},
+	}
+
+	editMessage(ctx, callback, message, keyboard)
+}
+
+// handleDeleteBranchConfirm удаляет ветку после подтверждения
+func handleDeleteBranchConfirm(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if !confirmAction(ctx, callback, data.Arg(2), branchDeleteTarget(data.Arg(1))) {
+		return
+	}
+
+	page, name := itemPage(data), data.Arg(1)
+	keyboard := [][]types.InlineKeyboardButton{backToListRow(callbacks.Paginator(actionBranches, 0), page)}
+
+	branch, err := githubAPI().GetBranch(name)
+	if err == nil && !canDeleteBranch(branch) {
+		err = fmt.Errorf("ветка %s защищена от удаления", name)
+	}
+	if err == nil {
+		err = githubClient().DeleteBranch(name)
+	}
+	recordAudit(ctx, audit.Event{
+		UserID: callback.UserID,
+		ChatID: callback.ChatID,
+		Action: audit.ActionBranchDelete,
+		Target: name,
+		Error:  auditError(err),
+	})
+	if err != nil {
+		bot.Logf(ctx, "Ошибка удаления ветки %s: %v", name, err)
+		if err := api.ShowAlert(ctx, callback.ID, "❌ Не удалось удалить ветку"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка удаления ветки: %v", err)), keyboard)
+		return
+	}
+
+	bot.Logf(ctx, "Пользователь %d удалил ветку %s", callback.UserID, name)
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Ветка удалена"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	editMessage(ctx, callback, format.New().Line(format.Text("🗑 Ветка "), format.Code(name), format.Text(" удалена.")), keyboard)
+}
+
+func handleShowPRs(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Получение списка PR..."); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	prs, err := githubAPI().GetPullRequests()
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения списка PR: %v", err)), nil)
+		return
+	}
+
+	pager := callbacks.Paginator(actionPRs, len(prs))
+	page, _ := pager.ParsePage(data)
+	start, end := pager.Bounds(page)
+
+	message := format.New().
+		Line(format.Bold("🔀 Список Pull Requests")).
+		Line(format.Italic(fmt.Sprintf("Всего: %d, страница %d из %d", len(prs), page+1, pager.Pages())))
+	if len(prs) == 0 {
+		message.Blank().Line(format.Text("Открытых PR нет."))
+	}
+
+	var items []types.InlineKeyboardButton
+	for i := start; i < end; i++ {
+		pr := prs[i]
+		message.Blank().
+			Line(format.Boldf("#%d %s", pr.Number, pr.Title)).
+			Item(format.Textf("Автор: %s", pr.User.Login)).
+			Item(format.Textf("Создан: %s", formatDate(pr.CreatedAt)))
+		items = append(items, callbacks.Button(fmt.Sprintf("#%d %s", pr.Number, pr.Title), actionPR, page, pr.Number))
+	}
+
+	editMessage(ctx, callback, message, pager.Keyboard(page, items, backToMainRow()))
+}
+
+func handleShowPR(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	page := itemPage(data)
+	pager := callbacks.Paginator(actionPRs, 0)
+
+	number, err := data.Int(1)
+	if err != nil {
+		editMessage(ctx, callback, format.Plain("❌ Некорректный номер PR."), [][]types.InlineKeyboardButton{backToListRow(pager, page)})
+		return
+	}
+
+	pr, err := githubAPI().GetPullRequest(number)
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения PR: %v", err)), [][]types.InlineKeyboardButton{backToListRow(pager, page)})
+		return
+	}
+
+	state := pr.State
+	if pr.Draft {
+		state += " (черновик)"
+	}
+
+	message := format.New().
+		Line(format.Boldf("🔀 #%d %s", pr.Number, pr.Title)).
+		Blank().
+		Item(format.Textf("Автор: %s", pr.User.Login)).
+		Item(format.Textf("Статус: %s", state)).
+		Item(format.Text("Ветки: "), format.Code(pr.Head.Ref), format.Text(" → "), format.Code(pr.Base.Ref)).
+		Item(format.Textf("Создан: %s", formatDate(pr.CreatedAt)))
+
+	keyboard := [][]types.InlineKeyboardButton{
+		{{Text: "🔗 Открыть на GitHub", URL: pr.HTMLURL}},
+	}
+	if pr.State == "open" && can(callback.UserID, callback.ChatID, access.PermissionDevelop) {
+		keyboard = append(keyboard, []types.InlineKeyboardButton{callbacks.Button("🚫 Закрыть PR", actionClosePR, page, pr.Number)})
+	}
+	keyboard = append(keyboard, backToListRow(pager, page))
+
+	editMessage(ctx, callback, message, keyboard)
+}
+
+// handleClosePR просит подтвердить закрытие PR
+func handleClosePR(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	page, number := itemPage(data), data.Arg(1)
+	confirmation := confirmations.Start(callback.UserID, prCloseTarget(number))
+	keyboard := [][]types.InlineKeyboardButton{
+		{
+			callbacks.Button("✅ Закрыть", actionClosePRConfirm, page, number, confirmation.ID),
+			callbacks.Button("❌ Отмена", actionPR, page, number),
+		},
+	}
+
+	text := fmt.Sprintf("Закрыть PR #%s без слияния? Подтвердите в течение %s.", number, formatTTL(confirmations.TTL()))
+	editMessage(ctx, callback, format.Plain(text), keyboard)
+}
+
+// handleClosePRConfirm закрывает PR после подтверждения
+func handleClosePRConfirm(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if !confirmAction(ctx, callback, data.Arg(2), prCloseTarget(data.Arg(1))) {
+		return
+	}
+
+	page := itemPage(data)
+	keyboard := [][]types.InlineKeyboardButton{backToListRow(callbacks.Paginator(actionPRs, 0), page)}
+
+	number, err := data.Int(1)
+	if err == nil {
+		err = githubClient().ClosePullRequest(number)
+	}
+	recordAudit(ctx, audit.Event{
+		UserID: callback.UserID,
+		ChatID: callback.ChatID,
+		Action: audit.ActionPRClose,
+		Target: "#" + data.Arg(1),
+		Error:  auditError(err),
+	})
+	if err != nil {
+		bot.Logf(ctx, "Ошибка закрытия PR %s: %v", data.Arg(1), err)
+		if err := api.ShowAlert(ctx, callback.ID, "❌ Не удалось закрыть PR"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка закрытия PR: %v", err)), keyboard)
+		return
+	}
+
+	bot.Logf(ctx, "Пользователь %d закрыл PR #%d", callback.UserID, number)
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "PR закрыт"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	editMessage(ctx, callback, format.Plain(fmt.Sprintf("🚫 PR #%d закрыт.", number)), keyboard)
+}
+
+func handleShowReleases(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Получение списка релизов..."); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	releases, err := githubAPI().GetReleases()
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения списка релизов: %v", err)), nil)
+		return
+	}
+
+	pager := callbacks.Paginator(actionReleases, len(releases))
+	page, _ := pager.ParsePage(data)
+	start, end := pager.Bounds(page)
+
+	message := format.New().
+		Line(format.Bold("🏷 Список релизов")).
+		Line(format.Italic(fmt.Sprintf("Всего: %d, страница %d из %d", len(releases), page+1, pager.Pages())))
+
+	var items []types.InlineKeyboardButton
+	for i := start; i < end; i++ {
+		release := releases[i]
+		label := release.TagName
+		if release.Prerelease {
+			label += " (pre-release)"
+		}
+		items = append(items, callbacks.Button("🏷 "+label, actionRelease, page, release.TagName))
+	}
+
+	editMessage(ctx, callback, message, pager.Keyboard(page, items, backToMainRow()))
+}
+
+func handleShowRelease(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	page := itemPage(data)
+	pager := callbacks.Paginator(actionReleases, 0)
+
+	release, err := githubAPI().GetReleaseByTag(data.Arg(1))
+	if err != nil {
+		editMessage(ctx, callback, format.Plain(fmt.Sprintf("❌ Ошибка получения релиза: %v", err)), [][]types.InlineKeyboardButton{backToListRow(pager, page)})
+		return
+	}
+
+	message := format.New().Line(format.Bold("🏷 Релиз")).Blank()
+	writeRelease(message, release)
+
+	keyboard := [][]types.InlineKeyboardButton{
+		{{Text: "🔗 Открыть на GitHub", URL: release.HTMLURL}},
+		backToListRow(pager, page),
+	}
+
+	editMessage(ctx, callback, message, keyboard)
+}
+
+func handleShowLatestRelease(ctx context.Context, callback *types.CallbackQuery, _ bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Получение информации о релизах..."); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	// Получаем последний релиз из main ветки
+	release, err := githubAPI().GetLatestRelease()
+	if err != nil {
+		if err := api.EditMessageText(ctx, callback.ChatID, callback.MessageID, render(format.Plain(fmt.Sprintf("❌ Ошибка получения информации о релизе: %v", err))), nil); err != nil {
+			bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
+		}
+		return
+	}
+
+	// Получаем последний pre-release из develop ветки
+	preRelease, err := githubAPI().GetLatestPreRelease()
+	if err != nil {
+		if err := api.EditMessageText(ctx, callback.ChatID, callback.MessageID, render(format.Plain(fmt.Sprintf("❌ Ошибка получения информации о pre-release: %v", err))), nil); err != nil {
+			bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
+		}
+		return
+	}
+
+	message := format.New().Line(format.Bold("📥 Информация о релизах")).Blank()
+
+	// Информация о последнем релизе
+	message.Line(format.Bold("Последний релиз (main):"))
+	writeRelease(message, release)
+
+	// Информация о последнем pre-release
+	message.Blank().Line(format.Bold("Последний pre-release (develop):"))
+	writeRelease(message, preRelease)
+
+	keyboard := [][]types.InlineKeyboardButton{backToMainRow()}
+
+	if err := api.EditLongMessage(ctx, callback.ChatID, callback.MessageID, render(message), keyboard); err != nil {
+		bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
+	}
+}
+
+// writeRelease добавляет в сообщение описание релиза и список его файлов
+func writeRelease(message *format.Message, release *types.Release) {
+	message.
+		Item(format.Textf("Название: %s", release.Name)).
+		Item(format.Text("Тег: "), format.Code(release.TagName)).
+		Item(format.Textf("Создан: %s", formatDate(release.CreatedAt))).
+		Item(format.Textf("Опубликован: %s", formatDate(release.PublishedAt))).
+		Item(format.Text("Ссылка: "), format.Link("GitHub Release", release.HTMLURL))
+
+	if len(release.Assets) > 0 {
+		message.Blank().Line(format.Bold("Доступные файлы:"))
+		for _, asset := range release.Assets {
+			message.Item(format.Text(asset.Name))
+		}
+	}
+}
+
+// shortSHA возвращает сокращенный хеш коммита
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
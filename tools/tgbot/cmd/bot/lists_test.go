@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+func TestCanDeleteBranch(t *testing.T) {
+	tests := []struct {
+		branch types.Branch
+		want   bool
+	}{
+		{types.Branch{Name: "feature/login"}, true},
+		{types.Branch{Name: "release/1.2"}, true},
+		{types.Branch{Name: "feature/locked", Protected: true}, false},
+		{types.Branch{Name: "main"}, false},
+		{types.Branch{Name: "develop"}, false},
+	}
+	for _, tt := range tests {
+		if got := canDeleteBranch(&tt.branch); got != tt.want {
+			t.Errorf("canDeleteBranch(%+v) = %v, ожидается %v", tt.branch, got, tt.want)
+		}
+	}
+}
@@ -1,442 +1,301 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
 	"tgbot/internal/github"
+	"tgbot/internal/storage"
 	"tgbot/internal/telegram"
+	"tgbot/internal/totp"
 	"tgbot/pkg/types"
 )
 
+// parseMode разметка, в которой бот отправляет сообщения
+const parseMode = format.MarkdownV2
+
 var (
-	api       *telegram.API
-	githubAPI *github.API
-	config    *types.BotConfig
+	api      *telegram.API
+	commands *bot.CommandRouter
+	policy   *access.Policy
+	// activeConfig действующая конфигурация; заменяется целиком при перезагрузке
+	activeConfig atomic.Pointer[types.BotConfig]
+	// activeGitHubAPI клиент GitHub API для текущих токена и репозитория
+	activeGitHubAPI atomic.Pointer[github.API]
+	// accessStore изменения доступа, сделанные командами администраторов
+	accessStore *access.Store
+	// confirmations действия, ожидающие подтверждения пользователем
+	confirmations *bot.Confirmations
+	// approvals запросы на одобрение релиза, ожидающие голосов релиз-менеджеров
+	approvals *bot.Approvals
+	// totpStore секреты второго фактора для запуска релизов
+	totpStore *totp.Store
+	// auditLog журнал привилегированных действий
+	auditLog *audit.Log
+	// botUsername имя бота для ссылок-приглашений t.me/<бот>?start=<токен>
+	botUsername string
+	metrics     *bot.Metrics
+	callbacks   *bot.CallbackRouter
 )
 
-func main() {
-	// Загружаем конфигурацию
-	var err error
-	config, err = loadConfig()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
+// releaseStartedText сообщение об успешном запуске пайплайна релиза
+const releaseStartedText = "✅ Пайплайн создания релиза успешно запущен!\nОжидайте уведомления о завершении."
 
-	// Создаем экземпляр Telegram API
-	api = telegram.NewAPI(config.TgBotKey)
+// config возвращает действующую конфигурацию. Значение нельзя изменять: при
+// перезагрузке подставляется новая конфигурация
+func config() *types.BotConfig {
+	return activeConfig.Load()
+}
 
-	// Создаем экземпляр GitHub API
-	githubAPI = github.NewAPI(config.GitHubToken, config.GitHubOwner, config.GitHubRepo)
+// githubAPI возвращает клиент GitHub API для действующей конфигурации
+func githubAPI() *github.API {
+	return activeGitHubAPI.Load()
+}
 
-	// Запускаем обработку обновлений
-	if err := api.HandleUpdates(handleUpdate); err != nil {
-		log.Fatalf("Ошибка обработки обновлений: %v", err)
+func main() {
+	if err := run(); err != nil {
+		log.Printf("%v", err)
+		os.Exit(1)
 	}
 }
 
-func loadConfig() (*types.BotConfig, error) {
-	// Сначала пробуем загрузить из файла
-	configPath := filepath.Join("utils", "tgapi.json")
-	configData, err := os.ReadFile(configPath)
-	if err == nil {
-		var config types.BotConfig
-		if err := json.Unmarshal(configData, &config); err == nil {
-			return &config, nil
-		}
-		fmt.Println(err)
-	}
+func run() error {
+	// Останавливаем бота по SIGINT/SIGTERM, давая обработчикам завершиться
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Если не удалось загрузить из файла, пробуем получить из переменных окружения
-	apiKey := os.Getenv("TG_KEY")
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	githubOwner := os.Getenv("GITHUB_OWNER")
-	githubRepo := os.Getenv("GITHUB_REPO")
-
-	if apiKey != "" && githubToken != "" && githubOwner != "" && githubRepo != "" {
-		return &types.BotConfig{
-			TgBotKey:    apiKey,
-			GitHubToken: githubToken,
-			GitHubOwner: githubOwner,
-			GitHubRepo:  githubRepo,
-		}, nil
+	// Загружаем конфигурацию
+	configLoader, err := bot.NewConfigLoader(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
 	}
-
-	return nil, fmt.Errorf("не удалось загрузить конфигурацию бота")
-}
-
-func handleUpdate(update types.Update) {
-	// Проверяем тип обновления
-	if update.CallbackQuery != nil {
-		handleCallback(update.CallbackQuery)
-		return
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
 	}
-
-	if update.Message == nil {
-		return
+	loaded, err := configLoader.Load()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
 	}
+	activeConfig.Store(loaded)
 
-	// Проверяем, разрешен ли пользователь
-	if !isUserAllowed(update.Message.UserID) {
-		if err := api.SendMessage(update.Message.ChatID, "У вас нет доступа к этому боту.", nil); err != nil {
-			log.Printf("Ошибка отправки сообщения: %v", err)
-		}
-		return
+	// Открываем хранилище состояния и переносим в него данные прежних версий
+	store, err := openStorage(config())
+	if err != nil {
+		return err
 	}
-
-	// Проверяем, разрешен ли чат
-	if !isChatAllowed(update.Message.ChatID) {
-		if err := api.SendMessage(update.Message.ChatID, "Этот чат не разрешен для использования бота.", nil); err != nil {
-			log.Printf("Ошибка отправки сообщения: %v", err)
+	defer func() {
+		if err := store.Close(); err != nil {
+			log.Printf("Ошибка закрытия хранилища: %v", err)
 		}
-		return
-	}
+	}()
 
-	// Обрабатываем команды
-	if strings.HasPrefix(update.Message.Text, "/") {
-		handleCommand(update.Message)
-		return
+	// Загружаем роли пользователей
+	policy, err = access.PolicyFromConfig(config())
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки ролей: %w", err)
 	}
-
-	// Показываем главное меню
-	showMainMenu(update.Message.ChatID)
-}
-
-func handleCommand(message *types.Message) {
-	switch message.Text {
-	case "/start":
-		showMainMenu(message.ChatID)
-	case "/help":
-		showHelp(message.ChatID)
-	default:
-		showMainMenu(message.ChatID)
+	accessStore = access.NewStore(store, policy)
+	if err := accessStore.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки изменений доступа: %w", err)
 	}
-}
-
-func showHelp(chatID int64) {
-	helpText := `🤖 *Бот управления релизами*
-
-*Доступные команды:*
-/help - показать это сообщение
-/start - показать главное меню
 
-*Функции бота:*
-📦 Создание релиза - запускает пайплайн сборки релизной версии
-🌿 Просмотр веток - показывает список всех веток репозитория
-🔀 Pull Requests - отображает активные PR с информацией
-⬇️ Последний релиз - показывает информацию о последнем релизе
-
-*Примечание:* Бот работает только с разрешенными пользователями и чатами.`
-
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "📋 Главное меню",
-				CallbackData: "back_to_main",
-			},
-		},
+	// Загружаем секреты второго фактора
+	totpStore = totp.NewStore(store)
+	if err := totpStore.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки секретов второго фактора: %w", err)
 	}
 
-	if err := api.SendMessage(chatID, helpText, keyboard); err != nil {
-		log.Printf("Ошибка отправки сообщения помощи: %v", err)
+	// Открываем журнал аудита; нарушение цепочки хешей не мешает работе, но требует проверки
+	var chainErr *audit.ChainError
+	if auditLog, err = audit.Open(config().AuditFile); errors.As(err, &chainErr) {
+		log.Printf("ВНИМАНИЕ: %v", err)
+	} else if err != nil {
+		return fmt.Errorf("ошибка открытия журнала аудита: %w", err)
 	}
-}
 
-func handleCallback(callback *types.CallbackQuery) {
-	// Проверяем, разрешен ли пользователь
-	if !isUserAllowed(callback.UserID) {
-		if err := api.AnswerCallbackQuery(callback.ID, "У вас нет доступа к этому боту."); err != nil {
-			log.Printf("Ошибка ответа на callback: %v", err)
-		}
-		return
+	// Создаем экземпляр Telegram API
+	apiOptions := []telegram.Option{
+		telegram.WithShutdownTimeout(time.Duration(config().ShutdownTimeoutSeconds) * time.Second),
+		telegram.WithConcurrency(config().Workers, config().QueueSize),
+		telegram.WithSkipBacklog(config().SkipBacklog),
+		telegram.WithParseMode(string(parseMode)),
+		telegram.WithOffsetStore(telegram.NewStorageOffsetStore(store)),
+	}
+	api = telegram.NewAPI(config().TgBotKey, apiOptions...)
+
+	// Регистрируем обработчики inline-кнопок; опасные действия требуют подтверждения
+	confirmations = bot.NewConfirmations(bot.DefaultConfirmationTTL)
+	approvals = bot.NewApprovals(store, time.Duration(config().ReleaseApprovalTimeoutMinutes)*time.Minute)
+	releasePlans = storage.NewRepository[savedReleasePlan](store, releasePlansBucket)
+	commandMembers = storage.NewRepository[[]commandMember](store, commandMembersBucket)
+	callbacks, err = newCallbackRouter()
+	if err != nil {
+		return fmt.Errorf("ошибка настройки inline-кнопок: %w", err)
 	}
-
-	// Проверяем, разрешен ли чат
-	if !isChatAllowed(callback.ChatID) {
-		if err := api.AnswerCallbackQuery(callback.ID, "Этот чат не разрешен для использования бота."); err != nil {
-			log.Printf("Ошибка ответа на callback: %v", err)
-		}
-		return
+	// Запросы на одобрение, начатые до перезапуска, продолжают ждать голосов
+	if err := resumeReleaseApprovals(ctx); err != nil {
+		return err
 	}
 
-	// Обрабатываем callback-данные
-	switch callback.Data {
-	case "create_release":
-		handleReleaseCommand(callback)
-	case "show_branches":
-		handleShowBranches(callback)
-	case "show_prs":
-		handleShowPRs(callback)
-	case "show_latest_release":
-		handleShowLatestRelease(callback)
-	case "back_to_main":
-		keyboard := [][]types.InlineKeyboardButton{
-			{
-				{
-					Text:         "📦 Создать релиз",
-					CallbackData: "create_release",
-				},
-			},
-			{
-				{
-					Text:         "🌿 Показать ветки",
-					CallbackData: "show_branches",
-				},
-			},
-			{
-				{
-					Text:         "🔀 Показать PR",
-					CallbackData: "show_prs",
-				},
-			},
-			{
-				{
-					Text:         "⬇️ Скачать последний релиз",
-					CallbackData: "show_latest_release",
-				},
-			},
-		}
-		if err := api.EditMessageText(callback.ChatID, callback.MessageID, "Выберите действие:", keyboard); err != nil {
-			log.Printf("Ошибка редактирования сообщения: %v", err)
-		}
-	default:
-		if err := api.AnswerCallbackQuery(callback.ID, "Неизвестная команда"); err != nil {
-			log.Printf("Ошибка ответа на callback: %v", err)
-		}
+	// Регистрируем команды; имя бота нужно, чтобы отличать команды вида /help@OtherBot в группах
+	commands = newCommandRouter()
+	if me, err := api.GetMe(ctx); err != nil {
+		log.Printf("Ошибка получения имени бота: %v", err)
+	} else {
+		botUsername = me.Username
+		commands.SetBotName(me.Username)
 	}
-}
+	// Меню команд публикуется в фоне, чтобы не задерживать начало обработки обновлений
+	go syncCommands(ctx)
 
-func showMainMenu(chatID int64) {
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "📦 Создать релиз",
-				CallbackData: "create_release",
-			},
-		},
-		{
-			{
-				Text:         "🌿 Показать ветки",
-				CallbackData: "show_branches",
-			},
-		},
-		{
-			{
-				Text:         "🔀 Показать PR",
-				CallbackData: "show_prs",
-			},
-		},
-		{
-			{
-				Text:         "⬇️ Скачать последний релиз",
-				CallbackData: "show_latest_release",
-			},
-		},
+	// Создаем экземпляр GitHub API
+	activeGitHubAPI.Store(github.NewAPI(config().GitHubToken, config().GitHubOwner, config().GitHubRepo))
+
+	// Следим за файлом конфигурации и сигналом SIGHUP
+	go watchConfig(ctx, configLoader)
+
+	// Оборачиваем обработчик в middleware: логирование, восстановление после паники,
+	// замер времени, ограничение частоты и проверка доступа
+	metrics = bot.NewMetrics()
+	handler := newUpdateHandler()
+
+	// Запускаем обработку обновлений в выбранном режиме
+	switch config().UpdateMode {
+	case types.UpdateModeWebhook:
+		err = api.HandleWebhook(ctx, telegram.WebhookConfig{
+			URL:         config().WebhookURL,
+			ListenAddr:  config().WebhookListenAddr,
+			SecretToken: config().WebhookSecret,
+			CertFile:    config().WebhookCertFile,
+			KeyFile:     config().WebhookKeyFile,
+		}, handler)
+	case "", types.UpdateModePolling:
+		err = api.HandleUpdates(ctx, handler)
+	default:
+		err = fmt.Errorf("неизвестный режим получения обновлений: %s", config().UpdateMode)
 	}
-
-	if err := api.SendMessage(chatID, "Выберите действие:", keyboard); err != nil {
-		log.Printf("Ошибка отправки главного меню: %v", err)
+	if err != nil {
+		return fmt.Errorf("ошибка обработки обновлений: %w", err)
 	}
-}
 
-func isUserAllowed(userID int64) bool {
-	for _, id := range config.AllowedUserIDs {
-		if id == userID {
-			return true
-		}
-	}
-	return false
+	log.Printf("Бот остановлен")
+	return nil
 }
 
-func isChatAllowed(chatID int64) bool {
-	for _, id := range config.AllowedChatIDs {
-		if id == chatID {
-			return true
-		}
+func handleUpdate(ctx context.Context, update types.Update) {
+	// Проверяем тип обновления
+	if update.CallbackQuery != nil {
+		handleCallback(ctx, update.CallbackQuery)
+		return
 	}
-	return false
-}
 
-func handleReleaseCommand(callback *types.CallbackQuery) {
-	// Отвечаем на callback
-	if err := api.AnswerCallbackQuery(callback.ID, "Запуск создания релиза..."); err != nil {
-		log.Printf("Ошибка ответа на callback: %v", err)
+	if update.Message == nil {
 		return
 	}
 
-	// Создаем клиент GitHub
-	client := github.NewClient(config.GitHubToken, fmt.Sprintf("%s/%s", config.GitHubOwner, config.GitHubRepo))
-
-	// Запускаем пайплайн
-	if err := client.TriggerWorkflow("merge.yml"); err != nil {
-		log.Printf("Ошибка запуска пайплайна: %v", err)
-		if err := api.ShowAlert(callback.ID, "❌ Ошибка: пайплайн не настроен для ручного запуска"); err != nil {
-			log.Printf("Ошибка отправки алерта: %v", err)
+	// Обрабатываем команды; доступ проверяется в зависимости от уровня, требуемого командой
+	if handled, err := commands.Handle(ctx, update.Message); handled {
+		if err != nil {
+			replyCommandError(ctx, update.Message, err)
 		}
 		return
 	}
 
-	// Добавляем кнопку "Назад"
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "◀️ Назад",
-				CallbackData: "back_to_main",
-			},
-		},
-	}
-
-	if err := api.EditMessageText(callback.ChatID, callback.MessageID, "✅ Пайплайн создания релиза успешно запущен!\nОжидайте уведомления о завершении.", keyboard); err != nil {
-		log.Printf("Ошибка редактирования сообщения: %v", err)
-	}
-}
-
-func handleShowBranches(callback *types.CallbackQuery) {
-	if err := api.AnswerCallbackQuery(callback.ID, "Получение списка веток..."); err != nil {
-		log.Printf("Ошибка ответа на callback: %v", err)
+	// Код второго фактора для ожидающего запуска релиза
+	if handleReleaseCode(ctx, update.Message) {
 		return
 	}
 
-	branches, err := githubAPI.GetBranches()
-	if err != nil {
-		if err := api.EditMessageText(callback.ChatID, callback.MessageID, fmt.Sprintf("❌ Ошибка получения списка веток: %v", err), nil); err != nil {
-			log.Printf("Ошибка редактирования сообщения: %v", err)
-		}
+	// Пересланное администратором сообщение показывает автора и его роль
+	if handleForwardedMessage(ctx, update.Message) {
 		return
 	}
 
-	var message strings.Builder
-	message.WriteString("*🌿 Список веток:*\n\n")
-	for _, branch := range branches {
-		message.WriteString(fmt.Sprintf("• %s\n", branch.Name))
-	}
-
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "◀️ Назад",
-				CallbackData: "back_to_main",
-			},
-		},
-	}
-
-	if err := api.EditMessageText(callback.ChatID, callback.MessageID, message.String(), keyboard); err != nil {
-		log.Printf("Ошибка редактирования сообщения: %v", err)
-	}
+	// Показываем главное меню
+	showMainMenu(ctx, update.Message)
 }
 
-func handleShowPRs(callback *types.CallbackQuery) {
-	if err := api.AnswerCallbackQuery(callback.ID, "Получение списка PR..."); err != nil {
-		log.Printf("Ошибка ответа на callback: %v", err)
-		return
-	}
-
-	prs, err := githubAPI.GetPullRequests()
-	if err != nil {
-		if err := api.EditMessageText(callback.ChatID, callback.MessageID, fmt.Sprintf("❌ Ошибка получения списка PR: %v", err), nil); err != nil {
-			log.Printf("Ошибка редактирования сообщения: %v", err)
+func handleCallback(ctx context.Context, callback *types.CallbackQuery) {
+	// Вызываем обработчик действия нажатой кнопки
+	if err := callbacks.Dispatch(ctx, callback); err != nil {
+		var text string
+		switch {
+		case errors.Is(err, bot.ErrCallbackExpired):
+			text = "Кнопка устарела, откройте меню заново: /start"
+		case errors.Is(err, bot.ErrCallbackUnsigned), errors.Is(err, bot.ErrCallbackSignature), errors.Is(err, bot.ErrCallbackMalformed):
+			text = "Недействительная кнопка, откройте меню заново: /start"
+		case errors.Is(err, bot.ErrForbidden):
+			text = accessDeniedText(callback.UserID, callback.ChatID)
+		default:
+			text = "Неизвестная команда"
+		}
+		bot.Logf(ctx, "Отклонен callback от пользователя %d в чате %d: %v", callback.UserID, callback.ChatID, err)
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, text); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
 		}
-		return
-	}
-
-	var message strings.Builder
-	message.WriteString("*🔀 Список Pull Requests:*\n\n")
-	for _, pr := range prs {
-		message.WriteString(fmt.Sprintf("*#%d %s*\n", pr.Number, pr.Title))
-		message.WriteString(fmt.Sprintf("• Автор: %s\n", pr.User.Login))
-		message.WriteString(fmt.Sprintf("• Статус: %s\n", pr.State))
-		message.WriteString(fmt.Sprintf("• Создан: %s\n", formatDate(pr.CreatedAt)))
-		message.WriteString(fmt.Sprintf("• [Ссылка на PR](%s)\n\n", pr.HTMLURL))
-	}
-
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "◀️ Назад",
-				CallbackData: "back_to_main",
-			},
-		},
-	}
-
-	if err := api.EditMessageText(callback.ChatID, callback.MessageID, message.String(), keyboard); err != nil {
-		log.Printf("Ошибка редактирования сообщения: %v", err)
 	}
 }
 
-func handleShowLatestRelease(callback *types.CallbackQuery) {
-	if err := api.AnswerCallbackQuery(callback.ID, "Получение информации о релизах..."); err != nil {
-		log.Printf("Ошибка ответа на callback: %v", err)
-		return
+func showMainMenu(ctx context.Context, message *types.Message) {
+	if err := sendMainMenu(ctx, message); err != nil {
+		bot.Logf(ctx, "Ошибка отправки главного меню: %v", err)
 	}
+}
 
-	// Получаем последний релиз из main ветки
-	release, err := githubAPI.GetLatestRelease()
-	if err != nil {
-		if err := api.EditMessageText(callback.ChatID, callback.MessageID, fmt.Sprintf("❌ Ошибка получения информации о релизе: %v", err), nil); err != nil {
-			log.Printf("Ошибка редактирования сообщения: %v", err)
-		}
-		return
-	}
+// sendMainMenu отправляет в ответ на сообщение главное меню
+func sendMainMenu(ctx context.Context, message *types.Message) error {
+	return api.SendMessage(ctx, message.ChatID, render(format.Plain("Выберите действие:")), mainMenuKeyboard(message.UserID, message.ChatID))
+}
 
-	// Получаем последний pre-release из develop ветки
-	preRelease, err := githubAPI.GetLatestPreRelease()
-	if err != nil {
-		if err := api.EditMessageText(callback.ChatID, callback.MessageID, fmt.Sprintf("❌ Ошибка получения информации о pre-release: %v", err), nil); err != nil {
-			log.Printf("Ошибка редактирования сообщения: %v", err)
-		}
-		return
-	}
+// mainMenuKeyboard возвращает клавиатуру главного меню с действиями, доступными пользователю
+func mainMenuKeyboard(userID, chatID int64) [][]types.InlineKeyboardButton {
+	var keyboard [][]types.InlineKeyboardButton
+	if can(userID, chatID, access.PermissionRelease) {
+		keyboard = append(keyboard, []types.InlineKeyboardButton{callbacks.Button("📦 Создать релиз", actionCreateRelease)})
+	}
+	return append(keyboard,
+		[]types.InlineKeyboardButton{callbacks.Button("🌿 Показать ветки", actionBranches)},
+		[]types.InlineKeyboardButton{callbacks.Button("🔀 Показать PR", actionPRs)},
+		[]types.InlineKeyboardButton{callbacks.Button("🏷 Все релизы", actionReleases)},
+		[]types.InlineKeyboardButton{callbacks.Button("⬇️ Скачать последний релиз", actionLatestRelease)},
+	)
+}
 
-	var message strings.Builder
-	message.WriteString("*📥 Информация о релизах*\n\n")
-
-	// Информация о последнем релизе
-	message.WriteString("*Последний релиз (main):*\n")
-	message.WriteString(fmt.Sprintf("• Название: %s\n", release.Name))
-	message.WriteString(fmt.Sprintf("• Тег: %s\n", release.TagName))
-	message.WriteString(fmt.Sprintf("• Создан: %s\n", formatDate(release.CreatedAt)))
-	message.WriteString(fmt.Sprintf("• Опубликован: %s\n", formatDate(release.PublishedAt)))
-	message.WriteString(fmt.Sprintf("• Ссылка: [GitHub Release](%s)\n\n", release.HTMLURL))
-
-	if len(release.Assets) > 0 {
-		message.WriteString("*Доступные файлы:*\n")
-		for _, asset := range release.Assets {
-			message.WriteString(fmt.Sprintf("• %s\n", asset.Name))
-		}
-	}
+// triggerRelease запускает пайплайн мержа develop в main и сборки релиза
+func triggerRelease() error {
+	return githubClient().TriggerWorkflow(releaseWorkflow)
+}
 
-	// Информация о последнем pre-release
-	message.WriteString("\n*Последний pre-release (develop):*\n")
-	message.WriteString(fmt.Sprintf("• Название: %s\n", preRelease.Name))
-	message.WriteString(fmt.Sprintf("• Тег: %s\n", preRelease.TagName))
-	message.WriteString(fmt.Sprintf("• Создан: %s\n", formatDate(preRelease.CreatedAt)))
-	message.WriteString(fmt.Sprintf("• Опубликован: %s\n", formatDate(preRelease.PublishedAt)))
-	message.WriteString(fmt.Sprintf("• Ссылка: [GitHub Release](%s)\n", preRelease.HTMLURL))
-
-	if len(preRelease.Assets) > 0 {
-		message.WriteString("\n*Доступные файлы:*\n")
-		for _, asset := range preRelease.Assets {
-			message.WriteString(fmt.Sprintf("• %s\n", asset.Name))
-		}
-	}
+// githubClient создает клиент для изменяющих запросов к репозиторию
+func githubClient() *github.Client {
+	current := config()
+	return github.NewClient(current.GitHubToken, fmt.Sprintf("%s/%s", current.GitHubOwner, current.GitHubRepo))
+}
 
-	keyboard := [][]types.InlineKeyboardButton{
-		{
-			{
-				Text:         "◀️ Назад",
-				CallbackData: "back_to_main",
-			},
-		},
+// editMessage заменяет текст сообщения, к которому относится callback. Повторное
+// нажатие кнопки, не меняющее сообщение, ошибкой не считается
+func editMessage(ctx context.Context, callback *types.CallbackQuery, message *format.Message, keyboard [][]types.InlineKeyboardButton) {
+	err := api.EditLongMessage(ctx, callback.ChatID, callback.MessageID, render(message), keyboard)
+	if err != nil && !telegram.IsMessageNotModified(err) {
+		bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
 	}
+}
 
-	if err := api.EditMessageText(callback.ChatID, callback.MessageID, message.String(), keyboard); err != nil {
-		log.Printf("Ошибка редактирования сообщения: %v", err)
-	}
+// render выводит сообщение в разметке, в которой его отправляет бот
+func render(message *format.Message) string {
+	return message.Render(parseMode)
 }
 
 func formatDate(dateStr string) string {
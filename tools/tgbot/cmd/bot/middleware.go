@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"tgbot/internal/bot"
+	"tgbot/pkg/types"
+)
+
+const (
+	// userRateBurst сколько обновлений подряд пользователь может отправить без ожидания
+	userRateBurst = 10
+	// slowUpdateThreshold время обработки, после которого обновление записывается в лог как медленное
+	slowUpdateThreshold = 5 * time.Second
+)
+
+// newUpdateHandler собирает обработчик обновлений с цепочкой middleware
+func newUpdateHandler() types.UpdateHandler {
+	return bot.Chain(handleUpdate,
+		bot.Logging(),
+		bot.Recover(),
+		bot.Timing(metrics, slowUpdateThreshold),
+		bot.RateLimit(bot.NewUserRateLimiter(config().UserRatePerMinute, userRateBurst), metrics, rateLimited),
+		bot.Authorize(allowUpdate, denyUpdate),
+		trackUsers(),
+	)
+}
+
+// trackUsers запоминает username пользователей, пишущих в разрешенных чатах, чтобы
+// администратор мог выдать им роль командой /allow @username. Сообщения из прочих
+// чатов не записываются, иначе любой написавший боту занимал бы место в хранилище.
+// Пользователей, получивших доступ по приглашению, запоминает redeemInvite
+func trackUsers() bot.Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			if isChatAllowed(bot.UpdateChatID(update)) {
+				switch {
+				case update.CallbackQuery != nil:
+					rememberUser(ctx, &update.CallbackQuery.From)
+				case update.Message != nil:
+					rememberUser(ctx, update.Message.From)
+				}
+			}
+			next(ctx, update)
+		}
+	}
+}
+
+// allowUpdate проверяет, что пользователю разрешен доступ к боту в этом чате. Команды
+// и нажатия кнопок дополнительно проверяются маршрутизаторами по праву, требуемому действием
+func allowUpdate(update types.Update) bool {
+	if update.Message != nil {
+		if _, _, _, ok := bot.ParseCommand(update.Message.Text); ok {
+			return true
+		}
+	}
+	return hasAccess(bot.UpdateUserID(update), bot.UpdateChatID(update))
+}
+
+// denyUpdate сообщает об отказе в доступе
+func denyUpdate(ctx context.Context, update types.Update) {
+	if update.Message != nil {
+		denyAccess(ctx, update.Message)
+		return
+	}
+
+	if callback := update.CallbackQuery; callback != nil {
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, accessDeniedText(callback.UserID, callback.ChatID)); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+	}
+}
+
+// rateLimited отвечает на нажатие кнопки сверх ограничения, чтобы у пользователя
+// не зависал индикатор загрузки. На сообщения не отвечаем, чтобы не усиливать поток
+func rateLimited(ctx context.Context, update types.Update) {
+	if callback := update.CallbackQuery; callback != nil {
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, "Слишком много запросов, подождите немного"); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+	}
+}
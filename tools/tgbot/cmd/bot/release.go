@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/internal/telegram"
+	"tgbot/pkg/types"
+)
+
+const (
+	// releaseBaseBranch ветка, в которую merge.yml вливает изменения
+	releaseBaseBranch = "main"
+	// releaseHeadBranch ветка, изменения которой попадают в релиз
+	releaseHeadBranch = "develop"
+	// releaseWorkflow пайплайн, который запускает релиз
+	releaseWorkflow = "merge.yml"
+	// maxReleaseCommits сколько коммитов показывать в диалоге подтверждения релиза
+	maxReleaseCommits = 15
+)
+
+// releasePlan что попадет в релиз
+type releasePlan struct {
+	// version версия из .github/version.json ветки develop
+	version    string
+	comparison *types.Comparison
+}
+
+// loadReleasePlan получает коммиты, которые попадут в релиз, и версию релиза
+func loadReleasePlan(ctx context.Context) (*releasePlan, error) {
+	comparison, err := githubAPI().CompareBranches(releaseBaseBranch, releaseHeadBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &releasePlan{version: "не удалось определить", comparison: comparison}
+	if v, err := githubAPI().GetVersion(releaseHeadBranch); err != nil {
+		bot.Logf(ctx, "Ошибка получения версии: %v", err)
+	} else {
+		plan.version = "v" + v.String()
+	}
+	return plan, nil
+}
+
+// fingerprint возвращает описание плана для подтверждения: если до подтверждения
+// в develop появятся новые коммиты или изменится версия, подтверждение не примется
+func (p *releasePlan) fingerprint() string {
+	shas := make([]string, len(p.comparison.Commits))
+	for i, commit := range p.comparison.Commits {
+		shas[i] = commit.SHA
+	}
+	return fmt.Sprintf("release|%s|%d|%d|%s", p.version, p.comparison.AheadBy, p.comparison.BehindBy, strings.Join(shas, ","))
+}
+
+// writeSummary добавляет в сообщение версию и число коммитов релиза
+func (p *releasePlan) writeSummary(message *format.Message) {
+	message.
+		Item(format.Text("Будет запущен пайплайн "), format.Code("merge.yml"), format.Textf(": PR из %s в %s", releaseHeadBranch, releaseBaseBranch)).
+		Item(format.Text("Версия: "), format.Code(p.version)).
+		Item(format.Textf("Коммитов: %d", p.comparison.AheadBy))
+}
+
+// releaseDialog формирует диалог подтверждения релиза для пользователя userID:
+// коммиты, которые попадут в релиз, версию и кнопки подтверждения и отмены
+func releaseDialog(ctx context.Context, userID int64) (*format.Message, [][]types.InlineKeyboardButton) {
+	plan, err := loadReleasePlan(ctx)
+	if err != nil {
+		return format.Plain(fmt.Sprintf("❌ Ошибка получения изменений для релиза: %v", err)), [][]types.InlineKeyboardButton{backToMainRow()}
+	}
+	comparison := plan.comparison
+	if comparison.AheadBy == 0 {
+		text := fmt.Sprintf("В ветке %s нет новых коммитов относительно %s, создавать релиз не из чего.", releaseHeadBranch, releaseBaseBranch)
+		return format.Plain(text), [][]types.InlineKeyboardButton{backToMainRow()}
+	}
+
+	message := format.New().Line(format.Bold("📦 Создание релиза")).Blank()
+	plan.writeSummary(message)
+	message.Blank().Line(format.Bold("Коммиты:"))
+
+	// GitHub возвращает коммиты от старых к новым; показываем последние
+	commits := comparison.Commits
+	for i := len(commits) - 1; i >= 0 && i >= len(commits)-maxReleaseCommits; i-- {
+		commit := commits[i]
+		title, _, _ := strings.Cut(commit.Commit.Message, "\n")
+		message.Item(format.Code(shortSHA(commit.SHA)), format.Textf(" %s — %s", title, commit.Commit.Author.Name))
+	}
+	if hidden := comparison.AheadBy - min(len(commits), maxReleaseCommits); hidden > 0 {
+		message.Line(format.Italic(fmt.Sprintf("…и еще %d", hidden)))
+	}
+
+	confirmation := confirmations.Start(userID, plan.fingerprint())
+	confirmText := fmt.Sprintf("Подтвердите запуск в течение %s. Подтвердить может только пользователь, начавший создание релиза.", formatTTL(confirmations.TTL()))
+	if config().ReleaseApprovals > 0 {
+		confirmText += fmt.Sprintf(" После подтверждения релиз должны одобрить релиз-менеджеры: %d.", config().ReleaseApprovals)
+	}
+	message.Blank().Line(format.Italic(confirmText))
+
+	keyboard := [][]types.InlineKeyboardButton{
+		{
+			callbacks.Button("✅ Подтвердить", actionReleaseConfirm, confirmation.ID),
+			callbacks.Button("❌ Отмена", actionReleaseCancel, confirmation.ID),
+		},
+		{{Text: "🔗 Изменения на GitHub", URL: comparison.HTMLURL}},
+	}
+	return message, keyboard
+}
+
+// formatTTL возвращает длительность для сообщений, например «2 мин»
+func formatTTL(d time.Duration) string {
+	return fmt.Sprintf("%.0f мин", d.Minutes())
+}
+
+// handleReleaseCommand показывает диалог подтверждения релиза
+func handleReleaseCommand(ctx context.Context, callback *types.CallbackQuery, _ bot.CallbackData) {
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Получение изменений для релиза..."); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		return
+	}
+
+	message, keyboard := releaseDialog(ctx, callback.UserID)
+	editMessage(ctx, callback, message, keyboard)
+}
+
+// releaseTarget пользователь, подтвердивший релиз, и сообщение с диалогом релиза
+type releaseTarget struct {
+	userID    int64
+	chatID    int64
+	messageID int
+}
+
+// edit заменяет диалог релиза сообщением message
+func (t releaseTarget) edit(ctx context.Context, message *format.Message, keyboard [][]types.InlineKeyboardButton) {
+	err := api.EditLongMessage(ctx, t.chatID, t.messageID, render(message), keyboard)
+	if err != nil && !telegram.IsMessageNotModified(err) {
+		bot.Logf(ctx, "Ошибка редактирования сообщения: %v", err)
+	}
+}
+
+// handleReleaseConfirm запускает пайплайн релиза после подтверждения
+func handleReleaseConfirm(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	// Подтверждение относится к показанному плану релиза: сверяем его с текущим
+	plan, err := loadReleasePlan(ctx)
+	if err != nil {
+		bot.Logf(ctx, "Ошибка получения изменений для релиза: %v", err)
+		if err := api.ShowAlert(ctx, callback.ID, "❌ Не удалось проверить изменения для релиза, попробуйте еще раз"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		return
+	}
+	if !confirmAction(ctx, callback, data.Arg(0), plan.fingerprint()) {
+		return
+	}
+	target := releaseTarget{userID: callback.UserID, chatID: callback.ChatID, messageID: callback.MessageID}
+
+	// Пользователь с подключенным вторым фактором сначала вводит код
+	if totpRequired(callback.UserID) {
+		requestReleaseCode(ctx, callback, target)
+		return
+	}
+
+	answer := "Запуск создания релиза..."
+	if config().ReleaseApprovals > 0 {
+		answer = "Отправка запроса на одобрение..."
+	}
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, answer); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	startRelease(ctx, target)
+}
+
+// startRelease запускает пайплайн релиза или, если для релиза нужны одобрения
+// других релиз-менеджеров, отправляет запрос на одобрение
+func startRelease(ctx context.Context, target releaseTarget) {
+	if config().ReleaseApprovals > 0 {
+		requestReleaseApproval(ctx, target)
+		return
+	}
+
+	keyboard := [][]types.InlineKeyboardButton{backToMainRow()}
+
+	// Запускаем пайплайн
+	err := triggerRelease()
+	recordAudit(ctx, audit.Event{
+		UserID: target.userID,
+		ChatID: target.chatID,
+		Action: audit.ActionReleaseDispatch,
+		Target: releaseWorkflow,
+		Error:  auditError(err),
+	})
+	if err != nil {
+		bot.Logf(ctx, "Ошибка запуска пайплайна: %v", err)
+		target.edit(ctx, format.Plain("❌ Ошибка: пайплайн не настроен для ручного запуска"), keyboard)
+		return
+	}
+
+	bot.Logf(ctx, "Пользователь %d подтвердил запуск релиза", target.userID)
+	target.edit(ctx, format.Plain(releaseStartedText), keyboard)
+}
+
+// handleReleaseCancel отменяет создание релиза
+func handleReleaseCancel(ctx context.Context, callback *types.CallbackQuery, data bot.CallbackData) {
+	err := confirmations.Cancel(data.Arg(0), callback.UserID)
+	if errors.Is(err, bot.ErrConfirmationNotOwner) {
+		if err := api.ShowAlert(ctx, callback.ID, "Отменить создание релиза может только пользователь, который его начал"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		return
+	}
+
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Создание релиза отменено"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	editMessage(ctx, callback, format.Plain("Создание релиза отменено."), [][]types.InlineKeyboardButton{backToMainRow()})
+}
+
+// confirmAction проверяет подтверждение опасного действия id над объектом target. Если
+// подтверждает не тот пользователь, показывает предупреждение; если время истекло или
+// объект изменился, заменяет диалог сообщением об этом. Возвращает true, если действие
+// можно выполнять; в этом случае на callback еще не ответили
+func confirmAction(ctx context.Context, callback *types.CallbackQuery, id, target string) bool {
+	err := confirmations.Confirm(id, callback.UserID, target)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, bot.ErrConfirmationNotOwner):
+		bot.Logf(ctx, "Пользователь %d пытался подтвердить чужое действие", callback.UserID)
+		if err := api.ShowAlert(ctx, callback.ID, "⛔ Подтвердить действие может только пользователь, который его начал"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+	case errors.Is(err, bot.ErrConfirmationChanged):
+		bot.Logf(ctx, "Пользователь %d подтвердил действие, которое изменилось после запроса подтверждения", callback.UserID)
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, "Действие изменилось"); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+		editMessage(ctx, callback, format.Plain("⚠️ С момента запроса подтверждения данные изменились (например, в develop появились новые коммиты). Начните заново."), [][]types.InlineKeyboardButton{backToMainRow()})
+	default:
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, "Время на подтверждение истекло"); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+		editMessage(ctx, callback, format.Plain("⌛ Время на подтверждение истекло или действие уже выполнено. Начните заново."), [][]types.InlineKeyboardButton{backToMainRow()})
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/github"
+	"tgbot/pkg/types"
+)
+
+// configPollInterval как часто проверяется, изменился ли файл конфигурации
+const configPollInterval = 5 * time.Second
+
+// configVersion хеш содержимого файла конфигурации; отсутствующий или нечитаемый
+// файл имеет нулевой хеш. Содержимое, а не время изменения, сравнивается потому, что
+// запись файла в течение одной секунды может не изменить ни время, ни размер
+func configVersion(path string) [sha256.Size]byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(content)
+}
+
+// watchConfig перезагружает конфигурацию, когда меняется ее файл или бот получает SIGHUP.
+// Файлы секретов (*_file) не отслеживаются: после их замены отправьте SIGHUP
+func watchConfig(ctx context.Context, loader *bot.ConfigLoader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	// last версия файла, с которой в последний раз загружалась конфигурация. Версия
+	// берется до загрузки: если файл изменится во время нее, изменение заметит
+	// следующая проверка. Файл с ошибками повторно не загружается, пока не изменится
+	last := configVersion(loader.Path())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			log.Printf("Получен SIGHUP, перезагружаем конфигурацию")
+		case <-ticker.C:
+			if configVersion(loader.Path()) == last {
+				continue
+			}
+			log.Printf("Файл конфигурации %s изменился, перезагружаем конфигурацию", loader.Path())
+		}
+		last = configVersion(loader.Path())
+		changed, err := reloadConfig(ctx, loader)
+		if err != nil {
+			log.Printf("Конфигурация не перезагружена, продолжаем с прежней: %v", err)
+		}
+		if changed {
+			// Состав пользователей и чатов мог измениться — обновляем меню команд
+			go syncCommands(ctx)
+		}
+	}
+}
+
+// reloadConfig загружает и проверяет конфигурацию и заменяет действующую. Если новая
+// конфигурация содержит ошибки, действующая не меняется. Параметры, которые нельзя
+// применить без перезапуска, сохраняют прежние значения. Возвращает true, если
+// действующая конфигурация изменилась
+func reloadConfig(ctx context.Context, loader *bot.ConfigLoader) (bool, error) {
+	next, err := loader.Load()
+	if err != nil {
+		return false, err
+	}
+	current := config()
+
+	applied := *current
+	applyReloadable(&applied, next)
+	if pending := bot.DiffConfig(&applied, next); len(pending) > 0 {
+		log.Printf("Параметры применятся после перезапуска бота: %s", changeKeys(pending))
+	}
+	changes := bot.DiffConfig(current, &applied)
+	if len(changes) == 0 {
+		log.Printf("Конфигурация перезагружена, изменений нет")
+		return false, nil
+	}
+
+	nextPolicy, err := access.PolicyFromConfig(&applied)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки ролей: %w", err)
+	}
+	accessStore.ReplacePolicy(nextPolicy)
+	if applied.GitHubToken != current.GitHubToken || applied.GitHubOwner != current.GitHubOwner || applied.GitHubRepo != current.GitHubRepo {
+		activeGitHubAPI.Store(github.NewAPI(applied.GitHubToken, applied.GitHubOwner, applied.GitHubRepo))
+	}
+	activeConfig.Store(&applied)
+
+	for _, change := range changes {
+		log.Printf("Конфигурация: %s", change)
+	}
+	log.Printf("Конфигурация перезагружена, изменено параметров: %d", len(changes))
+	recordAudit(ctx, audit.Event{Action: audit.ActionConfigReload, Details: changeKeys(changes)})
+	return true, nil
+}
+
+// applyReloadable переносит в dst параметры из src, которые применяются без перезапуска:
+// списки доступа, роли, настройки релизов и подключение к GitHub
+func applyReloadable(dst, src *types.BotConfig) {
+	dst.AllowedUserIDs = src.AllowedUserIDs
+	dst.AllowedChatIDs = src.AllowedChatIDs
+	dst.DefaultRole = src.DefaultRole
+	dst.Roles = src.Roles
+	dst.GitHubToken = src.GitHubToken
+	dst.GitHubTokenFile = src.GitHubTokenFile
+	dst.GitHubOwner = src.GitHubOwner
+	dst.GitHubRepo = src.GitHubRepo
+	dst.ReleaseApprovals = src.ReleaseApprovals
+	dst.ReleaseChatID = src.ReleaseChatID
+	dst.ReleaseRequireTOTP = src.ReleaseRequireTOTP
+}
+
+// changeKeys возвращает имена измененных параметров через запятую
+func changeKeys(changes []bot.ConfigChange) string {
+	keys := make([]string, len(changes))
+	for i, change := range changes {
+		keys[i] = change.Key
+	}
+	return strings.Join(keys, ", ")
+}
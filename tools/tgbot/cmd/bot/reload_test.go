@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/github"
+	"tgbot/internal/storage"
+)
+
+// reloadRequired обязательные параметры файла конфигурации в формате JSON
+const reloadRequired = `"bot_key": "key", "github_owner": "owner", "github_repo": "repo"`
+
+// startReload загружает конфигурацию из файла content и делает ее действующей, как
+// при запуске бота. Глобальное состояние восстанавливается после теста
+func startReload(t *testing.T, content string) (*bot.ConfigLoader, string) {
+	t.Helper()
+	previousConfig, previousGitHub := activeConfig.Load(), activeGitHubAPI.Load()
+	previousPolicy, previousStore, previousAudit := policy, accessStore, auditLog
+	t.Cleanup(func() {
+		activeConfig.Store(previousConfig)
+		activeGitHubAPI.Store(previousGitHub)
+		policy, accessStore, auditLog = previousPolicy, previousStore, previousAudit
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tgapi.json")
+	writeReloadConfig(t, path, content)
+	loader, err := bot.NewConfigLoader([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeConfig.Store(loaded)
+	activeGitHubAPI.Store(github.NewAPI(loaded.GitHubToken, loaded.GitHubOwner, loaded.GitHubRepo))
+	if policy, err = access.PolicyFromConfig(loaded); err != nil {
+		t.Fatal(err)
+	}
+	accessStore = access.NewStore(storage.NewMemory(), policy)
+	if auditLog, err = audit.Open(filepath.Join(dir, "audit.log")); err != nil {
+		t.Fatal(err)
+	}
+	return loader, path
+}
+
+// writeReloadConfig записывает файл конфигурации с обязательными параметрами и content
+func writeReloadConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("{"+reloadRequired+", "+content+"}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadConfigInvalidFileKeepsConfig(t *testing.T) {
+	loader, path := startReload(t, `"github_token": "token", "allowed_user_ids": [1]`)
+	current, client := config(), githubAPI()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"некорректный JSON", `{"bot_key": `},
+		{"неизвестная роль", `{` + reloadRequired + `, "github_token": "token", "default_role": "superuser"}`},
+		{"нет обязательного параметра", `{"bot_key": "key", "github_token": "token"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := reloadConfig(context.Background(), loader)
+			if err == nil {
+				t.Fatal("ожидается ошибка загрузки конфигурации")
+			}
+			if changed {
+				t.Error("конфигурация с ошибками отмечена как примененная")
+			}
+			if config() != current || githubAPI() != client {
+				t.Error("действующая конфигурация заменена конфигурацией с ошибками")
+			}
+			if !policy.Can(1, 1, access.PermissionView) {
+				t.Error("роли изменились после ошибки загрузки")
+			}
+		})
+	}
+}
+
+func TestReloadConfigRestartOnlyKeys(t *testing.T) {
+	loader, path := startReload(t, `"github_token": "token", "allowed_user_ids": [1], "workers": 4, "release_approvals": 0`)
+	writeReloadConfig(t, path, `"github_token": "token", "allowed_user_ids": [1, 2], "workers": 16, "queue_size": 10, "release_approvals": 2`)
+
+	changed, err := reloadConfig(context.Background(), loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("изменения конфигурации не применены")
+	}
+
+	// Списки доступа и настройки релизов применяются сразу
+	if !reflect.DeepEqual(config().AllowedUserIDs, []int64{1, 2}) || config().ReleaseApprovals != 2 {
+		t.Errorf("allowed_user_ids = %v, release_approvals = %d; ожидается [1 2] и 2", config().AllowedUserIDs, config().ReleaseApprovals)
+	}
+	if !policy.Can(2, 2, access.PermissionView) {
+		t.Error("новый пользователь allow-list не получил доступ")
+	}
+	// Параметры, требующие перезапуска, сохраняют прежние значения
+	if config().Workers != 4 || config().QueueSize != bot.DefaultConfig().QueueSize {
+		t.Errorf("workers = %d, queue_size = %d; ожидаются прежние значения", config().Workers, config().QueueSize)
+	}
+
+	events, err := auditLog.Query(audit.Filter{Action: audit.ActionConfigReload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Details != "allowed_user_ids, release_approvals" {
+		t.Errorf("записи аудита %+v, ожидается одна с allowed_user_ids, release_approvals", events)
+	}
+
+	// Повторная загрузка того же файла ничего не меняет
+	if changed, err := reloadConfig(context.Background(), loader); err != nil || changed {
+		t.Errorf("повторная загрузка: изменено %v, ошибка %v", changed, err)
+	}
+}
+
+func TestReloadConfigGitHubToken(t *testing.T) {
+	loader, path := startReload(t, `"github_token": "old-token"`)
+	client := githubAPI()
+
+	// Изменение параметра, не связанного с GitHub, сохраняет клиент
+	writeReloadConfig(t, path, `"github_token": "old-token", "allowed_user_ids": [1]`)
+	if _, err := reloadConfig(context.Background(), loader); err != nil {
+		t.Fatal(err)
+	}
+	if githubAPI() != client {
+		t.Error("клиент GitHub заменен, хотя подключение не менялось")
+	}
+
+	writeReloadConfig(t, path, `"github_token": "new-token", "allowed_user_ids": [1]`)
+	if _, err := reloadConfig(context.Background(), loader); err != nil {
+		t.Fatal(err)
+	}
+	if githubAPI() == client {
+		t.Error("клиент GitHub не заменен после смены токена")
+	}
+	if config().GitHubToken != "new-token" {
+		t.Errorf("github_token = %q, ожидается новый токен", config().GitHubToken)
+	}
+}
+
+func TestConfigVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tgapi.json")
+	if configVersion(path) != [32]byte{} {
+		t.Error("у отсутствующего файла ненулевая версия")
+	}
+	if err := os.WriteFile(path, []byte(`{"workers": 4}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	first := configVersion(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Запись того же размера с тем же временем изменения все равно замечается
+	if err := os.WriteFile(path, []byte(`{"workers": 8}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if configVersion(path) == first {
+		t.Error("версия не изменилась после изменения содержимого")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"tgbot/internal/access"
+	"tgbot/internal/storage"
+	"tgbot/internal/telegram"
+	"tgbot/internal/totp"
+	"tgbot/pkg/types"
+)
+
+// openStorage открывает хранилище состояния из storage_file и применяет миграции.
+// Без storage_file состояние хранится в памяти до перезапуска
+func openStorage(config *types.BotConfig) (storage.Store, error) {
+	var store storage.Store = storage.NewMemory()
+	if config.StorageFile != "" {
+		bolt, err := storage.Open(config.StorageFile)
+		if err != nil {
+			return nil, err
+		}
+		store = bolt
+	} else {
+		log.Printf("Файл хранилища не задан: состояние бота не сохранится после перезапуска")
+	}
+
+	applied, err := storage.Migrate(store, storageMigrations(config))
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("ошибка обновления хранилища: %w", err)
+	}
+	if applied > 0 {
+		log.Printf("Хранилище обновлено, применено миграций: %d", applied)
+	}
+	return store, nil
+}
+
+// storageMigrations миграции схемы хранилища. Новые миграции добавляются в конец
+// с очередной версией; примененные миграции изменять нельзя
+func storageMigrations(config *types.BotConfig) []storage.Migration {
+	return []storage.Migration{{
+		Version:     1,
+		Description: "перенос состояния из файлов state_file, access_file и totp_file",
+		Apply: func(tx storage.Tx) error {
+			imports := []struct {
+				path       string
+				importFile func(tx storage.Tx, path string) (bool, error)
+			}{
+				{config.StateFile, telegram.ImportOffsetFile},
+				{config.AccessFile, access.ImportFile},
+				{config.TOTPFile, totp.ImportFile},
+			}
+			for _, legacy := range imports {
+				imported, err := legacy.importFile(tx, legacy.path)
+				if err != nil {
+					return err
+				}
+				if imported {
+					log.Printf("Состояние из %s перенесено в хранилище; файл больше не используется", legacy.path)
+				}
+			}
+			return nil
+		},
+	}}
+}
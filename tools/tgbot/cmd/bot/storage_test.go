@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tgbot/internal/access"
+	"tgbot/internal/storage"
+	"tgbot/internal/telegram"
+	"tgbot/internal/totp"
+	"tgbot/pkg/types"
+)
+
+// writeLegacyFile записывает файл состояния прежней версии бота
+func writeLegacyFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStorageMigrationImportsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := &types.BotConfig{
+		StorageFile: filepath.Join(dir, "bot.db"),
+		StateFile:   writeLegacyFile(t, dir, "state.json", `{"offset":42,"updated_at":"2026-10-01T00:00:00Z"}`),
+		AccessFile: writeLegacyFile(t, dir, "access.json", `{
+			"roles": [{"user_id": 5, "role": "admin"}, {"user_id": 6, "chat_id": -100, "role": ""}],
+			"chats": [{"chat_id": -100, "allowed": true}],
+			"usernames": {"alice": 5}
+		}`),
+		TOTPFile: writeLegacyFile(t, dir, "totp.json", `{"7": {"secret": "JBSWY3DPEHPK3PXP", "active": true, "last_counter": 1}}`),
+	}
+
+	store, err := openStorage(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := storage.SchemaVersion(store); err != nil || version != len(storageMigrations(config)) {
+		t.Errorf("версия схемы %d, %v; ожидается %d", version, err, len(storageMigrations(config)))
+	}
+
+	offset, err := telegram.NewStorageOffsetStore(store).LoadOffset()
+	if err != nil || offset != 42 {
+		t.Errorf("смещение %d, %v; ожидается 42", offset, err)
+	}
+
+	policy := access.NewPolicy(access.DefaultRole)
+	accessStore := access.NewStore(store, policy)
+	if err := accessStore.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if role := policy.Role(5, -100); role != access.RoleAdmin {
+		t.Errorf("роль пользователя 5 = %q, ожидается admin", role)
+	}
+	if !policy.ChatAllowed(-100) {
+		t.Error("разрешенный чат не перенесен")
+	}
+	if userID, ok := accessStore.LookupUsername("@alice"); !ok || userID != 5 {
+		t.Errorf("username не перенесен: %d, %v", userID, ok)
+	}
+
+	totpStore := totp.NewStore(store)
+	if err := totpStore.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !totpStore.Enrolled(7) {
+		t.Error("секрет второго фактора не перенесен")
+	}
+
+	// Изменения после переноса сохраняются в хранилище, а не в прежний файл,
+	// и повторный запуск не переносит файл заново
+	if err := telegram.NewStorageOffsetStore(store).SaveOffset(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = openStorage(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if offset, _ := telegram.NewStorageOffsetStore(store).LoadOffset(); offset != 100 {
+		t.Errorf("после перезапуска смещение %d, ожидается 100", offset)
+	}
+}
+
+func TestStorageMigrationWithoutLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := &types.BotConfig{
+		StorageFile: filepath.Join(dir, "bot.db"),
+		StateFile:   filepath.Join(dir, "missing-state.json"),
+		AccessFile:  filepath.Join(dir, "missing-access.json"),
+		TOTPFile:    filepath.Join(dir, "missing-totp.json"),
+	}
+	store, err := openStorage(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	offset, err := telegram.NewStorageOffsetStore(store).LoadOffset()
+	if err != nil || offset != 0 {
+		t.Errorf("смещение %d, %v; ожидается 0", offset, err)
+	}
+}
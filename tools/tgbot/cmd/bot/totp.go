@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"tgbot/internal/access"
+	"tgbot/internal/audit"
+	"tgbot/internal/bot"
+	"tgbot/internal/format"
+	"tgbot/internal/telegram"
+	"tgbot/internal/totp"
+	"tgbot/pkg/types"
+)
+
+// registerTOTPCommands регистрирует команды управления вторым фактором
+func registerTOTPCommands(router *bot.CommandRouter) {
+	router.Register(bot.Command{
+		Name:        "totp",
+		Description: "второй фактор для запуска релизов",
+		Descriptions: map[string]string{
+			"en": "second factor for releases",
+		},
+		Args: []bot.Arg{
+			{Name: "действие", Description: "on — подключить, confirm — подтвердить подключение, off — отключить"},
+			{Name: "код", Description: "текущий код из приложения-аутентификатора"},
+		},
+		Permission: access.PermissionRelease,
+		Handler:    handleTOTPCommand,
+	})
+	router.Register(bot.Command{
+		Name:        "totp_reset",
+		Description: "отключить второй фактор пользователя, потерявшего телефон",
+		Descriptions: map[string]string{
+			"en": "reset a user's second factor",
+		},
+		Args:       []bot.Arg{targetArg},
+		Permission: access.PermissionAdmin,
+		Handler:    handleTOTPResetCommand,
+	})
+}
+
+// totpRequired сообщает, нужно ли пользователю вводить код перед запуском релиза
+func totpRequired(userID int64) bool {
+	return config().ReleaseRequireTOTP || totpStore.Enrolled(userID)
+}
+
+func handleTOTPCommand(ctx context.Context, request *bot.CommandRequest) error {
+	message := request.Message
+	reply := func(text string) error {
+		return api.SendMessage(ctx, message.ChatID, render(format.Plain(text)), nil)
+	}
+
+	code := request.Arg("код")
+	switch action := request.Arg("действие"); action {
+	case "":
+		if totpStore.Enrolled(message.UserID) {
+			return reply("🔐 Второй фактор подключен: перед запуском релиза бот попросит код из приложения.\nОтключить: /totp off <код>")
+		}
+		text := "Второй фактор не подключен. Подключить: /totp on"
+		if config().ReleaseRequireTOTP {
+			text += "\nБез второго фактора запускать релизы нельзя."
+		}
+		return reply(text)
+
+	case "on":
+		// Секрет нельзя показывать в группе: его увидят все участники
+		if message.ChatID != message.UserID {
+			return reply("Подключите второй фактор в личном чате с ботом.")
+		}
+		return enrollTOTP(ctx, message)
+
+	case "confirm":
+		if code == "" {
+			return &bot.UsageError{Command: request.Command, Reason: "Укажите код из приложения"}
+		}
+		deleteCodeMessage(ctx, message)
+		if err := totpStore.Activate(message.UserID, code); err != nil {
+			recordTOTPFailure(ctx, message, "подключение второго фактора", err)
+			return replyTOTPError(err, reply)
+		}
+		bot.Logf(ctx, "Пользователь %d подключил второй фактор", message.UserID)
+		recordAudit(ctx, audit.Event{UserID: message.UserID, ChatID: message.ChatID, Action: audit.ActionTOTPEnroll})
+		return reply("✅ Второй фактор подключен. Перед запуском релиза бот попросит код из приложения.")
+
+	case "off":
+		if code == "" {
+			return &bot.UsageError{Command: request.Command, Reason: "Укажите код из приложения"}
+		}
+		if config().ReleaseRequireTOTP {
+			return reply("Второй фактор обязателен для запуска релизов, отключить его нельзя.")
+		}
+		deleteCodeMessage(ctx, message)
+		if err := totpStore.Disable(message.UserID, code); err != nil {
+			recordTOTPFailure(ctx, message, "отключение второго фактора", err)
+			return replyTOTPError(err, reply)
+		}
+		bot.Logf(ctx, "Пользователь %d отключил второй фактор", message.UserID)
+		recordAudit(ctx, audit.Event{UserID: message.UserID, ChatID: message.ChatID, Action: audit.ActionTOTPDisable})
+		return reply("Второй фактор отключен.")
+
+	default:
+		return &bot.UsageError{Command: request.Command, Reason: "Неизвестное действие: " + action}
+	}
+}
+
+// enrollTOTP создает секрет пользователя и отправляет его QR-кодом для приложения-аутентификатора
+func enrollTOTP(ctx context.Context, message *types.Message) error {
+	secret, err := totpStore.Enroll(message.UserID)
+	if errors.Is(err, totp.ErrAlreadyEnrolled) {
+		return api.SendMessage(ctx, message.ChatID, render(format.Plain("Второй фактор уже подключен. Чтобы подключить заново, отключите его: /totp off <код>")), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	issuer := botUsername
+	if issuer == "" {
+		issuer = "tgbot"
+	}
+	account := strconv.FormatInt(message.UserID, 10)
+	if message.From != nil && message.From.Username != "" {
+		account = message.From.Username
+	}
+	png, err := qrcode.Encode(totp.URI(issuer, account, secret), qrcode.Medium, 256)
+	if err != nil {
+		return fmt.Errorf("ошибка создания QR-кода: %w", err)
+	}
+
+	caption := format.New().
+		Line(format.Bold("🔐 Подключение второго фактора")).
+		Blank().
+		Line(format.Text("Отсканируйте QR-код в приложении-аутентификаторе или введите секрет вручную:")).
+		Line(format.Code(secret)).
+		Blank().
+		Line(format.Text("Затем отправьте текущий код из приложения: /totp confirm <код>")).
+		Line(format.Italic("Удалите это сообщение после подключения."))
+	if _, err := api.SendPhoto(ctx, message.ChatID, telegram.InputFile{Name: "totp.png", Data: png}, render(caption)); err != nil {
+		return fmt.Errorf("ошибка отправки QR-кода: %w", err)
+	}
+	bot.Logf(ctx, "Пользователь %d начал подключение второго фактора", message.UserID)
+	return nil
+}
+
+func handleTOTPResetCommand(ctx context.Context, request *bot.CommandRequest) error {
+	target, rest, err := resolveTarget(ctx, request)
+	if err != nil {
+		return err
+	}
+	if target.isChat {
+		return &bot.UsageError{Command: request.Command, Reason: "Второй фактор подключается пользователю, а не чату"}
+	}
+	if len(rest) > 0 {
+		return &bot.UsageError{Command: request.Command, Reason: "Слишком много аргументов"}
+	}
+
+	if err := totpStore.Reset(target.userID); errors.Is(err, totp.ErrNotEnrolled) {
+		return api.SendMessage(ctx, request.Message.ChatID, render(format.Plain(fmt.Sprintf("У пользователя %s второй фактор не подключен.", userTitle(target.userID)))), nil)
+	} else if err != nil {
+		return err
+	}
+	bot.Logf(ctx, "Пользователь %d отключил второй фактор пользователя %d", request.Message.UserID, target.userID)
+	recordAudit(ctx, audit.Event{
+		UserID: request.Message.UserID,
+		ChatID: request.Message.ChatID,
+		Action: audit.ActionTOTPReset,
+		Target: userTitle(target.userID),
+	})
+	return api.SendMessage(ctx, request.Message.ChatID, render(format.Plain(fmt.Sprintf("Второй фактор пользователя %s отключен.", userTitle(target.userID)))), nil)
+}
+
+// replyTOTPError сообщает пользователю, почему код не принят; прочие ошибки возвращает
+func replyTOTPError(err error, reply func(text string) error) error {
+	switch {
+	case errors.Is(err, totp.ErrNotEnrolled):
+		return reply("Второй фактор не подключен. Подключить: /totp on")
+	case errors.Is(err, totp.ErrAlreadyEnrolled), errors.Is(err, totp.ErrInvalidCode), errors.Is(err, totp.ErrCodeReused):
+		return reply("⛔ " + err.Error())
+	case errors.As(err, new(*totp.LockedError)):
+		return reply("⛔ " + err.Error())
+	default:
+		return err
+	}
+}
+
+// recordTOTPFailure записывает в журнал аудита неверный код второго фактора.
+// Ошибки, не связанные с кодом (второй фактор не подключен), не записываются
+func recordTOTPFailure(ctx context.Context, message *types.Message, target string, err error) {
+	var locked *totp.LockedError
+	if !errors.Is(err, totp.ErrInvalidCode) && !errors.Is(err, totp.ErrCodeReused) && !errors.As(err, &locked) {
+		return
+	}
+	bot.Logf(ctx, "Пользователь %d ввел неверный код второго фактора: %v", message.UserID, err)
+	event := audit.Event{
+		UserID: message.UserID,
+		ChatID: message.ChatID,
+		Action: audit.ActionTOTPFailure,
+		Target: target,
+		Error:  auditError(err),
+	}
+	if locked != nil {
+		event.Details = "ввод кодов заблокирован до " + locked.Until.UTC().Format(time.RFC3339)
+	}
+	recordAudit(ctx, event)
+}
+
+// deleteCodeMessage удаляет сообщение с кодом, чтобы код не остался в истории чата.
+// В группах для этого боту нужны права администратора
+func deleteCodeMessage(ctx context.Context, message *types.Message) {
+	if err := api.DeleteMessage(ctx, message.ChatID, message.MessageID); err != nil {
+		bot.Logf(ctx, "Ошибка удаления сообщения с кодом: %v", err)
+	}
+}
+
+// releaseChallenge запуск релиза, ожидающий кода второго фактора
+type releaseChallenge struct {
+	target    releaseTarget
+	expiresAt time.Time
+}
+
+// releaseChallenges ожидающие кода запуски релиза по пользователю
+var (
+	releaseChallengesMu sync.Mutex
+	releaseChallenges   = make(map[int64]*releaseChallenge)
+)
+
+// requestReleaseCode просит пользователя, подтвердившего релиз, отправить код из
+// приложения-аутентификатора. Релиз запускается в handleReleaseCode
+func requestReleaseCode(ctx context.Context, callback *types.CallbackQuery, target releaseTarget) {
+	if !totpStore.Enrolled(target.userID) {
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+		target.edit(ctx, format.Plain("🔐 Для запуска релиза нужен второй фактор. Подключите его в личном чате с ботом: /totp on"), [][]types.InlineKeyboardButton{backToMainRow()})
+		return
+	}
+	if until, locked := totpStore.Locked(target.userID); locked {
+		if err := api.AnswerCallbackQuery(ctx, callback.ID, ""); err != nil {
+			bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+		}
+		text := fmt.Sprintf("⛔ Слишком много неверных кодов. Запустить релиз можно после %s.", until.Local().Format("02.01.2006 15:04"))
+		target.edit(ctx, format.Plain(text), [][]types.InlineKeyboardButton{backToMainRow()})
+		return
+	}
+
+	challenge := &releaseChallenge{target: target, expiresAt: time.Now().Add(confirmations.TTL())}
+	releaseChallengesMu.Lock()
+	releaseChallenges[target.userID] = challenge
+	releaseChallengesMu.Unlock()
+
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Введите код из приложения"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	text := fmt.Sprintf("🔐 Отправьте в этот чат текущий код из приложения-аутентификатора в течение %s.", formatTTL(confirmations.TTL()))
+	target.edit(ctx, format.Plain(text), [][]types.InlineKeyboardButton{{
+		callbacks.Button("❌ Отмена", actionReleaseCodeCancel),
+	}})
+
+	// Если код так и не введен, запуск отменяется
+	background := context.WithoutCancel(ctx)
+	time.AfterFunc(confirmations.TTL(), func() {
+		if takeReleaseChallenge(target.userID, challenge) {
+			log.Printf("Пользователь %d не ввел код второго фактора, запуск релиза отменен", target.userID)
+			target.edit(background, format.Plain("⌛ Время на ввод кода истекло, запуск релиза отменен."), [][]types.InlineKeyboardButton{backToMainRow()})
+		}
+	})
+}
+
+// handleReleaseCode принимает код второго фактора для ожидающего запуска релиза.
+// Возвращает false, если сообщение не похоже на код или код от пользователя не ожидается
+func handleReleaseCode(ctx context.Context, message *types.Message) bool {
+	if message.Forwarded || !isCode(message.Text) {
+		return false
+	}
+	releaseChallengesMu.Lock()
+	challenge, ok := releaseChallenges[message.UserID]
+	releaseChallengesMu.Unlock()
+	if !ok || challenge.target.chatID != message.ChatID {
+		return false
+	}
+
+	deleteCodeMessage(ctx, message)
+	if time.Now().After(challenge.expiresAt) {
+		return true
+	}
+
+	err := totpStore.Verify(message.UserID, message.Text)
+	if err != nil {
+		recordTOTPFailure(ctx, message, "запуск релиза", err)
+
+		var locked *totp.LockedError
+		switch {
+		case errors.As(err, &locked):
+			if takeReleaseChallenge(message.UserID, challenge) {
+				challenge.target.edit(ctx, format.Plain("⛔ "+locked.Error()+". Запуск релиза отменен."), [][]types.InlineKeyboardButton{backToMainRow()})
+			}
+			return true
+		case errors.Is(err, totp.ErrInvalidCode):
+			err = fmt.Errorf("%w. Осталось попыток до блокировки: %d", err, totpStore.Remaining(message.UserID))
+		case !errors.Is(err, totp.ErrCodeReused):
+			// Второй фактор отключен администратором, пока ожидался код
+			if takeReleaseChallenge(message.UserID, challenge) {
+				challenge.target.edit(ctx, format.Plain("⛔ "+err.Error()+". Запуск релиза отменен."), [][]types.InlineKeyboardButton{backToMainRow()})
+			}
+			return true
+		}
+		text := fmt.Sprintf("⛔ %v.", err)
+		if err := api.SendMessage(ctx, message.ChatID, render(format.Plain(text)), nil); err != nil {
+			bot.Logf(ctx, "Ошибка отправки сообщения: %v", err)
+		}
+		return true
+	}
+
+	if !takeReleaseChallenge(message.UserID, challenge) {
+		return true
+	}
+	bot.Logf(ctx, "Пользователь %d подтвердил запуск релиза кодом второго фактора", message.UserID)
+	startRelease(ctx, challenge.target)
+	return true
+}
+
+// handleReleaseCodeCancel отменяет запуск релиза, ожидающий кода
+func handleReleaseCodeCancel(ctx context.Context, callback *types.CallbackQuery, _ bot.CallbackData) {
+	releaseChallengesMu.Lock()
+	challenge, ok := releaseChallenges[callback.UserID]
+	releaseChallengesMu.Unlock()
+	if !ok || challenge.target.chatID != callback.ChatID || challenge.target.messageID != callback.MessageID {
+		if err := api.ShowAlert(ctx, callback.ID, "Отменить запуск может только пользователь, который его начал"); err != nil {
+			bot.Logf(ctx, "Ошибка отправки алерта: %v", err)
+		}
+		return
+	}
+
+	takeReleaseChallenge(callback.UserID, challenge)
+	if err := api.AnswerCallbackQuery(ctx, callback.ID, "Создание релиза отменено"); err != nil {
+		bot.Logf(ctx, "Ошибка ответа на callback: %v", err)
+	}
+	editMessage(ctx, callback, format.Plain("Создание релиза отменено."), [][]types.InlineKeyboardButton{backToMainRow()})
+}
+
+// takeReleaseChallenge удаляет ожидание кода, если оно еще не завершено, и сообщает,
+// удалось ли это: так запуск выполняется не более одного раза
+func takeReleaseChallenge(userID int64, challenge *releaseChallenge) bool {
+	releaseChallengesMu.Lock()
+	defer releaseChallengesMu.Unlock()
+	if releaseChallenges[userID] != challenge {
+		return false
+	}
+	delete(releaseChallenges, userID)
+	return true
+}
+
+// isCode сообщает, похож ли текст на код из приложения: цифры, возможно с пробелами
+func isCode(text string) bool {
+	digits := 0
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r != ' ':
+			return false
+		}
+	}
+	return digits == totp.Digits
+}
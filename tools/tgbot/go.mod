@@ -1,3 +1,12 @@
 module tgbot
 
 go 1.21
+
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	go.etcd.io/bbolt v1.3.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,296 @@
+// Package access реализует ролевую модель доступа к боту: пользователям назначаются
+// роли (глобально или в отдельном чате), а роли дают права на действия
+package access
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Permission право на действие в боте
+type Permission string
+
+const (
+	// PermissionPublic действие доступно любому пользователю
+	PermissionPublic Permission = ""
+	// PermissionView просмотр веток, PR и релизов
+	PermissionView Permission = "view"
+	// PermissionDevelop действия разработчика: закрытие PR, удаление веток
+	PermissionDevelop Permission = "develop"
+	// PermissionRelease запуск релиза
+	PermissionRelease Permission = "release"
+	// PermissionAdmin управление доступом к боту
+	PermissionAdmin Permission = "admin"
+)
+
+// Role роль пользователя
+type Role string
+
+const (
+	// RoleNone у пользователя нет доступа к боту
+	RoleNone Role = ""
+	// RoleViewer просмотр веток, PR и релизов (например, QA)
+	RoleViewer Role = "viewer"
+	// RoleDeveloper просмотр и действия с ветками и PR
+	RoleDeveloper Role = "developer"
+	// RoleReleaseManager все действия разработчика и запуск релиза
+	RoleReleaseManager Role = "release_manager"
+	// RoleAdmin все права, включая управление доступом
+	RoleAdmin Role = "admin"
+)
+
+// roles роли в порядке возрастания прав
+var roles = []Role{RoleViewer, RoleDeveloper, RoleReleaseManager, RoleAdmin}
+
+// rolePermissions права, которые дает каждая роль
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:         {PermissionView},
+	RoleDeveloper:      {PermissionView, PermissionDevelop},
+	RoleReleaseManager: {PermissionView, PermissionDevelop, PermissionRelease},
+	RoleAdmin:          {PermissionView, PermissionDevelop, PermissionRelease, PermissionAdmin},
+}
+
+// roleTitles названия ролей для сообщений
+var roleTitles = map[Role]string{
+	RoleNone:           "нет доступа",
+	RoleViewer:         "наблюдатель",
+	RoleDeveloper:      "разработчик",
+	RoleReleaseManager: "релиз-менеджер",
+	RoleAdmin:          "администратор",
+}
+
+// Roles возвращает все роли в порядке возрастания прав
+func Roles() []Role {
+	return append([]Role(nil), roles...)
+}
+
+// Rank возвращает положение роли в порядке возрастания прав: 0 у RoleNone и
+// неизвестных ролей, 1 у наблюдателя и так далее
+func (r Role) Rank() int {
+	for i, role := range roles {
+		if role == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ParseRole разбирает имя роли
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := rolePermissions[role]; !ok {
+		return RoleNone, fmt.Errorf("неизвестная роль %q", name)
+	}
+	return role, nil
+}
+
+// Can сообщает, дает ли роль право permission
+func (r Role) Can(permission Permission) bool {
+	if permission == PermissionPublic {
+		return true
+	}
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Title возвращает название роли для сообщений
+func (r Role) Title() string {
+	if title, ok := roleTitles[r]; ok {
+		return title
+	}
+	return string(r)
+}
+
+// chatUser ключ роли пользователя в конкретном чате
+type chatUser struct {
+	chatID int64
+	userID int64
+}
+
+// Policy разрешенные чаты и назначения ролей. Роль пользователя в чате определяется
+// так: роль, назначенная в этом чате, затем глобальная роль, затем роль по умолчанию
+// для пользователей из списка allowed_user_ids
+type Policy struct {
+	mu          sync.RWMutex
+	defaultRole Role
+	allowed     map[int64]bool
+	global      map[int64]Role
+	chatRoles   map[chatUser]Role
+	chats       map[int64]bool
+}
+
+// NewPolicy создает пустую политику; defaultRole назначается пользователям, добавленным через Allow
+func NewPolicy(defaultRole Role) *Policy {
+	return &Policy{
+		defaultRole: defaultRole,
+		allowed:     make(map[int64]bool),
+		global:      make(map[int64]Role),
+		chatRoles:   make(map[chatUser]Role),
+		chats:       make(map[int64]bool),
+	}
+}
+
+// DefaultRole возвращает роль пользователей, добавленных через Allow
+func (p *Policy) DefaultRole() Role {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.defaultRole
+}
+
+// Replace заменяет все назначения политики назначениями other, например после
+// перезагрузки конфигурации. Проверки доступа видят либо старые, либо новые
+// назначения целиком
+func (p *Policy) Replace(other *Policy) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.defaultRole = other.defaultRole
+	p.allowed = maps.Clone(other.allowed)
+	p.global = maps.Clone(other.global)
+	p.chatRoles = maps.Clone(other.chatRoles)
+	p.chats = maps.Clone(other.chats)
+}
+
+// Allow разрешает доступ пользователю с ролью по умолчанию
+func (p *Policy) Allow(userID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allowed[userID] = true
+}
+
+// Assign назначает пользователю роль во всех чатах (chatID = 0) или в одном чате.
+// RoleNone в чате запрещает пользователю доступ в этом чате
+func (p *Policy) Assign(userID, chatID int64, role Role) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if chatID == 0 {
+		p.global[userID] = role
+		return
+	}
+	p.chatRoles[chatUser{chatID: chatID, userID: userID}] = role
+}
+
+// Unassign удаляет назначение роли пользователю глобально (chatID = 0) или в одном чате
+func (p *Policy) Unassign(userID, chatID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if chatID == 0 {
+		delete(p.global, userID)
+		return
+	}
+	delete(p.chatRoles, chatUser{chatID: chatID, userID: userID})
+}
+
+// Role возвращает роль пользователя в чате
+func (p *Policy) Role(userID, chatID int64) Role {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if role, ok := p.chatRoles[chatUser{chatID: chatID, userID: userID}]; ok {
+		return role
+	}
+	if role, ok := p.global[userID]; ok {
+		return role
+	}
+	if p.allowed[userID] {
+		return p.defaultRole
+	}
+	return RoleNone
+}
+
+// UserChats возвращает чаты, в которых пользователю назначена отдельная роль
+func (p *Policy) UserChats(userID int64) []int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var chats []int64
+	for key := range p.chatRoles {
+		if key.userID == userID {
+			chats = append(chats, key.chatID)
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	return chats
+}
+
+// Can сообщает, есть ли у пользователя право permission в чате
+func (p *Policy) Can(userID, chatID int64, permission Permission) bool {
+	if permission == PermissionPublic {
+		return true
+	}
+	return p.Role(userID, chatID).Can(permission)
+}
+
+// Users возвращает пользователей, которым назначена роль или разрешен доступ
+// и доступ не отозван
+func (p *Policy) Users() []int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	seen := make(map[int64]bool)
+	for userID := range p.allowed {
+		seen[userID] = true
+	}
+	for userID, role := range p.global {
+		if role == RoleNone {
+			delete(seen, userID)
+			continue
+		}
+		seen[userID] = true
+	}
+	for key, role := range p.chatRoles {
+		if role != RoleNone {
+			seen[key.userID] = true
+		}
+	}
+
+	users := make([]int64, 0, len(seen))
+	for userID := range seen {
+		users = append(users, userID)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users
+}
+
+// AllowChat разрешает использование бота в чате
+func (p *Policy) AllowChat(chatID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chats[chatID] = true
+}
+
+// RevokeChat запрещает использование бота в чате
+func (p *Policy) RevokeChat(chatID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.chats, chatID)
+}
+
+// ChatAllowed сообщает, разрешено ли использование бота в чате
+func (p *Policy) ChatAllowed(chatID int64) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.chats[chatID]
+}
+
+// Chats возвращает разрешенные чаты
+func (p *Policy) Chats() []int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	chats := make([]int64, 0, len(p.chats))
+	for chatID := range p.chats {
+		chats = append(chats, chatID)
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	return chats
+}
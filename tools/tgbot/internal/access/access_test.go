@@ -0,0 +1,220 @@
+package access
+
+import (
+	"reflect"
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+func TestRolePermissions(t *testing.T) {
+	permissions := []Permission{PermissionPublic, PermissionView, PermissionDevelop, PermissionRelease, PermissionAdmin}
+	tests := []struct {
+		role Role
+		want []bool
+	}{
+		{RoleNone, []bool{true, false, false, false, false}},
+		{RoleViewer, []bool{true, true, false, false, false}},
+		{RoleDeveloper, []bool{true, true, true, false, false}},
+		{RoleReleaseManager, []bool{true, true, true, true, false}},
+		{RoleAdmin, []bool{true, true, true, true, true}},
+		{Role("owner"), []bool{true, false, false, false, false}},
+	}
+	for _, tt := range tests {
+		for i, permission := range permissions {
+			if got := tt.role.Can(permission); got != tt.want[i] {
+				t.Errorf("роль %q, право %q: %v, ожидается %v", tt.role, permission, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRoleRank(t *testing.T) {
+	tests := []struct {
+		role Role
+		want int
+	}{
+		{RoleNone, 0},
+		{Role("owner"), 0},
+		{RoleViewer, 1},
+		{RoleDeveloper, 2},
+		{RoleReleaseManager, 3},
+		{RoleAdmin, 4},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Rank(); got != tt.want {
+			t.Errorf("Rank(%q) = %d, ожидается %d", tt.role, got, tt.want)
+		}
+	}
+
+	// Ранг растет вместе с набором прав: старшая роль может все, что может младшая
+	all := Roles()
+	for i := 1; i < len(all); i++ {
+		lower, higher := all[i-1], all[i]
+		if lower.Rank() >= higher.Rank() {
+			t.Errorf("ранг %q не меньше ранга %q", lower, higher)
+		}
+		for _, permission := range rolePermissions[lower] {
+			if !higher.Can(permission) {
+				t.Errorf("роль %q не может %q, хотя это может %q", higher, permission, lower)
+			}
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Role
+		wantErr bool
+	}{
+		{"viewer", RoleViewer, false},
+		{" Release_Manager ", RoleReleaseManager, false},
+		{"ADMIN", RoleAdmin, false},
+		{"", RoleNone, true},
+		{"owner", RoleNone, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRole(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseRole(%q) = %q, %v; ожидается %q, ошибка %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPolicyRoleResolution(t *testing.T) {
+	const (
+		legacyUser  = 1
+		globalUser  = 2
+		chatAdmin   = 3
+		revokedUser = 4
+		stranger    = 5
+		chatID      = -100
+		otherChatID = -200
+		unknownChat = -300
+	)
+	policy, err := PolicyFromConfig(&types.BotConfig{
+		AllowedUserIDs: []int64{legacyUser, globalUser, revokedUser},
+		AllowedChatIDs: []int64{chatID, otherChatID},
+		Roles: []types.RoleAssignment{
+			{UserID: globalUser, Role: "developer"},
+			{UserID: globalUser, ChatID: chatID, Role: "viewer"},
+			{UserID: chatAdmin, ChatID: chatID, Role: "admin"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Assign(revokedUser, chatID, RoleNone)
+
+	tests := []struct {
+		name   string
+		userID int64
+		chatID int64
+		want   Role
+	}{
+		{"пользователь allow-list получает роль по умолчанию", legacyUser, chatID, DefaultRole},
+		{"глобальная роль важнее роли по умолчанию", globalUser, otherChatID, RoleDeveloper},
+		{"роль в чате важнее глобальной", globalUser, chatID, RoleViewer},
+		{"роль только в своем чате", chatAdmin, chatID, RoleAdmin},
+		{"в других чатах роли нет", chatAdmin, otherChatID, RoleNone},
+		{"RoleNone в чате отзывает доступ в чате", revokedUser, chatID, RoleNone},
+		{"в остальных чатах доступ остается", revokedUser, otherChatID, DefaultRole},
+		{"неизвестный пользователь", stranger, chatID, RoleNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Role(tt.userID, tt.chatID); got != tt.want {
+				t.Errorf("роль %q, ожидается %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, want := policy.Chats(), []int64{otherChatID, chatID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("разрешенные чаты %v, ожидается %v", got, want)
+	}
+	if policy.ChatAllowed(unknownChat) {
+		t.Errorf("чат %d не должен быть разрешен", unknownChat)
+	}
+}
+
+func TestPolicyCan(t *testing.T) {
+	const (
+		viewer  = 1
+		manager = 2
+		chatID  = -100
+	)
+	policy := NewPolicy(DefaultRole)
+	policy.Assign(viewer, 0, RoleViewer)
+	policy.Assign(manager, 0, RoleReleaseManager)
+	policy.Assign(manager, chatID, RoleViewer)
+
+	tests := []struct {
+		name       string
+		userID     int64
+		chatID     int64
+		permission Permission
+		want       bool
+	}{
+		{"наблюдатель не запускает релиз", viewer, 0, PermissionRelease, false},
+		{"наблюдатель не удаляет ветки", viewer, 0, PermissionDevelop, false},
+		{"наблюдатель смотрит списки", viewer, 0, PermissionView, true},
+		{"релиз-менеджер запускает релиз", manager, 0, PermissionRelease, true},
+		{"роль в чате ограничивает права", manager, chatID, PermissionRelease, false},
+		{"публичное действие доступно всем", 42, chatID, PermissionPublic, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Can(tt.userID, tt.chatID, tt.permission); got != tt.want {
+				t.Errorf("Can = %v, ожидается %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFromConfigDefaultRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultRole string
+		want        Role
+	}{
+		{"не задана: права как до появления ролей", "", RoleReleaseManager},
+		{"задана в конфигурации", "viewer", RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := PolicyFromConfig(&types.BotConfig{AllowedUserIDs: []int64{1}, DefaultRole: tt.defaultRole})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := policy.DefaultRole(); got != tt.want {
+				t.Errorf("роль по умолчанию %q, ожидается %q", got, tt.want)
+			}
+			if got := policy.Role(1, -100); got != tt.want {
+				t.Errorf("роль пользователя allow-list %q, ожидается %q", got, tt.want)
+			}
+		})
+	}
+
+	if DefaultRole != RoleReleaseManager {
+		t.Errorf("DefaultRole = %q, ожидается %q", DefaultRole, RoleReleaseManager)
+	}
+}
+
+func TestPolicyFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.BotConfig
+	}{
+		{"неизвестная роль по умолчанию", types.BotConfig{DefaultRole: "owner"}},
+		{"неизвестная роль", types.BotConfig{Roles: []types.RoleAssignment{{UserID: 1, Role: "owner"}}}},
+		{"нет user_id", types.BotConfig{Roles: []types.RoleAssignment{{Role: "viewer"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PolicyFromConfig(&tt.config); err == nil {
+				t.Error("ожидается ошибка")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+
+	"tgbot/pkg/types"
+)
+
+// DefaultRole роль пользователей из allowed_user_ids, если default_role не задан.
+// Совпадает с правами, которые у них были до появления ролей
+const DefaultRole = RoleReleaseManager
+
+// PolicyFromConfig создает политику доступа из конфигурации
+func PolicyFromConfig(config *types.BotConfig) (*Policy, error) {
+	defaultRole := DefaultRole
+	if config.DefaultRole != "" {
+		role, err := ParseRole(config.DefaultRole)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в параметре default_role: %w", err)
+		}
+		defaultRole = role
+	}
+
+	policy := NewPolicy(defaultRole)
+	for _, userID := range config.AllowedUserIDs {
+		policy.Allow(userID)
+	}
+	for _, chatID := range config.AllowedChatIDs {
+		policy.AllowChat(chatID)
+	}
+
+	var errs []error
+	for i, assignment := range config.Roles {
+		if assignment.UserID == 0 {
+			errs = append(errs, fmt.Errorf("roles[%d]: не указан user_id", i))
+			continue
+		}
+		role, err := ParseRole(assignment.Role)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("roles[%d]: %w", i, err))
+			continue
+		}
+		policy.Assign(assignment.UserID, assignment.ChatID, role)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("ошибка в назначениях ролей: %w", errors.Join(errs...))
+	}
+
+	return policy, nil
+}
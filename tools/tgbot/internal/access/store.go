@@ -0,0 +1,411 @@
+package access
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tgbot/internal/storage"
+	"tgbot/pkg/types"
+)
+
+// Бакет и ключ, в которых хранятся изменения доступа
+const (
+	storeBucket = "access"
+	storeKey    = "state"
+)
+
+// Store изменения доступа, сделанные администраторами из Telegram. Изменения сохраняются
+// в хранилище и только после этого применяются к политике, поэтому переживают перезапуск
+// бота и имеют приоритет над конфигурацией
+type Store struct {
+	mu     sync.Mutex
+	repo   *storage.Repository[storeState]
+	policy *Policy
+	state  storeState
+	now    func() time.Time
+}
+
+// storeState содержимое файла доступа
+type storeState struct {
+	Roles       []roleOverride   `json:"roles"`
+	Chats       []chatOverride   `json:"chats"`
+	Usernames   map[string]int64 `json:"usernames"`
+	Invites     []Invite         `json:"invites"`
+	Invitations []Invitation     `json:"invitations"`
+}
+
+// roleOverride назначение роли; пустая роль означает, что доступ отозван
+type roleOverride struct {
+	UserID int64 `json:"user_id"`
+	ChatID int64 `json:"chat_id,omitempty"`
+	Role   Role  `json:"role"`
+}
+
+// chatOverride разрешение или запрет использования бота в чате
+type chatOverride struct {
+	ChatID  int64 `json:"chat_id"`
+	Allowed bool  `json:"allowed"`
+}
+
+// clone возвращает копию состояния, которую можно изменять, не затрагивая исходное
+func (st storeState) clone() storeState {
+	return storeState{
+		Roles:       slices.Clone(st.Roles),
+		Chats:       slices.Clone(st.Chats),
+		Usernames:   maps.Clone(st.Usernames),
+		Invites:     slices.Clone(st.Invites),
+		Invitations: slices.Clone(st.Invitations),
+	}
+}
+
+// setRole записывает назначение роли, заменяя прежнее назначение в том же чате
+func (st *storeState) setRole(override roleOverride) {
+	for i, existing := range st.Roles {
+		if existing.UserID == override.UserID && existing.ChatID == override.ChatID {
+			st.Roles[i] = override
+			return
+		}
+	}
+	st.Roles = append(st.Roles, override)
+}
+
+// pruneInvites удаляет просроченные приглашения
+func (st *storeState) pruneInvites(now time.Time) {
+	invites := st.Invites[:0]
+	for _, invite := range st.Invites {
+		if now.Before(invite.ExpiresAt) {
+			invites = append(invites, invite)
+		}
+	}
+	st.Invites = invites
+}
+
+// NewStore создает хранилище изменений доступа в store
+func NewStore(store storage.Store, policy *Policy) *Store {
+	return &Store{
+		repo:   storage.NewRepository[storeState](store, storeBucket),
+		policy: policy,
+		state:  storeState{Usernames: make(map[string]int64)},
+		now:    time.Now,
+	}
+}
+
+// ImportFile переносит изменения доступа из файла прежних версий бота
+func ImportFile(tx storage.Tx, path string) (bool, error) {
+	return storage.ImportFile(tx, storeBucket, storeKey, path)
+}
+
+// Load читает сохраненные изменения и применяет их к политике
+func (s *Store) Load() error {
+	state, err := s.repo.Get(storeKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения изменений доступа: %w", err)
+	}
+	for _, override := range state.Roles {
+		if _, ok := rolePermissions[override.Role]; !ok && override.Role != RoleNone {
+			return fmt.Errorf("ошибка в изменениях доступа: неизвестная роль %q", override.Role)
+		}
+	}
+	if state.Usernames == nil {
+		state.Usernames = make(map[string]int64)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	s.apply(s.policy)
+	return nil
+}
+
+// ReplacePolicy применяет сохраненные изменения доступа к политике policy, созданной
+// из новой конфигурации, и заменяет ею текущую политику
+func (s *Store) ReplacePolicy(policy *Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apply(policy)
+	s.policy.Replace(policy)
+}
+
+// apply применяет сохраненные изменения к политике policy; вызывается под s.mu
+func (s *Store) apply(policy *Policy) {
+	for _, override := range s.state.Roles {
+		policy.Assign(override.UserID, override.ChatID, override.Role)
+	}
+	for _, override := range s.state.Chats {
+		if override.Allowed {
+			policy.AllowChat(override.ChatID)
+		} else {
+			policy.RevokeChat(override.ChatID)
+		}
+	}
+}
+
+// SetRole назначает пользователю роль глобально (chatID = 0) или в одном чате
+func (s *Store) SetRole(userID, chatID int64, role Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.update(func(state *storeState) {
+		state.setRole(roleOverride{UserID: userID, ChatID: chatID, Role: role})
+	})
+	if err != nil {
+		return err
+	}
+	s.policy.Assign(userID, chatID, role)
+	return nil
+}
+
+// Revoke отзывает доступ пользователя во всех чатах, в том числе назначенный в конфигурации
+func (s *Store) Revoke(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Роль в чате проверяется раньше глобальной, поэтому каждую роль в отдельном чате,
+	// в том числе из конфигурации, отзываем явно. Запрет сохраняется и применяется
+	// заново после перезагрузки конфигурации
+	chats := s.policy.UserChats(userID)
+	err := s.update(func(state *storeState) {
+		state.Roles = slices.DeleteFunc(state.Roles, func(override roleOverride) bool {
+			return override.UserID == userID
+		})
+		for _, chatID := range chats {
+			state.Roles = append(state.Roles, roleOverride{UserID: userID, ChatID: chatID, Role: RoleNone})
+		}
+		state.Roles = append(state.Roles, roleOverride{UserID: userID, Role: RoleNone})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chatID := range chats {
+		s.policy.Assign(userID, chatID, RoleNone)
+	}
+	s.policy.Assign(userID, 0, RoleNone)
+	return nil
+}
+
+// SetChat разрешает или запрещает использование бота в чате
+func (s *Store) SetChat(chatID int64, allowed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.update(func(state *storeState) {
+		override := chatOverride{ChatID: chatID, Allowed: allowed}
+		if i := slices.IndexFunc(state.Chats, func(existing chatOverride) bool { return existing.ChatID == chatID }); i >= 0 {
+			state.Chats[i] = override
+			return
+		}
+		state.Chats = append(state.Chats, override)
+	})
+	if err != nil {
+		return err
+	}
+
+	if allowed {
+		s.policy.AllowChat(chatID)
+	} else {
+		s.policy.RevokeChat(chatID)
+	}
+	return nil
+}
+
+// RememberUser запоминает username пользователя, чтобы администраторы могли
+// указывать пользователей по @username (Bot API не позволяет найти пользователя по нему)
+func (s *Store) RememberUser(user *types.User) error {
+	if user == nil || user.Username == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := normalizeUsername(user.Username)
+	if s.state.Usernames[username] == user.ID {
+		return nil
+	}
+	return s.update(func(state *storeState) {
+		// Пользователь мог сменить username: старый больше ему не принадлежит
+		for name, id := range state.Usernames {
+			if id == user.ID {
+				delete(state.Usernames, name)
+			}
+		}
+		state.Usernames[username] = user.ID
+	})
+}
+
+// LookupUsername возвращает идентификатор пользователя по @username, если бот его видел
+func (s *Store) LookupUsername(username string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userID, ok := s.state.Usernames[normalizeUsername(username)]
+	return userID, ok
+}
+
+// Username возвращает известный боту username пользователя
+func (s *Store) Username(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for username, id := range s.state.Usernames {
+		if id == userID {
+			return username
+		}
+	}
+	return ""
+}
+
+// DefaultInviteTTL срок действия приглашения по умолчанию
+const DefaultInviteTTL = 72 * time.Hour
+
+// ErrInviteInvalid приглашение не существует, уже использовано или истек его срок
+var ErrInviteInvalid = errors.New("приглашение недействительно или уже использовано")
+
+// Invite одноразовое приглашение, выдающее роль пользователю, который его использует
+type Invite struct {
+	Token string `json:"token"`
+	Role  Role   `json:"role"`
+	// ChatID группа, в которой действует роль; 0 — роль во всех чатах
+	ChatID    int64     `json:"chat_id,omitempty"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Invitation запись о том, кто пригласил пользователя
+type Invitation struct {
+	UserID    int64     `json:"user_id"`
+	InvitedBy int64     `json:"invited_by"`
+	Role      Role      `json:"role"`
+	ChatID    int64     `json:"chat_id,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// CreateInvite создает приглашение с ролью role от пользователя createdBy. Если chatID
+// не 0, роль будет действовать только в этом чате
+func (s *Store) CreateInvite(role Role, createdBy, chatID int64, ttl time.Duration) (Invite, error) {
+	if ttl <= 0 {
+		ttl = DefaultInviteTTL
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return Invite{}, fmt.Errorf("ошибка генерации приглашения: %w", err)
+	}
+
+	now := s.now().UTC()
+	invite := Invite{
+		// Параметр start ссылки допускает только символы A-Z, a-z, 0-9, _ и -
+		Token:     base64.RawURLEncoding.EncodeToString(buf),
+		Role:      role,
+		ChatID:    chatID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.update(func(state *storeState) {
+		state.pruneInvites(now)
+		state.Invites = append(state.Invites, invite)
+	})
+	if err != nil {
+		return Invite{}, err
+	}
+	return invite, nil
+}
+
+// RedeemInvite использует приглашение: назначает пользователю userID роль из
+// приглашения и запоминает, кто его пригласил. Повторно приглашение не действует.
+// Роль из приглашения в группу действует только в этой группе и в личном чате
+// пользователя с ботом, где приглашение используется
+func (s *Store) RedeemInvite(token string, userID int64) (Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	var (
+		invite Invite
+		found  bool
+	)
+	err := s.update(func(state *storeState) {
+		state.pruneInvites(now)
+		i := slices.IndexFunc(state.Invites, func(invite Invite) bool { return invite.Token == token })
+		if i < 0 {
+			return
+		}
+		invite, found = state.Invites[i], true
+		state.Invites = slices.Delete(state.Invites, i, i+1)
+		for _, chatID := range invite.roleChats(userID) {
+			state.setRole(roleOverride{UserID: userID, ChatID: chatID, Role: invite.Role})
+		}
+		state.Invitations = append(state.Invitations, Invitation{
+			UserID:    userID,
+			InvitedBy: invite.CreatedBy,
+			Role:      invite.Role,
+			ChatID:    invite.ChatID,
+			At:        now,
+		})
+	})
+	if err != nil {
+		return Invite{}, err
+	}
+	if !found {
+		return Invite{}, ErrInviteInvalid
+	}
+	for _, chatID := range invite.roleChats(userID) {
+		s.policy.Assign(userID, chatID, invite.Role)
+	}
+	return invite, nil
+}
+
+// roleChats возвращает чаты, в которых приглашение назначает роль пользователю userID:
+// 0 (все чаты) или группу приглашения и личный чат пользователя с ботом
+func (i Invite) roleChats(userID int64) []int64 {
+	if i.ChatID == 0 {
+		return []int64{0}
+	}
+	return []int64{i.ChatID, userID}
+}
+
+// InvitedBy возвращает последнюю запись о приглашении пользователя
+func (s *Store) InvitedBy(userID int64) (Invitation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.state.Invitations) - 1; i >= 0; i-- {
+		if s.state.Invitations[i].UserID == userID {
+			return s.state.Invitations[i], true
+		}
+	}
+	return Invitation{}, false
+}
+
+// update применяет изменение change к копии состояния и сохраняет копию. Текущее
+// состояние заменяется копией, только если сохранение удалось, поэтому при ошибке
+// хранилища ни состояние, ни политика не меняются; вызывается под s.mu
+func (s *Store) update(change func(state *storeState)) error {
+	state := s.state.clone()
+	change(&state)
+	if err := s.repo.Put(storeKey, state); err != nil {
+		return fmt.Errorf("ошибка сохранения изменений доступа: %w", err)
+	}
+	s.state = state
+	return nil
+}
+
+// normalizeUsername приводит username к виду без @ в нижнем регистре
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
@@ -0,0 +1,296 @@
+package access
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tgbot/internal/storage"
+	"tgbot/pkg/types"
+)
+
+func TestStoreRevokeConfigChatRole(t *testing.T) {
+	const (
+		userID      = 100
+		chatID      = -200
+		otherChatID = -300
+	)
+	config := &types.BotConfig{
+		AllowedUserIDs: []int64{userID},
+		Roles: []types.RoleAssignment{
+			{UserID: userID, ChatID: chatID, Role: string(RoleAdmin)},
+			{UserID: userID, Role: string(RoleDeveloper)},
+		},
+	}
+
+	policy, err := PolicyFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := storage.NewMemory()
+	store := NewStore(backend, policy)
+	if err := store.SetRole(userID, otherChatID, RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(userID); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(stage string, policy *Policy) {
+		t.Helper()
+		for _, chat := range []int64{0, chatID, otherChatID, -400} {
+			if role := policy.Role(userID, chat); role != RoleNone {
+				t.Errorf("%s: роль в чате %d = %q, ожидается отзыв доступа", stage, chat, role)
+			}
+		}
+		for _, user := range policy.Users() {
+			if user == userID {
+				t.Errorf("%s: пользователь остался в списке пользователей", stage)
+			}
+		}
+	}
+	check("после /revoke", policy)
+
+	// Перезагрузка конфигурации не возвращает роль из конфигурации
+	reloaded, err := PolicyFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.ReplacePolicy(reloaded)
+	check("после перезагрузки конфигурации", policy)
+
+	// Запрет сохраняется в хранилище и применяется после перезапуска
+	restarted, err := PolicyFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewStore(backend, restarted).Load(); err != nil {
+		t.Fatal(err)
+	}
+	check("после перезапуска", restarted)
+}
+
+// failingStore хранилище, в котором можно включить ошибки записи
+type failingStore struct {
+	storage.Store
+	fail bool
+}
+
+func (s *failingStore) Update(fn func(tx storage.Tx) error) error {
+	if s.fail {
+		return errors.New("диск заполнен")
+	}
+	return s.Store.Update(fn)
+}
+
+func TestStoreSaveFailureKeepsState(t *testing.T) {
+	const (
+		userID = 100
+		chatID = -200
+	)
+	policy := NewPolicy(DefaultRole)
+	policy.Assign(userID, 0, RoleViewer)
+	policy.AllowChat(chatID)
+	backend := &failingStore{Store: storage.NewMemory()}
+	store := NewStore(backend, policy)
+	if err := store.RememberUser(&types.User{ID: userID, Username: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRole(userID, chatID, RoleDeveloper); err != nil {
+		t.Fatal(err)
+	}
+	invite, err := store.CreateInvite(RoleAdmin, userID, 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := storage.Get(backend, storeBucket, storeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend.fail = true
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"назначение роли", func() error { return store.SetRole(userID, 0, RoleAdmin) }},
+		{"назначение роли в чате", func() error { return store.SetRole(userID, chatID, RoleNone) }},
+		{"отзыв доступа", func() error { return store.Revoke(userID) }},
+		{"запрет чата", func() error { return store.SetChat(chatID, false) }},
+		{"разрешение чата", func() error { return store.SetChat(-300, true) }},
+		{"смена username", func() error { return store.RememberUser(&types.User{ID: userID, Username: "bob"}) }},
+		{"создание приглашения", func() error { _, err := store.CreateInvite(RoleViewer, userID, 0, time.Hour); return err }},
+		{"использование приглашения", func() error { _, err := store.RedeemInvite(invite.Token, 300); return err }},
+	}
+	for _, tt := range changes {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err == nil {
+				t.Fatal("ошибка хранилища не возвращена")
+			}
+
+			// Ни политика, ни состояние в памяти не изменились
+			if role := policy.Role(userID, 0); role != RoleViewer {
+				t.Errorf("глобальная роль %q, ожидается %q", role, RoleViewer)
+			}
+			if role := policy.Role(userID, chatID); role != RoleDeveloper {
+				t.Errorf("роль в чате %q, ожидается %q", role, RoleDeveloper)
+			}
+			if role := policy.Role(300, 0); role != RoleNone {
+				t.Errorf("роль приглашенного %q, ожидается отсутствие доступа", role)
+			}
+			if !policy.ChatAllowed(chatID) || policy.ChatAllowed(-300) {
+				t.Errorf("разрешенные чаты изменились: %v", policy.Chats())
+			}
+			if got, ok := store.LookupUsername("alice"); !ok || got != userID {
+				t.Errorf("username alice: %d, %v", got, ok)
+			}
+			if _, ok := store.LookupUsername("bob"); ok {
+				t.Error("username bob запомнен без сохранения")
+			}
+			if _, ok := store.InvitedBy(300); ok {
+				t.Error("приглашение использовано без сохранения")
+			}
+		})
+	}
+
+	// После восстановления хранилища изменения применяются, а приглашение по-прежнему действует
+	backend.fail = false
+	if _, err := store.RedeemInvite(invite.Token, 300); err != nil {
+		t.Fatalf("приглашение после ошибки хранилища: %v", err)
+	}
+	if role := policy.Role(300, 0); role != RoleAdmin {
+		t.Errorf("роль приглашенного %q, ожидается %q", role, RoleAdmin)
+	}
+	if current, _ := storage.Get(backend, storeBucket, storeKey); string(current) == string(saved) {
+		t.Error("состояние не сохранено после восстановления хранилища")
+	}
+}
+
+func TestStoreInvites(t *testing.T) {
+	const (
+		admin   = 1
+		groupID = -100
+	)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	policy := NewPolicy(DefaultRole)
+	backend := storage.NewMemory()
+	store := NewStore(backend, policy)
+	store.now = func() time.Time { return now }
+
+	create := func(role Role, chatID int64, ttl time.Duration) Invite {
+		t.Helper()
+		invite, err := store.CreateInvite(role, admin, chatID, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return invite
+	}
+	redeem := func(token string, userID int64) error {
+		t.Helper()
+		_, err := store.RedeemInvite(token, userID)
+		return err
+	}
+
+	t.Run("создание", func(t *testing.T) {
+		invite := create(RoleDeveloper, 0, 0)
+		if len(invite.Token) != 22 || strings.Trim(invite.Token, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+			t.Errorf("токен %q непригоден для параметра start", invite.Token)
+		}
+		if !invite.ExpiresAt.Equal(now.Add(DefaultInviteTTL)) {
+			t.Errorf("срок действия %s, ожидается %s", invite.ExpiresAt, now.Add(DefaultInviteTTL))
+		}
+		if other := create(RoleDeveloper, 0, time.Hour); other.Token == invite.Token {
+			t.Error("токены приглашений совпадают")
+		}
+	})
+
+	t.Run("одноразовое использование", func(t *testing.T) {
+		invite := create(RoleDeveloper, 0, time.Hour)
+		if err := redeem(invite.Token, 10); err != nil {
+			t.Fatal(err)
+		}
+		if role := policy.Role(10, -500); role != RoleDeveloper {
+			t.Errorf("роль %q, ожидается %q во всех чатах", role, RoleDeveloper)
+		}
+		invitation, ok := store.InvitedBy(10)
+		if !ok || invitation.InvitedBy != admin || invitation.Role != RoleDeveloper || !invitation.At.Equal(now) {
+			t.Errorf("запись о приглашении %+v, %v", invitation, ok)
+		}
+
+		// Использованное приглашение не действует ни для другого пользователя, ни повторно
+		if err := redeem(invite.Token, 11); !errors.Is(err, ErrInviteInvalid) {
+			t.Errorf("повторное использование: ошибка %v, ожидается %v", err, ErrInviteInvalid)
+		}
+		if role := policy.Role(11, 0); role != RoleNone {
+			t.Errorf("роль по использованному приглашению %q", role)
+		}
+		if err := redeem("неизвестный", 11); !errors.Is(err, ErrInviteInvalid) {
+			t.Errorf("неизвестный токен: ошибка %v, ожидается %v", err, ErrInviteInvalid)
+		}
+	})
+
+	t.Run("истечение срока", func(t *testing.T) {
+		start := now
+		defer func() { now = start }()
+		valid := create(RoleViewer, 0, time.Hour)
+		expired := create(RoleViewer, 0, time.Hour)
+
+		now = start.Add(time.Hour - time.Second)
+		if err := redeem(valid.Token, 20); err != nil {
+			t.Errorf("до истечения срока: %v", err)
+		}
+		now = start.Add(time.Hour)
+		if err := redeem(expired.Token, 21); !errors.Is(err, ErrInviteInvalid) {
+			t.Errorf("после истечения срока: ошибка %v, ожидается %v", err, ErrInviteInvalid)
+		}
+		if role := policy.Role(21, 0); role != RoleNone {
+			t.Errorf("роль по просроченному приглашению %q", role)
+		}
+	})
+
+	t.Run("приглашение в группу", func(t *testing.T) {
+		const userID = 30
+		invite := create(RoleReleaseManager, groupID, time.Hour)
+		if err := redeem(invite.Token, userID); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			chatID int64
+			want   Role
+		}{
+			{groupID, RoleReleaseManager},
+			{userID, RoleReleaseManager},
+			{-200, RoleNone},
+			{0, RoleNone},
+		}
+		for _, tt := range tests {
+			if role := policy.Role(userID, tt.chatID); role != tt.want {
+				t.Errorf("роль в чате %d: %q, ожидается %q", tt.chatID, role, tt.want)
+			}
+		}
+		if invitation, _ := store.InvitedBy(userID); invitation.ChatID != groupID {
+			t.Errorf("в записи о приглашении чат %d, ожидается %d", invitation.ChatID, groupID)
+		}
+	})
+
+	t.Run("после перезапуска", func(t *testing.T) {
+		used := create(RoleViewer, 0, time.Hour)
+		unused := create(RoleViewer, 0, time.Hour)
+		if err := redeem(used.Token, 40); err != nil {
+			t.Fatal(err)
+		}
+
+		restarted := NewStore(backend, NewPolicy(DefaultRole))
+		restarted.now = store.now
+		if err := restarted.Load(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := restarted.RedeemInvite(used.Token, 41); !errors.Is(err, ErrInviteInvalid) {
+			t.Errorf("использованное приглашение: ошибка %v, ожидается %v", err, ErrInviteInvalid)
+		}
+		if _, err := restarted.RedeemInvite(unused.Token, 42); err != nil {
+			t.Errorf("неиспользованное приглашение: %v", err)
+		}
+	})
+}
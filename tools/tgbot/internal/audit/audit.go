@@ -0,0 +1,321 @@
+// Package audit ведет журнал привилегированных действий: запусков релизов, удаления
+// веток, закрытия PR, изменений доступа. Журнал хранится в файле JSONL, записи только
+// добавляются, а каждая запись содержит хеш предыдущей, поэтому изменение или удаление
+// записи обнаруживается проверкой цепочки
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Действия, записываемые в журнал
+const (
+	ActionReleaseDispatch = "release.dispatch"
+	ActionReleaseRequest  = "release.request"
+	ActionReleaseVote     = "release.vote"
+	ActionReleaseExpire   = "release.expire"
+	ActionBranchDelete    = "branch.delete"
+	ActionPRClose         = "pr.close"
+	ActionAccessAllow     = "access.allow"
+	ActionAccessRevoke    = "access.revoke"
+	ActionAccessRole      = "access.role"
+	ActionChatAllow       = "chat.allow"
+	ActionChatRevoke      = "chat.revoke"
+	ActionInviteCreate    = "invite.create"
+	ActionInviteRedeem    = "invite.redeem"
+	ActionTOTPEnroll      = "totp.enroll"
+	ActionTOTPDisable     = "totp.disable"
+	ActionTOTPReset       = "totp.reset"
+	ActionTOTPFailure     = "totp.failure"
+	ActionConfigReload    = "config.reload"
+)
+
+// maxLineSize максимальный размер одной записи в файле
+const maxLineSize = 1 << 20
+
+// Event запись журнала
+type Event struct {
+	// Seq порядковый номер записи, начиная с 1
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	// UserID пользователь, выполнивший действие; 0 — сам бот (например, по таймеру)
+	UserID int64  `json:"user_id"`
+	ChatID int64  `json:"chat_id,omitempty"`
+	Action string `json:"action"`
+	// Target объект действия: ветка, номер PR, пользователь, чат
+	Target  string `json:"target,omitempty"`
+	Details string `json:"details,omitempty"`
+	// Error ошибка, если действие не удалось
+	Error string `json:"error,omitempty"`
+	// PrevHash хеш предыдущей записи; у первой записи пустой
+	PrevHash string `json:"prev_hash"`
+	// Hash SHA-256 записи вместе с PrevHash
+	Hash string `json:"hash"`
+}
+
+// Failed сообщает, что действие не удалось
+func (e Event) Failed() bool {
+	return e.Error != ""
+}
+
+// hash вычисляет хеш записи; поле Hash в вычислении не участвует
+func (e Event) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("ошибка маршалинга записи журнала: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainError нарушение цепочки хешей: запись изменена, удалена или вставлена
+type ChainError struct {
+	// Line номер строки файла, начиная с 1
+	Line   int
+	Reason string
+}
+
+// Error реализует интерфейс error
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("журнал аудита поврежден в строке %d: %s", e.Line, e.Reason)
+}
+
+// Log журнал действий в файле JSONL. Пустой путь означает, что записи хранятся
+// только в памяти до перезапуска
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	events   []Event
+	lastSeq  int64
+	lastHash string
+	now      func() time.Time
+}
+
+// Open открывает журнал в файле path и проверяет цепочку хешей. Если цепочка
+// нарушена, журнал все равно открывается, чтобы новые действия продолжали
+// записываться, а нарушение возвращается как *ChainError
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, now: time.Now}
+	if path == "" {
+		return l, nil
+	}
+
+	var chainErr error
+	err := l.scan(func(line int, event Event, err error) bool {
+		if err == nil {
+			err = l.check(line, event)
+		}
+		if err != nil && chainErr == nil {
+			chainErr = err
+		}
+		if event.Hash != "" {
+			l.lastSeq, l.lastHash = event.Seq, event.Hash
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, chainErr
+}
+
+// Append добавляет запись в журнал, заполняя номер, время и хеши
+func (l *Log) Append(event Event) (Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Seq = l.lastSeq + 1
+	if event.Time.IsZero() {
+		event.Time = l.now()
+	}
+	event.Time = event.Time.UTC()
+	event.PrevHash = l.lastHash
+	hash, err := event.hash()
+	if err != nil {
+		return Event{}, err
+	}
+	event.Hash = hash
+
+	if l.path == "" {
+		l.events = append(l.events, event)
+	} else if err := l.write(event); err != nil {
+		return Event{}, err
+	}
+	l.lastSeq, l.lastHash = event.Seq, event.Hash
+	return event, nil
+}
+
+// write дописывает запись в конец файла; вызывается под l.mu
+func (l *Log) write(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга записи журнала: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога журнала: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала аудита: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("ошибка записи в журнал аудита: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("ошибка синхронизации журнала аудита: %w", err)
+	}
+	return file.Close()
+}
+
+// Verify проверяет цепочку хешей всего журнала и возвращает число записей.
+// При нарушении цепочки возвращается *ChainError
+func (l *Log) Verify() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	verifier := &Log{}
+	count := 0
+	var chainErr error
+	err := l.scan(func(line int, event Event, err error) bool {
+		if err == nil {
+			err = verifier.check(line, event)
+		}
+		if err != nil {
+			chainErr = err
+			return false
+		}
+		verifier.lastSeq, verifier.lastHash = event.Seq, event.Hash
+		count++
+		return true
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, chainErr
+}
+
+// check проверяет, что запись продолжает цепочку после l.lastSeq и l.lastHash
+func (l *Log) check(line int, event Event) error {
+	hash, err := event.hash()
+	if err != nil {
+		return err
+	}
+	switch {
+	case event.Hash != hash:
+		return &ChainError{Line: line, Reason: "хеш записи не совпадает с содержимым"}
+	case event.PrevHash != l.lastHash:
+		return &ChainError{Line: line, Reason: "хеш предыдущей записи не совпадает"}
+	case event.Seq != l.lastSeq+1:
+		return &ChainError{Line: line, Reason: fmt.Sprintf("ожидалась запись %d, найдена %d", l.lastSeq+1, event.Seq)}
+	}
+	return nil
+}
+
+// Filter условия отбора записей; нулевые поля не ограничивают выборку
+type Filter struct {
+	UserID int64
+	// Action действие или его префикс до точки: «release» отбирает все release.*
+	Action string
+	// From и To границы времени: From включительно, To не включительно
+	From time.Time
+	To   time.Time
+}
+
+// Match сообщает, подходит ли запись под условия
+func (f Filter) Match(event Event) bool {
+	if f.UserID != 0 && event.UserID != f.UserID {
+		return false
+	}
+	if f.Action != "" && event.Action != f.Action && !strings.HasPrefix(event.Action, f.Action+".") {
+		return false
+	}
+	if !f.From.IsZero() && event.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !event.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Query возвращает записи, подходящие под условия, в порядке добавления.
+// Нечитаемые строки пропускаются
+func (l *Log) Query(filter Filter) ([]Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var events []Event
+	err := l.scan(func(_ int, event Event, err error) bool {
+		if err == nil && filter.Match(event) {
+			events = append(events, event)
+		}
+		return true
+	})
+	return events, err
+}
+
+// scan читает записи журнала по порядку и передает их в fn вместе с ошибкой
+// разбора строки; fn возвращает false, чтобы остановить чтение
+func (l *Log) scan(fn func(line int, event Event, err error) bool) error {
+	if l.path == "" {
+		for i, event := range l.events {
+			if !fn(i+1, event, nil) {
+				break
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала аудита: %w", err)
+	}
+	defer file.Close()
+
+	return scanEvents(file, fn)
+}
+
+// scanEvents разбирает записи JSONL из r
+func scanEvents(r io.Reader, fn func(line int, event Event, err error) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var event Event
+		var err error
+		if jsonErr := json.Unmarshal(data, &event); jsonErr != nil {
+			err = &ChainError{Line: line, Reason: "запись не разбирается: " + jsonErr.Error()}
+		}
+		if !fn(line, event, err) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения журнала аудита: %w", err)
+	}
+	return nil
+}
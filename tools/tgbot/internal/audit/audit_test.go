@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog создает журнал из трех записей и возвращает путь к файлу
+func writeLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"main", "feature/a", "feature/b"} {
+		if _, err := log.Append(Event{UserID: 1, Action: ActionBranchDelete, Target: target}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// readLines возвращает строки файла журнала
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// writeLines перезаписывает файл журнала строками lines
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyIntactLog(t *testing.T) {
+	path := writeLog(t)
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("открытие целого журнала: %v", err)
+	}
+	count, err := log.Verify()
+	if err != nil || count != 3 {
+		t.Fatalf("Verify = %d, %v; ожидается 3 записи без ошибки", count, err)
+	}
+
+	// Записи после повторного открытия продолжают цепочку
+	event, err := log.Append(Event{UserID: 2, Action: ActionPRClose, Target: "#7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Seq != 4 {
+		t.Errorf("номер новой записи %d, ожидается 4", event.Seq)
+	}
+	if count, err := log.Verify(); err != nil || count != 4 {
+		t.Errorf("Verify = %d, %v; ожидается 4 записи без ошибки", count, err)
+	}
+}
+
+func TestVerifyMemoryLog(t *testing.T) {
+	log, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := log.Append(Event{Action: ActionReleaseDispatch}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count, err := log.Verify(); err != nil || count != 2 {
+		t.Errorf("Verify = %d, %v; ожидается 2 записи без ошибки", count, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper изменяет строки файла журнала
+		tamper    func(lines []string) []string
+		wantLine  int
+		wantCount int
+	}{
+		{
+			name: "изменена запись",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], "feature/a", "feature/x", 1)
+				return lines
+			},
+			wantLine:  2,
+			wantCount: 1,
+		},
+		{
+			name: "изменен пользователь",
+			tamper: func(lines []string) []string {
+				lines[0] = strings.Replace(lines[0], `"user_id":1`, `"user_id":2`, 1)
+				return lines
+			},
+			wantLine:  1,
+			wantCount: 0,
+		},
+		{
+			name: "удалена запись",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			wantLine:  2,
+			wantCount: 1,
+		},
+		{
+			name: "записи переставлены",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantLine:  2,
+			wantCount: 1,
+		},
+		{
+			name: "запись повторена",
+			tamper: func(lines []string) []string {
+				return append(lines[:2], lines[1:]...)
+			},
+			wantLine:  3,
+			wantCount: 2,
+		},
+		{
+			name: "строка не разбирается",
+			tamper: func(lines []string) []string {
+				lines[2] = "{не json"
+				return lines
+			},
+			wantLine:  3,
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLog(t)
+			log, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, path, tt.tamper(readLines(t, path)))
+
+			count, err := log.Verify()
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				t.Fatalf("Verify вернул %v, ожидается *ChainError", err)
+			}
+			if chainErr.Line != tt.wantLine {
+				t.Errorf("нарушение в строке %d, ожидается %d: %v", chainErr.Line, tt.wantLine, chainErr)
+			}
+			if count != tt.wantCount {
+				t.Errorf("проверено записей %d, ожидается %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestOpenBrokenChain(t *testing.T) {
+	path := writeLog(t)
+	lines := readLines(t, path)
+	writeLines(t, path, append(lines[:1], lines[2:]...))
+
+	// Журнал открывается, но нарушение цепочки возвращается как *ChainError
+	log, err := Open(path)
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) || chainErr.Line != 2 {
+		t.Fatalf("Open вернул %v, ожидается *ChainError в строке 2", err)
+	}
+	if log == nil {
+		t.Fatal("журнал с нарушенной цепочкой не открыт")
+	}
+
+	// Новые действия продолжают записываться после последней записи
+	event, err := log.Append(Event{UserID: 1, Action: ActionAccessRevoke, Target: "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Seq != 4 {
+		t.Errorf("номер новой записи %d, ожидается 4", event.Seq)
+	}
+	events, err := log.Query(Filter{Action: "access"})
+	if err != nil || len(events) != 1 {
+		t.Errorf("Query = %d записей, %v; ожидается 1", len(events), err)
+	}
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader заголовок CSV-выгрузки
+var csvHeader = []string{"seq", "time", "user_id", "chat_id", "action", "target", "details", "error", "prev_hash", "hash"}
+
+// csvFormulaPrefixes символы, с которых табличные редакторы начинают формулу
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// WriteCSV выгружает записи в формате CSV вместе с хешами, чтобы выгрузку можно
+// было сверить с журналом. Текстовые поля экранируются, чтобы открытая в табличном
+// редакторе выгрузка не выполняла формулы из имен веток, пользователей или ошибок
+func WriteCSV(w io.Writer, events []Event) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("ошибка записи CSV: %w", err)
+	}
+	for _, event := range events {
+		record := []string{
+			strconv.FormatInt(event.Seq, 10),
+			event.Time.Format(time.RFC3339),
+			strconv.FormatInt(event.UserID, 10),
+			strconv.FormatInt(event.ChatID, 10),
+			csvText(event.Action),
+			csvText(event.Target),
+			csvText(event.Details),
+			csvText(event.Error),
+			event.PrevHash,
+			event.Hash,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("ошибка записи CSV: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("ошибка записи CSV: %w", err)
+	}
+	return nil
+}
+
+// csvText экранирует значение, которое табличный редактор принял бы за формулу,
+// добавляя в начало апостроф
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"обычный текст", "feature/login", "feature/login"},
+		{"пустое значение", "", ""},
+		{"равно", "=HYPERLINK(\"http://example.com\")", "'=HYPERLINK(\"http://example.com\")"},
+		{"плюс", "+1+2", "'+1+2"},
+		{"минус", "-2+3", "'-2+3"},
+		{"собака", "@SUM(A1)", "'@SUM(A1)"},
+		{"табуляция", "\t=1", "'\t=1"},
+		{"знак не в начале", "a=1", "a=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := Event{
+				Seq:     1,
+				Time:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+				ChatID:  -100,
+				Action:  ActionBranchDelete,
+				Target:  tt.value,
+				Details: tt.value,
+				Error:   tt.value,
+			}
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, []Event{event}); err != nil {
+				t.Fatal(err)
+			}
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("строк %d, ожидается 2", len(records))
+			}
+			record := records[1]
+			for _, i := range []int{5, 6, 7} {
+				if record[i] != tt.want {
+					t.Errorf("поле %s = %q, ожидается %q", csvHeader[i], record[i], tt.want)
+				}
+			}
+			// Числовые поля не экранируются
+			if record[3] != "-100" {
+				t.Errorf("chat_id = %q, ожидается -100", record[3])
+			}
+		})
+	}
+}
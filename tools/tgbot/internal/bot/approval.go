@@ -0,0 +1,244 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"tgbot/internal/storage"
+)
+
+// DefaultApprovalTTL время на сбор одобрений по умолчанию
+const DefaultApprovalTTL = time.Hour
+
+// Ошибки голосования за запрос
+var (
+	// ErrApprovalClosed запрос уже одобрен, отклонен или истек срок его рассмотрения
+	ErrApprovalClosed = errors.New("запрос уже рассмотрен или истек срок его рассмотрения")
+	// ErrAlreadyVoted пользователь уже проголосовал
+	ErrAlreadyVoted = errors.New("вы уже проголосовали")
+	// ErrSelfApproval инициатор не может одобрить собственный запрос
+	ErrSelfApproval = errors.New("нельзя одобрить собственный запрос")
+)
+
+// ApprovalState состояние запроса на одобрение
+type ApprovalState int
+
+const (
+	// ApprovalPending запрос ожидает голосов
+	ApprovalPending ApprovalState = iota
+	// ApprovalApproved набрано нужное число одобрений
+	ApprovalApproved
+	// ApprovalRejected запрос отклонен
+	ApprovalRejected
+	// ApprovalExpired истек срок рассмотрения запроса
+	ApprovalExpired
+)
+
+// approvalsBucket бакет, в котором хранятся запросы, ожидающие голосов
+const approvalsBucket = "approvals"
+
+// Vote голос за запрос или против него
+type Vote struct {
+	UserID  int64     `json:"user_id"`
+	Approve bool      `json:"approve"`
+	At      time.Time `json:"at"`
+}
+
+// Approval запрос, которому нужно Required одобрений от разных пользователей (N из M).
+// Одного голоса против достаточно, чтобы отклонить запрос
+type Approval struct {
+	ID          string        `json:"id"`
+	InitiatorID int64         `json:"initiator_id"`
+	Required    int           `json:"required"`
+	State       ApprovalState `json:"state"`
+	Votes       []Vote        `json:"votes"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	// ChatID и MessageID сообщение с карточкой запроса
+	ChatID    int64 `json:"chat_id"`
+	MessageID int   `json:"message_id"`
+}
+
+// Approved возвращает число одобрений
+func (a Approval) Approved() int {
+	approved := 0
+	for _, vote := range a.Votes {
+		if vote.Approve {
+			approved++
+		}
+	}
+	return approved
+}
+
+// Approvals запросы, ожидающие одобрения. Запросы сохраняются в хранилище, поэтому
+// голосование продолжается после перезапуска бота
+type Approvals struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]*Approval
+	repo    *storage.Repository[Approval]
+	now     func() time.Time
+}
+
+// NewApprovals создает список запросов в store со сроком рассмотрения ttl
+func NewApprovals(store storage.Store, ttl time.Duration) *Approvals {
+	if ttl <= 0 {
+		ttl = DefaultApprovalTTL
+	}
+	return &Approvals{
+		ttl:     ttl,
+		pending: make(map[string]*Approval),
+		repo:    storage.NewRepository[Approval](store, approvalsBucket),
+		now:     time.Now,
+	}
+}
+
+// Load читает запросы, ожидавшие голосов до перезапуска бота, и возвращает их, чтобы
+// вызывающий снова назначил отмену по истечении срока (в том числе уже истекшего)
+func (a *Approvals) Load() ([]Approval, error) {
+	saved, err := a.repo.All()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения запросов на одобрение: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	loaded := make([]Approval, 0, len(saved))
+	for id, approval := range saved {
+		approval := approval
+		a.pending[id] = &approval
+		loaded = append(loaded, a.copy(&approval))
+	}
+	return loaded, nil
+}
+
+// TTL возвращает срок рассмотрения запросов
+func (a *Approvals) TTL() time.Duration {
+	return a.ttl
+}
+
+// Start создает запрос пользователя initiatorID, которому нужно required одобрений
+func (a *Approvals) Start(initiatorID int64, required int) (Approval, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if required < 1 {
+		required = 1
+	}
+	now := a.now()
+	approval := &Approval{
+		ID:          newCallbackID(),
+		InitiatorID: initiatorID,
+		Required:    required,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(a.ttl),
+	}
+	if err := a.save(*approval); err != nil {
+		return Approval{}, err
+	}
+	a.pending[approval.ID] = approval
+	return *approval, nil
+}
+
+// SetMessage запоминает сообщение с карточкой запроса
+func (a *Approvals) SetMessage(id string, chatID int64, messageID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	approval, ok := a.pending[id]
+	if !ok {
+		return nil
+	}
+	updated := a.copy(approval)
+	updated.ChatID, updated.MessageID = chatID, messageID
+	if err := a.save(updated); err != nil {
+		return err
+	}
+	*approval = updated
+	return nil
+}
+
+// Vote учитывает голос пользователя userID и возвращает запрос после голосования.
+// Когда запрос одобрен или отклонен, он удаляется из списка ожидающих
+func (a *Approvals) Vote(id string, userID int64, approve bool) (Approval, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	approval, ok := a.pending[id]
+	if !ok {
+		return Approval{}, ErrApprovalClosed
+	}
+	now := a.now()
+	if now.After(approval.ExpiresAt) {
+		return Approval{}, ErrApprovalClosed
+	}
+	if approve && userID == approval.InitiatorID {
+		return a.copy(approval), ErrSelfApproval
+	}
+	for _, vote := range approval.Votes {
+		if vote.UserID == userID {
+			return a.copy(approval), ErrAlreadyVoted
+		}
+	}
+
+	// Голос учитывается, только если его удалось сохранить
+	updated := a.copy(approval)
+	updated.Votes = append(updated.Votes, Vote{UserID: userID, Approve: approve, At: now})
+	switch {
+	case !approve:
+		updated.State = ApprovalRejected
+	case updated.Approved() >= updated.Required:
+		updated.State = ApprovalApproved
+	}
+	if err := a.save(updated); err != nil {
+		return Approval{}, err
+	}
+	if updated.State != ApprovalPending {
+		delete(a.pending, id)
+	} else {
+		*approval = updated
+	}
+	return a.copy(&updated), nil
+}
+
+// Expire закрывает запрос id, если он еще ожидает голосов, и возвращает его
+func (a *Approvals) Expire(id string) (Approval, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	approval, ok := a.pending[id]
+	if !ok {
+		return Approval{}, false
+	}
+	delete(a.pending, id)
+	approval.State = ApprovalExpired
+	// Если удалить запрос не удалось, после перезапуска он будет загружен и сразу отменен
+	if err := a.save(*approval); err != nil {
+		log.Printf("%v", err)
+	}
+	return a.copy(approval), true
+}
+
+// save сохраняет запрос, ожидающий голосов, или удаляет рассмотренный; вызывается под a.mu
+func (a *Approvals) save(approval Approval) error {
+	var err error
+	if approval.State == ApprovalPending {
+		err = a.repo.Put(approval.ID, approval)
+	} else {
+		err = a.repo.Delete(approval.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения запроса на одобрение: %w", err)
+	}
+	return nil
+}
+
+// copy возвращает копию запроса, которую можно читать без блокировки
+func (a *Approvals) copy(approval *Approval) Approval {
+	result := *approval
+	result.Votes = append([]Vote(nil), approval.Votes...)
+	return result
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tgbot/internal/storage"
+)
+
+func TestApprovalsSurviveRestart(t *testing.T) {
+	store := storage.NewMemory()
+	approvals := NewApprovals(store, time.Hour)
+
+	approval, err := approvals.Start(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := approvals.SetMessage(approval.ID, -100, 42); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Vote(approval.ID, 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// После перезапуска запрос загружается вместе с голосами и сообщением карточки
+	restarted := NewApprovals(store, time.Hour)
+	pending, err := restarted.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("загружено запросов %d, ожидается 1", len(pending))
+	}
+	loaded := pending[0]
+	if loaded.ID != approval.ID || loaded.ChatID != -100 || loaded.MessageID != 42 || loaded.Approved() != 1 {
+		t.Errorf("загружен запрос %+v", loaded)
+	}
+	if !loaded.ExpiresAt.Equal(approval.ExpiresAt) {
+		t.Errorf("срок рассмотрения %v, ожидается %v", loaded.ExpiresAt, approval.ExpiresAt)
+	}
+
+	// Голосование продолжается: уже проголосовавший не голосует повторно
+	if _, err := restarted.Vote(approval.ID, 2, true); !errors.Is(err, ErrAlreadyVoted) {
+		t.Errorf("повторный голос: ошибка %v, ожидается ErrAlreadyVoted", err)
+	}
+	result, err := restarted.Vote(approval.ID, 3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.State != ApprovalApproved {
+		t.Errorf("состояние %v, ожидается ApprovalApproved", result.State)
+	}
+
+	// Рассмотренный запрос удаляется из хранилища
+	pending, err = NewApprovals(store, time.Hour).Load()
+	if err != nil || len(pending) != 0 {
+		t.Errorf("после одобрения загружено %d запросов, %v; ожидается 0", len(pending), err)
+	}
+}
+
+func TestApprovalsExpireRemovesSaved(t *testing.T) {
+	store := storage.NewMemory()
+	approvals := NewApprovals(store, time.Hour)
+	approval, err := approvals.Start(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, ok := approvals.Expire(approval.ID)
+	if !ok || expired.State != ApprovalExpired {
+		t.Fatalf("Expire = %+v, %v", expired, ok)
+	}
+	if _, err := approvals.Vote(approval.ID, 2, true); !errors.Is(err, ErrApprovalClosed) {
+		t.Errorf("голос за истекший запрос: ошибка %v, ожидается ErrApprovalClosed", err)
+	}
+	pending, err := NewApprovals(store, time.Hour).Load()
+	if err != nil || len(pending) != 0 {
+		t.Errorf("после отмены загружено %d запросов, %v; ожидается 0", len(pending), err)
+	}
+}
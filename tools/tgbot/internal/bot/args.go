@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// errUnterminatedQuote ошибка разбора аргументов с незакрытой кавычкой
+var errUnterminatedQuote = errors.New("незакрытая кавычка")
+
+// ParseCommand выделяет из текста сообщения имя команды, имя бота, которому она
+// адресована (после @), и строку аргументов. ok равен false, если текст не является командой
+func ParseCommand(text string) (name, botName, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", "", false
+	}
+
+	command := text[1:]
+	if i := strings.IndexFunc(command, unicode.IsSpace); i >= 0 {
+		command, args = command[:i], command[i:]
+	}
+	name, botName, _ = strings.Cut(command, "@")
+	if name == "" {
+		return "", "", "", false
+	}
+
+	return strings.ToLower(name), botName, strings.TrimSpace(args), true
+}
+
+// SplitArgs разбивает строку аргументов по пробелам. Аргумент с пробелами можно
+// заключить в двойные или одинарные кавычки либо в «ёлочки», а символ после \ берется как есть.
+// Одинарная кавычка открывает кавычки только в начале аргумента, поэтому
+// апострофы внутри слов (user's) остаются частью аргумента
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		// quote открывающая кавычка текущего аргумента или 0
+		quote rune
+		// inArg текущий аргумент начат (нужно для пустых аргументов в кавычках)
+		inArg   bool
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '«' || r == '\'' && !inArg:
+			if r == '«' {
+				r = '»'
+			}
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errUnterminatedQuote
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		name    string
+		botName string
+		args    string
+		ok      bool
+	}{
+		{"/help", "help", "", "", true},
+		{"/Release v1.2.3", "release", "", "v1.2.3", true},
+		{"/release@ReleaseBot  v1.2.3 ", "release", "ReleaseBot", "v1.2.3", true},
+		{"/note\tпервая строка\nвторая", "note", "", "первая строка\nвторая", true},
+		{"/start@OtherBot", "start", "OtherBot", "", true},
+		{"help", "", "", "", false},
+		{"/", "", "", "", false},
+		{"/@ReleaseBot", "", "", "", false},
+		{"", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			name, botName, args, ok := ParseCommand(tt.text)
+			if name != tt.name || botName != tt.botName || args != tt.args || ok != tt.ok {
+				t.Errorf("ParseCommand(%q) = %q, %q, %q, %v; ожидается %q, %q, %q, %v",
+					tt.text, name, botName, args, ok, tt.name, tt.botName, tt.args, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"пустая строка", "", nil},
+		{"только пробелы", "  \t ", nil},
+		{"несколько пробелов", " a   b\tc ", []string{"a", "b", "c"}},
+		{"двойные кавычки", `"два слова" три`, []string{"два слова", "три"}},
+		{"одинарные кавычки", `'два слова' три`, []string{"два слова", "три"}},
+		{"ёлочки", "«релиз 1.2» готов", []string{"релиз 1.2", "готов"}},
+		{"пустой аргумент в кавычках", `a "" b`, []string{"a", "", "b"}},
+		{"кавычки внутри аргумента", `name="два слова"`, []string{"name=два слова"}},
+		{"другие кавычки внутри кавычек", `"it's «ok»"`, []string{"it's «ok»"}},
+		{"экранированный пробел", `два\ слова`, []string{"два слова"}},
+		{"экранированная кавычка", `\"a b\"`, []string{`"a`, `b"`}},
+		{"экранирование в двойных кавычках", `"a \" b"`, []string{`a " b`}},
+		{"без экранирования в одинарных кавычках", `'C:\path'`, []string{`C:\path`}},
+		{"двойная косая черта", `a\\b`, []string{`a\b`}},
+		{"косая черта в конце", `a\`, []string{`a\`}},
+		{"апостроф", "user's branch", []string{"user's", "branch"}},
+		{"апострофы в словах", "don't stop'n'go", []string{"don't", "stop'n'go"}},
+		{"апостроф в конце слова", "users' list", []string{"users'", "list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.in)
+			if err != nil {
+				t.Fatalf("SplitArgs(%q): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitArgs(%q) = %q, ожидается %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgsUnterminatedQuote(t *testing.T) {
+	for _, in := range []string{`"a b`, `'a b`, "«a b", `a "b`, `"a\"`} {
+		if got, err := SplitArgs(in); !errors.Is(err, errUnterminatedQuote) {
+			t.Errorf("SplitArgs(%q) = %q, %v; ожидается ошибка %v", in, got, err, errUnterminatedQuote)
+		}
+	}
+}
+
+func TestCommandRouterHandle(t *testing.T) {
+	var got *CommandRequest
+	router := NewCommandRouter(nil)
+	router.SetBotName("@ReleaseBot")
+	router.Register(Command{
+		Name: "release",
+		Args: []Arg{
+			{Name: "version", Required: true},
+			{Name: "notes", Rest: true},
+		},
+		Handler: func(_ context.Context, request *CommandRequest) error {
+			got = request
+			return nil
+		},
+	})
+	router.Register(Command{
+		Name: "tag",
+		Args: []Arg{{Name: "name", Required: true}},
+		Handler: func(_ context.Context, request *CommandRequest) error {
+			got = request
+			return nil
+		},
+	})
+
+	tests := []struct {
+		name     string
+		text     string
+		handled  bool
+		called   bool
+		wantArgs map[string]string
+		wantErr  error
+	}{
+		{"не команда", "release v1", false, false, nil, nil},
+		{"без имени бота", "/release v1", true, true, map[string]string{"version": "v1"}, nil},
+		{"своему боту", "/release@releasebot v1", true, true, map[string]string{"version": "v1"}, nil},
+		{"другому боту", "/release@OtherBot v1", true, false, nil, nil},
+		{"неизвестная команда другому боту", "/deploy@OtherBot", true, false, nil, nil},
+		{"неизвестная команда", "/deploy", true, false, nil, ErrUnknownCommand},
+		{"остаток текста", `/release v1 "исправлен" user's баг`, true, true, map[string]string{"version": "v1", "notes": "исправлен user's баг"}, nil},
+		{"не указан обязательный аргумент", "/release", true, false, nil, &UsageError{}},
+		{"лишний аргумент", "/tag a b", true, false, nil, &UsageError{}},
+		{"незакрытая кавычка", `/tag "a`, true, false, nil, &UsageError{}},
+		{"апостроф в аргументе", "/tag user's", true, true, map[string]string{"name": "user's"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			handled, err := router.Handle(context.Background(), &types.Message{Text: tt.text})
+			if handled != tt.handled {
+				t.Errorf("handled = %v, ожидается %v", handled, tt.handled)
+			}
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("ошибка %v", err)
+				}
+			case *UsageError:
+				var usage *UsageError
+				if !errors.As(err, &usage) {
+					t.Errorf("ошибка %v, ожидается *UsageError", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("ошибка %v, ожидается %v", err, want)
+				}
+			}
+			if (got != nil) != tt.called {
+				t.Fatalf("обработчик вызван: %v, ожидается %v", got != nil, tt.called)
+			}
+			if got != nil && !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("аргументы %q, ожидается %q", got.Args, tt.wantArgs)
+			}
+		})
+	}
+}
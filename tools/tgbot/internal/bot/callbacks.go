@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/pkg/types"
+)
+
+const (
+	// MaxCallbackDataLength максимальная длина callback_data в Telegram (в байтах)
+	MaxCallbackDataLength = 64
+
+	// callbackVersion версия формата callback-данных
+	callbackVersion = "1"
+	// callbackSeparator разделитель версии, действия и аргументов
+	callbackSeparator = "|"
+	// callbackStoredAction действие-ссылка на данные, сохраненные в CallbackStore
+	callbackStoredAction = "~"
+
+	// DefaultCallbackTTL время хранения длинных callback-данных по умолчанию
+	DefaultCallbackTTL = 24 * time.Hour
+	// maxStoredCallbacks ограничение числа сохраненных callback-данных
+	maxStoredCallbacks = 10000
+)
+
+// Ошибки маршрутизации callback-запросов
+var (
+	// ErrUnknownAction для действия не зарегистрирован обработчик
+	ErrUnknownAction = errors.New("неизвестное действие")
+	// ErrCallbackExpired данные кнопки больше не хранятся (истек срок или бот перезапускался)
+	ErrCallbackExpired = errors.New("данные кнопки устарели")
+	// ErrCallbackMalformed данные кнопки не разбираются: пустые, неизвестной версии формата или без действия
+	ErrCallbackMalformed = errors.New("некорректные данные кнопки")
+)
+
+var (
+	callbackEscaper   = strings.NewReplacer("%", "%25", callbackSeparator, "%7C")
+	callbackUnescaper = strings.NewReplacer("%25", "%", "%7C", callbackSeparator)
+)
+
+// CallbackData структурированные данные inline-кнопки: действие и его аргументы.
+// Кодируются в строку вида "1|действие|аргумент|аргумент"
+type CallbackData struct {
+	Action string
+	Args   []string
+}
+
+// NewCallback создает данные кнопки; аргументы приводятся к строкам через fmt.Sprint
+func NewCallback(action string, args ...any) CallbackData {
+	data := CallbackData{Action: action, Args: make([]string, len(args))}
+	for i, arg := range args {
+		data.Args[i] = fmt.Sprint(arg)
+	}
+	return data
+}
+
+// Arg возвращает аргумент с индексом i или пустую строку
+func (d CallbackData) Arg(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
+
+// Int возвращает аргумент с индексом i как число
+func (d CallbackData) Int(i int) (int, error) {
+	value, err := strconv.Atoi(d.Arg(i))
+	if err != nil {
+		return 0, fmt.Errorf("некорректный аргумент %d действия %s: %w", i, d.Action, err)
+	}
+	return value, nil
+}
+
+// Encode кодирует данные в строку без подписи и без учета ограничения длины
+func (d CallbackData) Encode() string {
+	return callbackVersion + callbackSeparator + d.encodeBody()
+}
+
+// encodeBody кодирует действие и аргументы без версии формата
+func (d CallbackData) encodeBody() string {
+	var b strings.Builder
+	b.WriteString(callbackEscaper.Replace(d.Action))
+	for _, arg := range d.Args {
+		b.WriteString(callbackSeparator)
+		b.WriteString(callbackEscaper.Replace(arg))
+	}
+	return b.String()
+}
+
+// DecodeCallback разбирает строку callback-данных. Строки без версии считаются
+// данными старого формата "действие" или "действие:аргументы"; в них не бывает
+// разделителя, поэтому строки с разделителем и другой версией формата отклоняются
+// с ErrCallbackMalformed
+func DecodeCallback(raw string) (CallbackData, error) {
+	body, ok := strings.CutPrefix(raw, callbackVersion+callbackSeparator)
+	if !ok {
+		if version, _, found := strings.Cut(raw, callbackSeparator); found {
+			return CallbackData{}, fmt.Errorf("%w: неизвестная версия формата %q", ErrCallbackMalformed, version)
+		}
+		action, rest, found := strings.Cut(raw, ":")
+		if action == "" {
+			return CallbackData{}, fmt.Errorf("%w: нет действия", ErrCallbackMalformed)
+		}
+		data := CallbackData{Action: action}
+		if found {
+			data.Args = strings.SplitN(rest, ":", 2)
+		}
+		return data, nil
+	}
+
+	parts := strings.Split(body, callbackSeparator)
+	for i, part := range parts {
+		parts[i] = callbackUnescaper.Replace(part)
+	}
+	if parts[0] == "" {
+		return CallbackData{}, fmt.Errorf("%w: нет действия", ErrCallbackMalformed)
+	}
+	return CallbackData{Action: parts[0], Args: parts[1:]}, nil
+}
+
+// CallbackStore хранит callback-данные, не помещающиеся в 64 байта; в кнопку
+// записывается только короткий идентификатор
+type CallbackStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]storedCallback
+	// ids идентификаторы по данным, чтобы повторная отрисовка экрана не создавала новых записей
+	ids map[string]string
+	now func() time.Time
+}
+
+type storedCallback struct {
+	payload   string
+	expiresAt time.Time
+}
+
+// NewCallbackStore создает хранилище с временем жизни записей ttl
+func NewCallbackStore(ttl time.Duration) *CallbackStore {
+	if ttl <= 0 {
+		ttl = DefaultCallbackTTL
+	}
+	return &CallbackStore{
+		ttl:     ttl,
+		entries: make(map[string]storedCallback),
+		ids:     make(map[string]string),
+		now:     time.Now,
+	}
+}
+
+// Put сохраняет данные и возвращает их идентификатор
+func (s *CallbackStore) Put(payload string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if id, ok := s.ids[payload]; ok {
+		s.entries[id] = storedCallback{payload: payload, expiresAt: now.Add(s.ttl)}
+		return id
+	}
+
+	if len(s.entries) >= maxStoredCallbacks {
+		s.prune(now)
+	}
+
+	id := newCallbackID()
+	s.entries[id] = storedCallback{payload: payload, expiresAt: now.Add(s.ttl)}
+	s.ids[payload] = id
+	return id
+}
+
+// Get возвращает сохраненные данные, если срок их хранения не истек
+func (s *CallbackStore) Get(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok || s.now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.payload, true
+}
+
+// prune удаляет просроченные записи, а если их нет — запись, которая истекает раньше всех
+func (s *CallbackStore) prune(now time.Time) {
+	oldestID, oldest := "", time.Time{}
+	for id, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			s.remove(id)
+			continue
+		}
+		if oldestID == "" || entry.expiresAt.Before(oldest) {
+			oldestID, oldest = id, entry.expiresAt
+		}
+	}
+	if len(s.entries) >= maxStoredCallbacks && oldestID != "" {
+		s.remove(oldestID)
+	}
+}
+
+func (s *CallbackStore) remove(id string) {
+	delete(s.ids, s.entries[id].payload)
+	delete(s.entries, id)
+}
+
+// newCallbackID возвращает случайный идентификатор из 8 символов
+func newCallbackID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("bot: ошибка генерации идентификатора: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// CallbackEncoder кодирует данные inline-кнопок
+type CallbackEncoder interface {
+	Encode(data CallbackData) string
+}
+
+// CallbackHandler обработчик callback-запроса для одного действия
+type CallbackHandler func(ctx context.Context, callback *types.CallbackQuery, data CallbackData)
+
+// CallbackAuthorizer проверяет, есть ли у нажавшего кнопку пользователя право permission
+type CallbackAuthorizer func(callback *types.CallbackQuery, permission access.Permission) bool
+
+// callbackRoute обработчик действия и право, необходимое для его выполнения
+type callbackRoute struct {
+	handler    CallbackHandler
+	permission access.Permission
+}
+
+// CallbackRouter кодирует данные inline-кнопок и вызывает обработчик,
+// зарегистрированный для действия нажатой кнопки
+type CallbackRouter struct {
+	mu     sync.RWMutex
+	routes map[string]callbackRoute
+	// ttls сроки действия кнопок отдельных действий
+	ttls      map[string]time.Duration
+	store     *CallbackStore
+	signer    *CallbackSigner
+	authorize CallbackAuthorizer
+}
+
+// NewCallbackRouter создает маршрутизатор; store хранит данные длиннее 64 байт.
+// Если signer задан, данные кнопок подписываются, а неподписанные, измененные и
+// просроченные callback-запросы отклоняются. authorize может быть nil, тогда
+// доступны все действия
+func NewCallbackRouter(store *CallbackStore, signer *CallbackSigner, authorize CallbackAuthorizer) *CallbackRouter {
+	if store == nil {
+		store = NewCallbackStore(DefaultCallbackTTL)
+	}
+	return &CallbackRouter{
+		routes:    make(map[string]callbackRoute),
+		ttls:      make(map[string]time.Duration),
+		store:     store,
+		signer:    signer,
+		authorize: authorize,
+	}
+}
+
+// Expire задает срок действия кнопок для действий, например более короткий для
+// опасных действий вроде запуска релиза. Действует только при подписи данных
+func (r *CallbackRouter) Expire(ttl time.Duration, actions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, action := range actions {
+		r.ttls[action] = ttl
+	}
+}
+
+// Handle регистрирует обработчик действий, для выполнения которых нужно право permission
+func (r *CallbackRouter) Handle(permission access.Permission, handler CallbackHandler, actions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, action := range actions {
+		if action == "" || action == callbackStoredAction || strings.Contains(action, callbackSeparator) {
+			panic(fmt.Sprintf("bot: некорректное имя действия %q", action))
+		}
+		if _, exists := r.routes[action]; exists {
+			panic(fmt.Sprintf("bot: обработчик действия %s уже зарегистрирован", action))
+		}
+		r.routes[action] = callbackRoute{handler: handler, permission: permission}
+	}
+}
+
+// Encode кодирует и подписывает данные кнопки; если они длиннее 64 байт, сохраняет
+// их в хранилище и возвращает ссылку на них
+func (r *CallbackRouter) Encode(data CallbackData) string {
+	if r.signer == nil {
+		encoded := data.Encode()
+		if len(encoded) <= MaxCallbackDataLength {
+			return encoded
+		}
+		return r.storedRef(data).Encode()
+	}
+
+	r.mu.RLock()
+	ttl := r.ttls[data.Action]
+	r.mu.RUnlock()
+
+	signed := r.signer.Sign(data.encodeBody(), ttl)
+	if len(signed) <= MaxCallbackDataLength {
+		return signed
+	}
+	return r.signer.Sign(r.storedRef(data).encodeBody(), ttl)
+}
+
+// storedRef сохраняет данные в хранилище и возвращает ссылку на них
+func (r *CallbackRouter) storedRef(data CallbackData) CallbackData {
+	return CallbackData{Action: callbackStoredAction, Args: []string{r.store.Put(data.Encode())}}
+}
+
+// Paginator создает пагинатор, кнопки навигации которого кодируются этим маршрутизатором
+func (r *CallbackRouter) Paginator(prefix string, total int) Paginator {
+	pager := NewPaginator(prefix, total)
+	pager.Encoder = r
+	return pager
+}
+
+// Button создает inline-кнопку с действием action и аргументами args
+func (r *CallbackRouter) Button(text, action string, args ...any) types.InlineKeyboardButton {
+	return types.InlineKeyboardButton{Text: text, CallbackData: r.Encode(NewCallback(action, args...))}
+}
+
+// Decode проверяет подпись и разбирает callback-данные, подставляя сохраненные в хранилище
+func (r *CallbackRouter) Decode(raw string) (CallbackData, error) {
+	if r.signer != nil {
+		body, err := r.signer.Verify(raw)
+		if err != nil {
+			return CallbackData{}, err
+		}
+		raw = callbackVersion + callbackSeparator + body
+	}
+
+	data, err := DecodeCallback(raw)
+	if err != nil || data.Action != callbackStoredAction {
+		return data, err
+	}
+
+	payload, ok := r.store.Get(data.Arg(0))
+	if !ok {
+		return CallbackData{}, ErrCallbackExpired
+	}
+	return DecodeCallback(payload)
+}
+
+// Dispatch разбирает данные callback-запроса, проверяет право пользователя на
+// действие и вызывает его обработчик. Если права нет, возвращается ErrForbidden
+func (r *CallbackRouter) Dispatch(ctx context.Context, callback *types.CallbackQuery) error {
+	data, err := r.Decode(callback.Data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	route, ok := r.routes[data.Action]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAction, data.Action)
+	}
+	if r.authorize != nil && !r.authorize(callback, route.permission) {
+		return fmt.Errorf("%w: %s", ErrForbidden, data.Action)
+	}
+
+	route.handler(ctx, callback, data)
+	return nil
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tgbot/internal/access"
+	"tgbot/pkg/types"
+)
+
+// testCallbackStore хранилище callback-данных с управляемыми часами
+func testCallbackStore(now *time.Time) *CallbackStore {
+	store := NewCallbackStore(time.Hour)
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestCallbackRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data CallbackData
+		want string
+	}{
+		{"без аргументов", NewCallback("menu"), "1|menu"},
+		{"с аргументами", NewCallback("branch", 2, "feature/x"), "1|branch|2|feature/x"},
+		{"пустой аргумент", NewCallback("search", ""), "1|search|"},
+		{"разделитель и процент в аргументах", NewCallback("note", "a|b", "100%", "%7C"), "1|note|a%7Cb|100%25|%257C"},
+		{"двоеточие в аргументе", NewCallback("pr", "owner:branch"), "1|pr|owner:branch"},
+		{"кириллица", NewCallback("поиск", "ветка"), "1|поиск|ветка"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := tt.data.Encode()
+			if encoded != tt.want {
+				t.Errorf("закодировано %q, ожидается %q", encoded, tt.want)
+			}
+			got, err := DecodeCallback(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.data) {
+				t.Errorf("разобрано %+v, ожидается %+v", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestDecodeCallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    CallbackData
+		wantErr bool
+	}{
+		{"старый формат без аргументов", "menu", CallbackData{Action: "menu"}, false},
+		{"старый формат с аргументами", "branch:2:feature:x", CallbackData{Action: "branch", Args: []string{"2", "feature:x"}}, false},
+		{"пустые данные", "", CallbackData{}, true},
+		{"только версия", "1|", CallbackData{}, true},
+		{"нет действия", "1||2", CallbackData{}, true},
+		{"старый формат без действия", ":2", CallbackData{}, true},
+		{"неизвестная версия", "3|menu", CallbackData{}, true},
+		{"подписанные данные без проверки подписи", "2|abc|sig|menu", CallbackData{}, true},
+		{"разделитель без версии", "menu|2", CallbackData{}, true},
+		{"только разделители", "|||", CallbackData{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCallback(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCallbackMalformed) {
+					t.Errorf("ошибка %v, ожидается ErrCallbackMalformed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("разобрано %+v, ожидается %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackDataArgs(t *testing.T) {
+	data := NewCallback("page", 3, "x")
+	if data.Arg(1) != "x" || data.Arg(2) != "" || data.Arg(-1) != "" {
+		t.Errorf("аргументы %q, %q, %q", data.Arg(1), data.Arg(2), data.Arg(-1))
+	}
+	if page, err := data.Int(0); err != nil || page != 3 {
+		t.Errorf("Int(0) = %d, %v, ожидается 3", page, err)
+	}
+	if _, err := data.Int(1); err == nil {
+		t.Error("ожидается ошибка для нечислового аргумента")
+	}
+}
+
+func TestCallbackStore(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := testCallbackStore(&now)
+
+	id := store.Put("1|search|длинный запрос")
+	if payload, ok := store.Get(id); !ok || payload != "1|search|длинный запрос" {
+		t.Errorf("получено %q, %v", payload, ok)
+	}
+	if again := store.Put("1|search|длинный запрос"); again != id {
+		t.Errorf("повторное сохранение тех же данных вернуло %s, ожидается %s", again, id)
+	}
+	if _, ok := store.Get("неизвестный"); ok {
+		t.Error("получены данные по неизвестному идентификатору")
+	}
+
+	// Повторное сохранение продлевает срок хранения
+	now = now.Add(50 * time.Minute)
+	store.Put("1|search|длинный запрос")
+	now = now.Add(50 * time.Minute)
+	if _, ok := store.Get(id); !ok {
+		t.Error("данные истекли, хотя срок продлен повторным сохранением")
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if _, ok := store.Get(id); ok {
+		t.Error("получены данные с истекшим сроком хранения")
+	}
+}
+
+func TestCallbackStoreOverflow(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := testCallbackStore(&now)
+
+	payload := func(i int) string { return fmt.Sprintf("1|search|%d", i) }
+	first := store.Put(payload(0))
+	now = now.Add(time.Second)
+	for i := 1; i < maxStoredCallbacks; i++ {
+		store.Put(payload(i))
+	}
+
+	// Хранилище заполнено: новая запись вытесняет ту, что истекает раньше всех
+	latest := store.Put(payload(maxStoredCallbacks))
+	if len(store.entries) != maxStoredCallbacks || len(store.ids) != maxStoredCallbacks {
+		t.Errorf("записей %d (индекс %d), ожидается %d", len(store.entries), len(store.ids), maxStoredCallbacks)
+	}
+	if _, ok := store.Get(first); ok {
+		t.Error("самая старая запись не вытеснена")
+	}
+	if got, ok := store.Get(latest); !ok || got != payload(maxStoredCallbacks) {
+		t.Errorf("новая запись: %q, %v", got, ok)
+	}
+
+	// Когда все записи истекли, при переполнении они удаляются разом
+	now = now.Add(2 * time.Hour)
+	store.Put(payload(-1))
+	if len(store.entries) != 1 || len(store.ids) != 1 {
+		t.Errorf("после удаления истекших записей осталось %d (индекс %d), ожидается 1", len(store.entries), len(store.ids))
+	}
+}
+
+func TestCallbackRouterStoredData(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	router := NewCallbackRouter(testCallbackStore(&now), nil, nil)
+
+	data := NewCallback("search", strings.Repeat("запрос ", 20))
+	raw := router.Encode(data)
+	if len(raw) > MaxCallbackDataLength {
+		t.Fatalf("длина данных %d больше %d", len(raw), MaxCallbackDataLength)
+	}
+	if !strings.HasPrefix(raw, "1|~|") {
+		t.Errorf("данные %q, ожидается ссылка на хранилище", raw)
+	}
+	got, err := router.Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Errorf("разобрано %+v, ожидается %+v", got, data)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := router.Decode(raw); !errors.Is(err, ErrCallbackExpired) {
+		t.Errorf("ошибка %v, ожидается ErrCallbackExpired", err)
+	}
+	if _, err := router.Decode("1|~|неизвестный"); !errors.Is(err, ErrCallbackExpired) {
+		t.Errorf("ссылка на неизвестные данные: ошибка %v, ожидается ErrCallbackExpired", err)
+	}
+}
+
+func TestCallbackRouterDispatch(t *testing.T) {
+	router := NewCallbackRouter(nil, nil, nil)
+	var handled []CallbackData
+	router.Handle(access.PermissionPublic, func(_ context.Context, _ *types.CallbackQuery, data CallbackData) {
+		handled = append(handled, data)
+	}, "menu")
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr error
+	}{
+		{"известное действие", "1|menu|2", nil},
+		{"неизвестное действие", "1|unknown", ErrUnknownAction},
+		{"пустые данные", "", ErrCallbackMalformed},
+		{"неизвестная версия", "9|menu", ErrCallbackMalformed},
+		{"без действия", "1|", ErrCallbackMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled = nil
+			err := router.Dispatch(context.Background(), &types.CallbackQuery{Data: tt.raw})
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(handled) != 1 || handled[0].Arg(0) != "2" {
+					t.Errorf("обработчик вызван с %+v", handled)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ошибка %v, ожидается %v", err, tt.wantErr)
+			}
+			if len(handled) != 0 {
+				t.Errorf("обработчик вызван с %+v", handled)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"tgbot/internal/access"
+	"tgbot/internal/format"
+	"tgbot/pkg/types"
+)
+
+// Ошибки маршрутизации команд
+var (
+	// ErrUnknownCommand команда не зарегистрирована
+	ErrUnknownCommand = errors.New("неизвестная команда")
+	// ErrForbidden у пользователя нет прав на выполнение команды
+	ErrForbidden = errors.New("нет доступа к команде")
+)
+
+// Arg описание аргумента команды
+type Arg struct {
+	// Name имя аргумента, по которому его значение доступно обработчику
+	Name string
+	// Description описание аргумента для справки
+	Description string
+	// Required обязательный аргумент
+	Required bool
+	// Rest аргумент забирает весь оставшийся текст; допустим только последним
+	Rest bool
+	// Validate проверяет значение аргумента (необязательно)
+	Validate func(value string) error
+}
+
+// CommandHandler обработчик команды
+type CommandHandler func(ctx context.Context, request *CommandRequest) error
+
+// Command описание команды бота
+type Command struct {
+	// Name имя команды без косой черты, в нижнем регистре
+	Name string
+	// Description краткое описание для справки и меню команд Telegram
+	Description string
+	// Descriptions описания на других языках по коду языка, например "en"
+	Descriptions map[string]string
+	// Args аргументы команды в порядке следования
+	Args []Arg
+	// Permission право, необходимое для выполнения команды
+	Permission access.Permission
+	// Hidden не показывать команду в справке
+	Hidden bool
+	// Handler обработчик команды
+	Handler CommandHandler
+}
+
+// Usage возвращает строку использования команды, например "/help [команда]"
+func (c *Command) Usage() string {
+	var b strings.Builder
+	b.WriteString("/" + c.Name)
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Required {
+			b.WriteString(" <" + name + ">")
+		} else {
+			b.WriteString(" [" + name + "]")
+		}
+	}
+	return b.String()
+}
+
+// LocalizedDescription возвращает описание команды на языке languageCode,
+// а если перевода нет — основное описание
+func (c *Command) LocalizedDescription(languageCode string) string {
+	if description, ok := c.Descriptions[languageCode]; ok && description != "" {
+		return description
+	}
+	return c.Description
+}
+
+// CommandRequest вызов команды
+type CommandRequest struct {
+	// Message сообщение с командой
+	Message *types.Message
+	// Command вызванная команда
+	Command *Command
+	// Args значения аргументов по именам; необязательные аргументы могут отсутствовать
+	Args map[string]string
+	// RawArgs строка аргументов без разбора
+	RawArgs string
+}
+
+// Arg возвращает значение аргумента или пустую строку, если он не указан
+func (r *CommandRequest) Arg(name string) string {
+	return r.Args[name]
+}
+
+// UsageError ошибка в аргументах команды
+type UsageError struct {
+	Command *Command
+	Reason  string
+}
+
+// Error реализует интерфейс error
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s. Использование: %s", e.Reason, e.Command.Usage())
+}
+
+// Authorizer проверяет, есть ли у отправителя сообщения право permission
+type Authorizer func(message *types.Message, permission access.Permission) bool
+
+// CommandRouter маршрутизатор команд: находит зарегистрированную команду по имени,
+// проверяет доступ, разбирает аргументы и вызывает обработчик
+type CommandRouter struct {
+	mu        sync.RWMutex
+	botName   string
+	commands  map[string]*Command
+	order     []*Command
+	authorize Authorizer
+}
+
+// NewCommandRouter создает маршрутизатор команд. authorize может быть nil, тогда
+// доступны все команды
+func NewCommandRouter(authorize Authorizer) *CommandRouter {
+	return &CommandRouter{
+		commands:  make(map[string]*Command),
+		authorize: authorize,
+	}
+}
+
+// SetBotName задает имя бота. Команды вида /cmd@OtherBot, адресованные другим
+// ботам в группе, игнорируются. Пока имя не задано, принимаются команды с любым суффиксом
+func (r *CommandRouter) SetBotName(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.botName = strings.TrimPrefix(name, "@")
+}
+
+// Register регистрирует команду. Повторная регистрация имени и некорректное
+// описание аргументов считаются ошибкой программы
+func (r *CommandRouter) Register(command Command) {
+	command.Name = strings.ToLower(strings.TrimPrefix(command.Name, "/"))
+	if command.Name == "" || command.Handler == nil {
+		panic("bot: команда должна иметь имя и обработчик")
+	}
+	for i, arg := range command.Args {
+		if arg.Rest && i != len(command.Args)-1 {
+			panic(fmt.Sprintf("bot: аргумент %s команды /%s забирает остаток текста и должен быть последним", arg.Name, command.Name))
+		}
+		if arg.Required && i > 0 && !command.Args[i-1].Required {
+			panic(fmt.Sprintf("bot: обязательный аргумент %s команды /%s следует за необязательным", arg.Name, command.Name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.commands[command.Name]; exists {
+		panic(fmt.Sprintf("bot: команда /%s уже зарегистрирована", command.Name))
+	}
+	r.commands[command.Name] = &command
+	r.order = append(r.order, &command)
+}
+
+// Lookup возвращает зарегистрированную команду по имени
+func (r *CommandRouter) Lookup(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	command, ok := r.commands[strings.ToLower(strings.TrimPrefix(name, "/"))]
+	return command, ok
+}
+
+// Commands возвращает команды в порядке регистрации
+func (r *CommandRouter) Commands() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Command(nil), r.order...)
+}
+
+// Handle обрабатывает сообщение с командой. handled равен false, если сообщение не
+// является командой; команды, адресованные другому боту, пропускаются. Ошибки доступа и
+// использования возвращаются как ErrForbidden, ErrUnknownCommand и *UsageError
+func (r *CommandRouter) Handle(ctx context.Context, message *types.Message) (handled bool, err error) {
+	name, botName, rawArgs, ok := ParseCommand(message.Text)
+	if !ok {
+		return false, nil
+	}
+
+	r.mu.RLock()
+	ownName := r.botName
+	command, exists := r.commands[name]
+	r.mu.RUnlock()
+
+	if botName != "" && ownName != "" && !strings.EqualFold(botName, ownName) {
+		return true, nil
+	}
+	if !exists {
+		return true, fmt.Errorf("%w: /%s", ErrUnknownCommand, name)
+	}
+	if !r.Allowed(message, command) {
+		return true, fmt.Errorf("%w: /%s", ErrForbidden, name)
+	}
+
+	args, err := command.parseArgs(rawArgs)
+	if err != nil {
+		return true, err
+	}
+
+	return true, command.Handler(ctx, &CommandRequest{
+		Message: message,
+		Command: command,
+		Args:    args,
+		RawArgs: rawArgs,
+	})
+}
+
+// Allowed сообщает, может ли отправитель сообщения выполнить команду
+func (r *CommandRouter) Allowed(message *types.Message, command *Command) bool {
+	return r.authorize == nil || r.authorize(message, command.Permission)
+}
+
+// parseArgs разбирает и проверяет аргументы команды
+func (c *Command) parseArgs(rawArgs string) (map[string]string, error) {
+	values, err := SplitArgs(rawArgs)
+	if err != nil {
+		return nil, &UsageError{Command: c, Reason: "Ошибка в аргументах: " + err.Error()}
+	}
+
+	args := make(map[string]string, len(c.Args))
+	for i, arg := range c.Args {
+		if i >= len(values) {
+			if arg.Required {
+				return nil, &UsageError{Command: c, Reason: fmt.Sprintf("Не указан аргумент «%s»", arg.Name)}
+			}
+			continue
+		}
+
+		value := values[i]
+		if arg.Rest {
+			value = strings.Join(values[i:], " ")
+		}
+		if arg.Validate != nil {
+			if err := arg.Validate(value); err != nil {
+				return nil, &UsageError{Command: c, Reason: fmt.Sprintf("Некорректный аргумент «%s»: %v", arg.Name, err)}
+			}
+		}
+		args[arg.Name] = value
+	}
+
+	if len(values) > len(c.Args) && (len(c.Args) == 0 || !c.Args[len(c.Args)-1].Rest) {
+		return nil, &UsageError{Command: c, Reason: "Слишком много аргументов"}
+	}
+
+	return args, nil
+}
+
+// Help формирует справку по командам, доступным отправителю сообщения
+func (r *CommandRouter) Help(message *types.Message) *format.Message {
+	help := format.New()
+	for _, command := range r.Commands() {
+		if command.Hidden || !r.Allowed(message, command) {
+			continue
+		}
+		help.Line(format.Code(command.Usage()), format.Text(" - "+command.Description))
+	}
+	return help
+}
+
+// BotCommands возвращает меню команд Telegram на языке languageCode из команд,
+// доступных отправителю сообщения message
+func (r *CommandRouter) BotCommands(message *types.Message, languageCode string) []types.BotCommand {
+	return r.botCommands(languageCode, func(command *Command) bool {
+		return r.Allowed(message, command)
+	})
+}
+
+// RoleBotCommands возвращает меню команд Telegram на языке languageCode из команд,
+// доступных пользователю с ролью role в разрешенном чате
+func (r *CommandRouter) RoleBotCommands(role access.Role, languageCode string) []types.BotCommand {
+	return r.botCommands(languageCode, func(command *Command) bool {
+		return role.Can(command.Permission)
+	})
+}
+
+// botCommands возвращает меню из видимых команд, для которых allowed возвращает true
+func (r *CommandRouter) botCommands(languageCode string, allowed func(command *Command) bool) []types.BotCommand {
+	var botCommands []types.BotCommand
+	for _, command := range r.Commands() {
+		if command.Hidden || !allowed(command) {
+			continue
+		}
+		botCommands = append(botCommands, types.BotCommand{
+			Command:     command.Name,
+			Description: command.LocalizedDescription(languageCode),
+		})
+	}
+	return botCommands
+}
+
+// CommandHelp формирует подробную справку по одной команде
+func CommandHelp(command *Command) *format.Message {
+	help := format.New().
+		Line(format.Code(command.Usage())).
+		Line(format.Text(command.Description))
+
+	if len(command.Args) > 0 {
+		help.Blank().Line(format.Bold("Аргументы:"))
+		for _, arg := range command.Args {
+			description := arg.Description
+			if !arg.Required {
+				description += " (необязательный)"
+			}
+			help.Item(format.Code(arg.Name), format.Text(" - "+description))
+		}
+	}
+
+	return help
+}
@@ -1,35 +1,385 @@
 // Package bot предоставляет функционал для работы с конфигурацией бота
+// и общие компоненты интерфейса бота
 package bot
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 
+	"tgbot/internal/access"
 	"tgbot/pkg/types"
 )
 
-// LoadConfig загружает конфигурацию бота из файла или переменных окружения
-func LoadConfig() (*types.BotConfig, error) {
-	// Сначала пробуем загрузить из файла
-	configPath := filepath.Join("utils", "tgapi.json")
-	configData, err := os.ReadFile(configPath)
-	if err == nil {
-		var config types.BotConfig
-		if err := json.Unmarshal(configData, &config); err == nil {
-			return &config, nil
+// DefaultConfigPath файл конфигурации по умолчанию. Вместо него может использоваться
+// файл с тем же именем в формате YAML или TOML
+var DefaultConfigPath = filepath.Join("utils", "tgapi.json")
+
+const (
+	// ConfigPathEnv переменная окружения с путем к файлу конфигурации
+	ConfigPathEnv = "TGBOT_CONFIG"
+	// configEnvPrefix префикс переменных окружения с параметрами конфигурации
+	configEnvPrefix = "TGBOT_"
+)
+
+// legacyEnv переменные окружения прежних версий; переменные с префиксом TGBOT_
+// имеют приоритет над ними
+var legacyEnv = map[string]string{
+	"bot_key":      "TG_KEY",
+	"github_token": "GITHUB_TOKEN",
+	"github_owner": "GITHUB_OWNER",
+	"github_repo":  "GITHUB_REPO",
+}
+
+// DefaultConfig возвращает конфигурацию со значениями по умолчанию
+func DefaultConfig() *types.BotConfig {
+	return &types.BotConfig{
+		UpdateMode:                    types.UpdateModePolling,
+		ShutdownTimeoutSeconds:        30,
+		Workers:                       8,
+		QueueSize:                     100,
+		CallbackTTLHours:              int(DefaultSignedCallbackTTL.Hours()),
+		UserRatePerMinute:             30,
+		ReleaseApprovalTimeoutMinutes: int(DefaultApprovalTTL.Minutes()),
+		StorageFile:                   filepath.Join("utils", "bot.db"),
+		AccessFile:                    filepath.Join("utils", "access.json"),
+		TOTPFile:                      filepath.Join("utils", "totp.json"),
+		AuditFile:                     filepath.Join("utils", "audit.jsonl"),
+	}
+}
+
+// LoadConfig загружает конфигурацию бота из источников, описанных в ConfigLoader
+func LoadConfig(args []string) (*types.BotConfig, error) {
+	loader, err := NewConfigLoader(args)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// ConfigLoader загружает конфигурацию бота. Каждый следующий источник переопределяет
+// предыдущий: значения по умолчанию, файл, переменные окружения, флаги командной
+// строки (кроме секретов). Путь к файлу задается флагом -config или переменной TGBOT_CONFIG.
+// Загрузку можно повторять, например чтобы перечитать измененный файл
+type ConfigLoader struct {
+	path string
+	// explicit файл указан явно и должен существовать
+	explicit bool
+	// flags значения флагов командной строки по именам параметров
+	flags map[string]*flagValue
+}
+
+// NewConfigLoader разбирает флаги командной строки args и определяет файл конфигурации
+func NewConfigLoader(args []string) (*ConfigLoader, error) {
+	loader := &ConfigLoader{explicit: true, flags: make(map[string]*flagValue)}
+
+	flags := flag.NewFlagSet("bot", flag.ContinueOnError)
+	flags.StringVar(&loader.path, "config", "", "файл конфигурации (по умолчанию "+DefaultConfigPath+")")
+	// Секреты флагами не принимаются: аргументы процесса видны другим пользователям
+	// системы (ps, /proc). Вместо них можно передать флаг с путем к файлу (*_file)
+	secrets := make(map[string]bool)
+	for _, ref := range secretRefs(DefaultConfig()) {
+		secrets[ref.key] = true
+	}
+	for _, field := range configFields(DefaultConfig()) {
+		if secrets[field.key] {
+			continue
+		}
+		value := &flagValue{isBool: field.value.Kind() == reflect.Bool}
+		loader.flags[field.key] = value
+		flags.Var(value, flagName(field.key), "параметр "+field.key)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("лишние аргументы командной строки: %s", strings.Join(flags.Args(), " "))
+	}
+
+	// Файл по умолчанию необязателен: конфигурацию можно задать переменными окружения
+	if loader.path == "" {
+		loader.path = os.Getenv(ConfigPathEnv)
+	}
+	if loader.path == "" {
+		loader.path, loader.explicit = findDefaultConfig(), false
+	}
+	return loader, nil
+}
+
+// Path возвращает путь к файлу конфигурации
+func (l *ConfigLoader) Path() string {
+	return l.path
+}
+
+// Load загружает и проверяет конфигурацию. Все ошибки в значениях и проверки
+// конфигурации возвращаются вместе
+func (l *ConfigLoader) Load() (*types.BotConfig, error) {
+	config := DefaultConfig()
+	if err := loadConfigFile(l.path, l.explicit, config); err != nil {
+		return nil, err
+	}
+
+	fields := configFields(config)
+	var errs []error
+	for _, field := range fields {
+		name, raw, ok := lookupEnv(field.key)
+		if !ok {
+			continue
+		}
+		if err := field.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("переменная %s: %w", name, err))
+		}
+	}
+	for _, field := range fields {
+		value := l.flags[field.key]
+		if value == nil || !value.set {
+			continue
+		}
+		if err := field.set(value.raw); err != nil {
+			errs = append(errs, fmt.Errorf("флаг -%s: %w", flagName(field.key), err))
+		}
+	}
+
+	if err := resolveSecrets(config); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ValidateConfig(config); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadConfigFile читает файл конфигурации в формате JSON, YAML или TOML поверх
+// значений config. Неизвестные параметры считаются ошибкой, чтобы опечатка в имени
+// не оставалась незамеченной
+func loadConfigFile(path string, required bool, config *types.BotConfig) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
+	}
+
+	if err := decodeConfig(path, data, config); err != nil {
+		return fmt.Errorf("ошибка разбора файла конфигурации %s: %w", path, err)
+	}
+	return nil
+}
+
+// ValidateConfig проверяет конфигурацию и возвращает сразу все найденные ошибки
+func ValidateConfig(config *types.BotConfig) error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	required := map[string]string{
+		"bot_key":      config.TgBotKey,
+		"github_token": config.GitHubToken,
+		"github_owner": config.GitHubOwner,
+		"github_repo":  config.GitHubRepo,
+	}
+	secrets := make(map[string]bool)
+	for _, ref := range secretRefs(config) {
+		secrets[ref.key] = true
+	}
+	for _, key := range []string{"bot_key", "github_token", "github_owner", "github_repo"} {
+		if strings.TrimSpace(required[key]) != "" {
+			continue
+		}
+		if secrets[key] {
+			add("не задан параметр %s (%s_file или переменная %s)", key, key, envName(key))
+		} else {
+			add("не задан параметр %s (переменная %s)", key, envName(key))
+		}
+	}
+
+	switch config.UpdateMode {
+	case "", types.UpdateModePolling:
+	case types.UpdateModeWebhook:
+		if config.WebhookURL == "" {
+			add("не задан параметр webhook_url, обязательный в режиме webhook")
+		} else if u, err := url.Parse(config.WebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			add("webhook_url должен быть адресом https://, указано %q", config.WebhookURL)
+		}
+		if config.WebhookListenAddr == "" {
+			add("не задан параметр webhook_listen_addr, обязательный в режиме webhook")
+		}
+		if (config.WebhookCertFile == "") != (config.WebhookKeyFile == "") {
+			add("webhook_cert_file и webhook_key_file задаются вместе")
 		}
+	default:
+		add("неизвестный режим update_mode %q: допустимы %s и %s", config.UpdateMode, types.UpdateModePolling, types.UpdateModeWebhook)
 	}
 
-	// Если не удалось загрузить из файла, пробуем получить из переменной окружения
-	apiKey := os.Getenv("TG_KEY")
-	if apiKey != "" {
-		return &types.BotConfig{
-			TgBotKey: apiKey,
-			// TODO: Загрузить остальные параметры из переменных окружения
-		}, nil
+	positive := []struct {
+		key   string
+		value int
+	}{
+		{"shutdown_timeout_seconds", config.ShutdownTimeoutSeconds},
+		{"workers", config.Workers},
+		{"queue_size", config.QueueSize},
+		{"callback_ttl_hours", config.CallbackTTLHours},
+		{"user_rate_per_minute", config.UserRatePerMinute},
+		{"release_approval_timeout_minutes", config.ReleaseApprovalTimeoutMinutes},
+	}
+	for _, setting := range positive {
+		if setting.value <= 0 {
+			add("%s должен быть больше нуля, указано %d", setting.key, setting.value)
+		}
+	}
+	if config.ReleaseApprovals < 0 {
+		add("release_approvals не может быть отрицательным, указано %d", config.ReleaseApprovals)
+	}
+
+	for _, id := range config.AllowedUserIDs {
+		if id <= 0 {
+			add("allowed_user_ids: некорректный ID пользователя %d", id)
+		}
 	}
+	for _, id := range config.AllowedChatIDs {
+		if id == 0 {
+			add("allowed_chat_ids: ID чата не может быть нулевым")
+		}
+	}
+	if config.DefaultRole != "" {
+		if _, err := access.ParseRole(config.DefaultRole); err != nil {
+			add("default_role: %w", err)
+		}
+	}
+	for i, assignment := range config.Roles {
+		if assignment.UserID <= 0 {
+			add("roles[%d]: некорректный ID пользователя %d", i, assignment.UserID)
+		}
+		if _, err := access.ParseRole(assignment.Role); err != nil {
+			add("roles[%d]: %w", i, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configField параметр конфигурации, который можно задать строкой
+type configField struct {
+	// key имя параметра в файле конфигурации
+	key   string
+	value reflect.Value
+}
+
+// configFields возвращает параметры config, которые задаются переменными окружения
+// и флагами: строки, числа, флаги и списки ID. Составные параметры (roles)
+// задаются только в файле
+func configFields(config *types.BotConfig) []configField {
+	value := reflect.ValueOf(config).Elem()
+	var fields []configField
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.Int64 {
+				continue
+			}
+		default:
+			continue
+		}
+		fields = append(fields, configField{key: key, value: field})
+	}
+	return fields
+}
+
+// set разбирает строковое значение параметра
+func (f configField) set(raw string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, указано %q", raw)
+		}
+		f.value.SetBool(value)
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, f.value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("ожидается целое число, указано %q", raw)
+		}
+		f.value.SetInt(value)
+	case reflect.Slice:
+		var ids []int64
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return fmt.Errorf("ожидается список ID через запятую, указано %q", item)
+			}
+			ids = append(ids, id)
+		}
+		f.value.Set(reflect.ValueOf(ids))
+	}
+	return nil
+}
+
+// envName возвращает имя переменной окружения для параметра key
+func envName(key string) string {
+	return configEnvPrefix + strings.ToUpper(key)
+}
+
+// flagName возвращает имя флага командной строки для параметра key
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// lookupEnv ищет значение параметра key в переменных окружения: сначала
+// TGBOT_<KEY>, затем переменную прежних версий
+func lookupEnv(key string) (name, value string, ok bool) {
+	name = envName(key)
+	if value, ok = os.LookupEnv(name); ok {
+		return name, value, true
+	}
+	if legacy, exists := legacyEnv[key]; exists {
+		if value, ok = os.LookupEnv(legacy); ok && value != "" {
+			return legacy, value, true
+		}
+	}
+	return "", "", false
+}
+
+// flagValue значение флага командной строки; разбирается после файла и переменных
+// окружения, чтобы флаги имели наивысший приоритет
+type flagValue struct {
+	raw    string
+	set    bool
+	isBool bool
+}
+
+// String реализует flag.Value
+func (v *flagValue) String() string {
+	return v.raw
+}
+
+// Set реализует flag.Value
+func (v *flagValue) Set(raw string) error {
+	v.raw, v.set = raw, true
+	return nil
+}
 
-	return nil, fmt.Errorf("не удалось загрузить конфигурацию бота")
+// IsBoolFlag позволяет указывать логические флаги без значения: -skip-backlog
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
 }
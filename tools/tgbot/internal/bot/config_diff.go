@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"tgbot/pkg/types"
+)
+
+// ConfigChange изменение параметра конфигурации
+type ConfigChange struct {
+	// Key имя параметра в файле конфигурации
+	Key string
+	Old string
+	New string
+}
+
+// String возвращает описание изменения для журнала
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %s → %s", c.Key, c.Old, c.New)
+}
+
+// DiffConfig возвращает параметры, значения которых различаются в old и new.
+// Значения секретов в результат не попадают
+func DiffConfig(old, new *types.BotConfig) []ConfigChange {
+	secrets := make(map[string]bool)
+	for _, ref := range secretRefs(old) {
+		secrets[ref.key] = true
+	}
+
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	var changes []ConfigChange
+	for i := 0; i < oldValue.NumField(); i++ {
+		key, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		before, after := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		change := ConfigChange{Key: key, Old: formatConfigValue(before), New: formatConfigValue(after)}
+		if secrets[key] {
+			change.Old, change.New = "***", "*** (изменен)"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// formatConfigValue возвращает значение параметра в записи JSON
+func formatConfigValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
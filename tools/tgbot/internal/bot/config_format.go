@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"tgbot/pkg/types"
+)
+
+// configExtensions форматы файла конфигурации по расширению
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// findDefaultConfig возвращает файл конфигурации по умолчанию: utils/tgapi с любым
+// из поддерживаемых расширений. Если файла нет, возвращает путь к JSON-файлу
+func findDefaultConfig() string {
+	base := strings.TrimSuffix(DefaultConfigPath, filepath.Ext(DefaultConfigPath))
+	for _, ext := range configExtensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return DefaultConfigPath
+}
+
+// decodeConfig разбирает файл конфигурации в формате по расширению path. YAML и TOML
+// приводятся к JSON, поэтому во всех форматах действуют одни имена параметров и
+// неизвестные параметры одинаково считаются ошибкой
+func decodeConfig(path string, data []byte, config *types.BotConfig) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		var values map[string]any
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		converted, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("ошибка преобразования YAML: %w", err)
+		}
+		data = converted
+	case ".toml":
+		var values map[string]any
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		converted, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("ошибка преобразования TOML: %w", err)
+		}
+		data = converted
+	default:
+		return fmt.Errorf("неизвестный формат %q: поддерживаются %s", ext, strings.Join(configExtensions, ", "))
+	}
+
+	// Пустой YAML-файл разбирается в null и не меняет значения по умолчанию
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("лишние данные после объекта")
+	}
+	return nil
+}
+
+// secretRef параметр-секрет и путь к файлу, из которого его можно прочитать
+type secretRef struct {
+	key   string
+	value *string
+	file  string
+}
+
+// secretRefs возвращает секреты конфигурации, которые можно хранить в отдельных файлах
+func secretRefs(config *types.BotConfig) []secretRef {
+	return []secretRef{
+		{"bot_key", &config.TgBotKey, config.TgBotKeyFile},
+		{"github_token", &config.GitHubToken, config.GitHubTokenFile},
+		{"webhook_secret", &config.WebhookSecret, config.WebhookSecretFile},
+		{"callback_secret", &config.CallbackSecret, config.CallbackSecretFile},
+	}
+}
+
+// resolveSecrets читает секреты из файлов, указанных в параметрах *_file, например
+// github_token_file: /run/secrets/gh. Перевод строки в конце файла отбрасывается
+func resolveSecrets(config *types.BotConfig) error {
+	var errs []error
+	for _, ref := range secretRefs(config) {
+		if ref.file == "" {
+			continue
+		}
+		if *ref.value != "" {
+			errs = append(errs, fmt.Errorf("заданы одновременно %s и %s_file, оставьте один из них", ref.key, ref.key))
+			continue
+		}
+		data, err := os.ReadFile(ref.file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_file: ошибка чтения секрета: %w", ref.key, err))
+			continue
+		}
+		secret := strings.TrimRight(string(data), "\r\n")
+		if strings.TrimSpace(secret) == "" {
+			errs = append(errs, fmt.Errorf("%s_file: файл %s пуст", ref.key, ref.file))
+			continue
+		}
+		*ref.value = secret
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+// fixtureConfig конфигурация, описанная файлами testdata/config.*
+func fixtureConfig() *types.BotConfig {
+	config := DefaultConfig()
+	config.TgBotKey = "123456:ABC"
+	config.GitHubToken = "ghp_token"
+	config.GitHubOwner = "owner"
+	config.GitHubRepo = "repo"
+	config.AllowedUserIDs = []int64{1001, 1002}
+	config.AllowedChatIDs = []int64{-1001234567890}
+	config.UpdateMode = types.UpdateModeWebhook
+	config.WebhookURL = "https://bot.example.com/hook"
+	config.WebhookListenAddr = ":8443"
+	config.Workers = 4
+	config.SkipBacklog = true
+	config.DefaultRole = "viewer"
+	config.Roles = []types.RoleAssignment{
+		{UserID: 1001, Role: "admin"},
+		{UserID: 1002, Role: "release_manager", ChatID: -1001234567890},
+	}
+	config.ReleaseApprovals = 2
+	config.ReleaseChatID = -1001234567890
+	return config
+}
+
+func TestConfigFormats(t *testing.T) {
+	want := fixtureConfig()
+	for _, name := range []string{"config.json", "config.yaml", "config.toml"} {
+		t.Run(name, func(t *testing.T) {
+			clearConfigEnv(t)
+			config, err := LoadConfig([]string{"-config", filepath.Join("testdata", name)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(config, want) {
+				t.Errorf("конфигурация\n%+v\nожидается\n%+v", config, want)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"tgapi.json", `{"worker": 4}`},
+		{"tgapi.yaml", "worker: 4\n"},
+		{"tgapi.yml", "worker: 4\n"},
+		{"tgapi.toml", "worker = 4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeConfig(tt.name, []byte(tt.content), DefaultConfig())
+			if err == nil || !strings.Contains(err.Error(), `unknown field "worker"`) {
+				t.Errorf("ошибка %v, ожидается ошибка о неизвестном параметре", err)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigEdgeCases(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		wantErr bool
+	}{
+		{"пустой YAML", "tgapi.yaml", "", false},
+		{"YAML с комментарием", "tgapi.yml", "# настройки\n", false},
+		{"пустой TOML", "tgapi.toml", "", false},
+		{"расширение в верхнем регистре", "TGAPI.JSON", "{}", false},
+		{"неизвестный формат", "tgapi.ini", "workers=4", true},
+		{"синтаксическая ошибка YAML", "tgapi.yaml", "workers: [4", true},
+		{"синтаксическая ошибка TOML", "tgapi.toml", "workers = ", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			err := decodeConfig(tt.path, []byte(tt.content), config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидается ошибка: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(config, DefaultConfig()) {
+				t.Errorf("пустой файл изменил значения по умолчанию: %+v", config)
+			}
+		})
+	}
+}
+
+func TestFindDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	defaultPath := DefaultConfigPath
+	DefaultConfigPath = filepath.Join(dir, "tgapi.json")
+	t.Cleanup(func() { DefaultConfigPath = defaultPath })
+
+	if got := findDefaultConfig(); got != DefaultConfigPath {
+		t.Errorf("без файлов выбран %s, ожидается %s", got, DefaultConfigPath)
+	}
+	toml := filepath.Join(dir, "tgapi.toml")
+	if err := os.WriteFile(toml, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := findDefaultConfig(); got != toml {
+		t.Errorf("выбран %s, ожидается %s", got, toml)
+	}
+	// JSON проверяется первым
+	if err := os.WriteFile(DefaultConfigPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := findDefaultConfig(); got != DefaultConfigPath {
+		t.Errorf("выбран %s, ожидается %s", got, DefaultConfigPath)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		// file путь к файлу секрета bot_key_file
+		file  string
+		value string
+		want  string
+		// wantErr фрагмент ожидаемой ошибки
+		wantErr string
+	}{
+		{name: "без перевода строки", file: secretFile("plain", "secret"), want: "secret"},
+		{name: "перевод строки в конце", file: secretFile("lf", "secret\n"), want: "secret"},
+		{name: "перевод строки Windows", file: secretFile("crlf", "secret\r\n"), want: "secret"},
+		{name: "несколько переводов строки", file: secretFile("lines", "secret\n\n"), want: "secret"},
+		{name: "пробелы сохраняются", file: secretFile("spaces", " sec ret \n"), want: " sec ret "},
+		{name: "значение без файла", value: "inline", want: "inline"},
+		{name: "файл не найден", file: filepath.Join(dir, "missing"), wantErr: "bot_key_file: ошибка чтения секрета"},
+		{name: "пустой файл", file: secretFile("empty", "\n"), wantErr: "пуст"},
+		{name: "значение и файл вместе", file: secretFile("both", "secret"), value: "inline", wantErr: "заданы одновременно bot_key и bot_key_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.TgBotKey, config.TgBotKeyFile = tt.value, tt.file
+
+			err := resolveSecrets(config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ошибка %v, ожидается ошибка с %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.TgBotKey != tt.want {
+				t.Errorf("bot_key = %q, ожидается %q", config.TgBotKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingSecretFile(t *testing.T) {
+	clearConfigEnv(t)
+	missing := filepath.Join(t.TempDir(), "gh")
+	path := writeConfig(t, "tgapi.yaml", "bot_key: key\ngithub_token_file: "+missing+"\ngithub_owner: owner\ngithub_repo: repo\n")
+
+	_, err := LoadConfig([]string{"-config", path})
+	if err == nil {
+		t.Fatal("отсутствующий файл секрета не вернул ошибку")
+	}
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "github_token_file") {
+		t.Errorf("ошибка %v, ожидается ошибка чтения github_token_file", err)
+	}
+
+	// Секрет из файла, заданного переменной окружения, читается так же
+	t.Setenv("TGBOT_GITHUB_TOKEN_FILE", writeConfig(t, "gh", "token\n"))
+	config, err := LoadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GitHubToken != "token" {
+		t.Errorf("github_token = %q, ожидается token", config.GitHubToken)
+	}
+}
@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+// requiredConfig обязательные параметры в формате JSON
+const requiredConfig = `"bot_key": "file-key", "github_token": "file-token", "github_owner": "owner", "github_repo": "file-repo"`
+
+// clearConfigEnv убирает переменные окружения конфигурации до конца теста, чтобы
+// окружение, в котором запущены тесты, не влияло на результат
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	names := []string{ConfigPathEnv}
+	for _, field := range configFields(DefaultConfig()) {
+		names = append(names, envName(field.key))
+	}
+	for _, legacy := range legacyEnv {
+		names = append(names, legacy)
+	}
+	for _, name := range names {
+		if _, ok := os.LookupEnv(name); ok {
+			// t.Setenv восстановит прежнее значение после теста
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// writeConfig записывает файл конфигурации name во временный каталог теста
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setEnv задает переменные окружения до конца теста
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		// file дополнительные параметры файла конфигурации
+		file string
+		env  map[string]string
+		args []string
+
+		wantWorkers int
+		wantRepo    string
+		wantChats   []int64
+		wantSkip    bool
+	}{
+		{
+			name:        "значения по умолчанию",
+			wantWorkers: 8,
+			wantRepo:    "file-repo",
+		},
+		{
+			name:        "файл переопределяет значения по умолчанию",
+			file:        `"workers": 4, "allowed_chat_ids": [-1], "skip_backlog": true`,
+			wantWorkers: 4,
+			wantRepo:    "file-repo",
+			wantChats:   []int64{-1},
+			wantSkip:    true,
+		},
+		{
+			name:        "окружение переопределяет файл",
+			file:        `"workers": 4, "allowed_chat_ids": [-1], "skip_backlog": true`,
+			env:         map[string]string{"TGBOT_WORKERS": "6", "TGBOT_GITHUB_REPO": "env-repo", "TGBOT_ALLOWED_CHAT_IDS": "-2, -3", "TGBOT_SKIP_BACKLOG": "false"},
+			wantWorkers: 6,
+			wantRepo:    "env-repo",
+			wantChats:   []int64{-2, -3},
+		},
+		{
+			name:        "флаги переопределяют окружение",
+			file:        `"workers": 4`,
+			env:         map[string]string{"TGBOT_WORKERS": "6", "TGBOT_GITHUB_REPO": "env-repo"},
+			args:        []string{"-workers", "10", "-github-repo=flag-repo", "-allowed-chat-ids=-4", "-skip-backlog"},
+			wantWorkers: 10,
+			wantRepo:    "flag-repo",
+			wantChats:   []int64{-4},
+			wantSkip:    true,
+		},
+		{
+			name:        "пустая переменная очищает значение из файла",
+			file:        `"allowed_chat_ids": [-1]`,
+			env:         map[string]string{"TGBOT_ALLOWED_CHAT_IDS": ""},
+			wantWorkers: 8,
+			wantRepo:    "file-repo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			setEnv(t, tt.env)
+			content := "{" + requiredConfig
+			if tt.file != "" {
+				content += ", " + tt.file
+			}
+			path := writeConfig(t, "tgapi.json", content+"}")
+
+			config, err := LoadConfig(append([]string{"-config", path}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Workers != tt.wantWorkers {
+				t.Errorf("workers = %d, ожидается %d", config.Workers, tt.wantWorkers)
+			}
+			if config.GitHubRepo != tt.wantRepo {
+				t.Errorf("github_repo = %q, ожидается %q", config.GitHubRepo, tt.wantRepo)
+			}
+			if !reflect.DeepEqual(config.AllowedChatIDs, tt.wantChats) {
+				t.Errorf("allowed_chat_ids = %v, ожидается %v", config.AllowedChatIDs, tt.wantChats)
+			}
+			if config.SkipBacklog != tt.wantSkip {
+				t.Errorf("skip_backlog = %v, ожидается %v", config.SkipBacklog, tt.wantSkip)
+			}
+			// Параметры, не заданные ни в одном источнике, сохраняют значения по умолчанию
+			if config.QueueSize != DefaultConfig().QueueSize {
+				t.Errorf("queue_size = %d, ожидается значение по умолчанию", config.QueueSize)
+			}
+		})
+	}
+}
+
+func TestLoadConfigLegacyEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantKey   string
+		wantToken string
+	}{
+		{
+			name:      "без переменных",
+			wantKey:   "file-key",
+			wantToken: "file-token",
+		},
+		{
+			name:      "переменные прежних версий",
+			env:       map[string]string{"TG_KEY": "legacy-key", "GITHUB_TOKEN": "legacy-token"},
+			wantKey:   "legacy-key",
+			wantToken: "legacy-token",
+		},
+		{
+			name:      "переменные TGBOT_ имеют приоритет",
+			env:       map[string]string{"TG_KEY": "legacy-key", "TGBOT_BOT_KEY": "new-key", "GITHUB_TOKEN": "legacy-token"},
+			wantKey:   "new-key",
+			wantToken: "legacy-token",
+		},
+		{
+			name:      "пустая переменная прежней версии не учитывается",
+			env:       map[string]string{"TG_KEY": ""},
+			wantKey:   "file-key",
+			wantToken: "file-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			setEnv(t, tt.env)
+			path := writeConfig(t, "tgapi.json", "{"+requiredConfig+"}")
+
+			config, err := LoadConfig([]string{"-config", path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.TgBotKey != tt.wantKey || config.GitHubToken != tt.wantToken {
+				t.Errorf("bot_key = %q, github_token = %q; ожидаются %q, %q", config.TgBotKey, config.GitHubToken, tt.wantKey, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	clearConfigEnv(t)
+	setEnv(t, map[string]string{"TG_KEY": "key", "GITHUB_TOKEN": "token", "GITHUB_OWNER": "owner", "GITHUB_REPO": "repo"})
+	defaultPath := DefaultConfigPath
+	DefaultConfigPath = filepath.Join(t.TempDir(), "tgapi.json")
+	t.Cleanup(func() { DefaultConfigPath = defaultPath })
+
+	// Файл по умолчанию необязателен
+	config, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GitHubRepo != "repo" {
+		t.Errorf("github_repo = %q, ожидается repo", config.GitHubRepo)
+	}
+
+	// Явно указанный файл должен существовать
+	if _, err := LoadConfig([]string{"-config", "missing.json"}); err == nil {
+		t.Error("отсутствующий файл из -config не вернул ошибку")
+	}
+	t.Setenv(ConfigPathEnv, "missing.json")
+	if _, err := LoadConfig(nil); err == nil {
+		t.Error("отсутствующий файл из TGBOT_CONFIG не вернул ошибку")
+	}
+}
+
+func TestLoadConfigRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		args    []string
+		wantErr string
+	}{
+		{"неизвестный параметр", `{` + requiredConfig + `, "worker": 4}`, nil, `unknown field "worker"`},
+		{"неверный тип значения", `{` + requiredConfig + `, "workers": "4"}`, nil, "workers"},
+		{"лишние данные после объекта", `{` + requiredConfig + `} {}`, nil, "лишние данные"},
+		{"неизвестный флаг", `{` + requiredConfig + `}`, []string{"-worker=4"}, "worker"},
+		{"лишние аргументы", `{` + requiredConfig + `}`, []string{"run"}, "лишние аргументы"},
+		{"секрет bot_key во флаге", `{` + requiredConfig + `}`, []string{"-bot-key=flag-key"}, "bot-key"},
+		{"секрет github_token во флаге", `{` + requiredConfig + `}`, []string{"-github-token", "flag-token"}, "github-token"},
+		{"секрет webhook_secret во флаге", `{` + requiredConfig + `}`, []string{"-webhook-secret=s"}, "webhook-secret"},
+		{"секрет callback_secret во флаге", `{` + requiredConfig + `}`, []string{"-callback-secret=s"}, "callback-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			path := writeConfig(t, "tgapi.json", tt.file)
+			_, err := LoadConfig(append([]string{"-config", path}, tt.args...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %v, ожидается ошибка с %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSecretsWithoutFlags(t *testing.T) {
+	clearConfigEnv(t)
+	setEnv(t, map[string]string{"TGBOT_GITHUB_TOKEN": "env-token"})
+	keyFile := writeConfig(t, "bot_key", "flag-file-key\n")
+	path := writeConfig(t, "tgapi.json", `{"github_owner": "owner", "github_repo": "repo"}`)
+
+	// Секреты передаются окружением или флагом с путем к файлу секрета
+	config, err := LoadConfig([]string{"-config", path, "-bot-key-file", keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.TgBotKey != "flag-file-key" || config.GitHubToken != "env-token" {
+		t.Errorf("bot_key = %q, github_token = %q; ожидается flag-file-key и env-token", config.TgBotKey, config.GitHubToken)
+	}
+
+	// Флагов для самих секретов нет, и в справке они не показываются
+	loader, err := NewConfigLoader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range secretRefs(DefaultConfig()) {
+		if _, ok := loader.flags[ref.key]; ok {
+			t.Errorf("секрет %s можно задать флагом -%s", ref.key, flagName(ref.key))
+		}
+		if _, ok := loader.flags[ref.key+"_file"]; !ok {
+			t.Errorf("нет флага -%s", flagName(ref.key+"_file"))
+		}
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	clearConfigEnv(t)
+	setEnv(t, map[string]string{"TGBOT_QUEUE_SIZE": "много"})
+	path := writeConfig(t, "tgapi.json", `{
+		"github_owner": "owner",
+		"github_repo": "repo",
+		"update_mode": "push",
+		"workers": 0,
+		"allowed_user_ids": [-5]
+	}`)
+
+	_, err := LoadConfig([]string{"-config", path, "-callback-ttl-hours", "x"})
+	if err == nil {
+		t.Fatal("ошибочная конфигурация не вернула ошибку")
+	}
+	for _, want := range []string{
+		"переменная TGBOT_QUEUE_SIZE: ожидается целое число",
+		"флаг -callback-ttl-hours: ожидается целое число",
+		"не задан параметр bot_key",
+		"не задан параметр github_token",
+		`неизвестный режим update_mode "push"`,
+		"workers должен быть больше нуля",
+		"некорректный ID пользователя -5",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("в ошибке нет %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := func(change func(config *types.BotConfig)) *types.BotConfig {
+		config := DefaultConfig()
+		config.TgBotKey, config.GitHubToken = "key", "token"
+		config.GitHubOwner, config.GitHubRepo = "owner", "repo"
+		if change != nil {
+			change(config)
+		}
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		config *types.BotConfig
+		// wantErrs фрагменты ожидаемых ошибок; пустой список — конфигурация корректна
+		wantErrs []string
+	}{
+		{"корректная конфигурация", valid(nil), nil},
+		{
+			name: "корректный webhook",
+			config: valid(func(c *types.BotConfig) {
+				c.UpdateMode, c.WebhookURL, c.WebhookListenAddr = types.UpdateModeWebhook, "https://bot.example.com/hook", ":8443"
+			}),
+		},
+		{
+			name:     "webhook без адресов",
+			config:   valid(func(c *types.BotConfig) { c.UpdateMode = types.UpdateModeWebhook }),
+			wantErrs: []string{"webhook_url", "webhook_listen_addr"},
+		},
+		{
+			name: "webhook по http и сертификат без ключа",
+			config: valid(func(c *types.BotConfig) {
+				c.UpdateMode, c.WebhookURL, c.WebhookListenAddr = types.UpdateModeWebhook, "http://bot.example.com", ":8080"
+				c.WebhookCertFile = "cert.pem"
+			}),
+			wantErrs: []string{"https://", "webhook_cert_file и webhook_key_file"},
+		},
+		{
+			name:     "пустые обязательные параметры",
+			config:   valid(func(c *types.BotConfig) { c.TgBotKey, c.GitHubOwner = " ", "" }),
+			wantErrs: []string{"bot_key (bot_key_file или переменная TGBOT_BOT_KEY)", "github_owner (переменная TGBOT_GITHUB_OWNER)"},
+		},
+		{
+			name: "роли и списки доступа",
+			config: valid(func(c *types.BotConfig) {
+				c.DefaultRole = "root"
+				c.AllowedChatIDs = []int64{0}
+				c.Roles = []types.RoleAssignment{{UserID: 0, Role: "admin"}, {UserID: 1, Role: "boss"}}
+				c.ReleaseApprovals = -1
+			}),
+			wantErrs: []string{"default_role", "allowed_chat_ids", "roles[0]: некорректный ID", "roles[1]", "release_approvals"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("ошибка %v, ожидается корректная конфигурация", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ошибка не возвращена, ожидаются %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("в ошибке нет %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultConfirmationTTL время на подтверждение действия по умолчанию
+const DefaultConfirmationTTL = 2 * time.Minute
+
+// Ошибки подтверждения действий
+var (
+	// ErrConfirmationExpired время на подтверждение истекло, действие уже подтверждено или отменено
+	ErrConfirmationExpired = errors.New("время на подтверждение истекло")
+	// ErrConfirmationNotOwner подтвердить действие пытается не тот пользователь, который его начал
+	ErrConfirmationNotOwner = errors.New("подтвердить действие может только пользователь, который его начал")
+	// ErrConfirmationChanged объект действия изменился с момента запроса подтверждения
+	ErrConfirmationChanged = errors.New("действие изменилось после запроса подтверждения")
+)
+
+// Confirmation действие, ожидающее подтверждения
+type Confirmation struct {
+	ID     string
+	UserID int64
+	// target хеш объекта действия: плана релиза, имени ветки или номера PR
+	target    [sha256.Size]byte
+	ExpiresAt time.Time
+}
+
+// Confirmations ожидающие подтверждения опасные действия (запуск релиза, удаление
+// ветки). Подтверждение одноразовое, ограничено по времени, принимается только
+// от пользователя, который начал действие, и только для того же объекта действия
+type Confirmations struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]Confirmation
+	now     func() time.Time
+}
+
+// NewConfirmations создает список подтверждений со временем на подтверждение ttl
+func NewConfirmations(ttl time.Duration) *Confirmations {
+	if ttl <= 0 {
+		ttl = DefaultConfirmationTTL
+	}
+	return &Confirmations{
+		ttl:     ttl,
+		pending: make(map[string]Confirmation),
+		now:     time.Now,
+	}
+}
+
+// TTL возвращает время на подтверждение
+func (c *Confirmations) TTL() time.Duration {
+	return c.ttl
+}
+
+// Start начинает действие пользователя userID над объектом target (например, планом
+// релиза) и возвращает идентификатор для кнопок подтверждения и отмены
+func (c *Confirmations) Start(userID int64, target string) Confirmation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for id, pending := range c.pending {
+		if now.After(pending.ExpiresAt) {
+			delete(c.pending, id)
+		}
+	}
+
+	confirmation := Confirmation{
+		ID:        newCallbackID(),
+		UserID:    userID,
+		target:    sha256.Sum256([]byte(target)),
+		ExpiresAt: now.Add(c.ttl),
+	}
+	c.pending[confirmation.ID] = confirmation
+	return confirmation
+}
+
+// Confirm подтверждает действие id от имени пользователя userID. target — текущее
+// состояние объекта действия: если оно отличается от переданного в Start, действие
+// завершается с ErrConfirmationChanged. После успешного подтверждения повторное
+// нажатие возвращает ErrConfirmationExpired
+func (c *Confirmations) Confirm(id string, userID int64, target string) error {
+	hash := sha256.Sum256([]byte(target))
+	return c.finish(id, userID, &hash)
+}
+
+// Cancel отменяет действие id от имени пользователя userID
+func (c *Confirmations) Cancel(id string, userID int64) error {
+	return c.finish(id, userID, nil)
+}
+
+// finish завершает действие, если оно не истекло, его начал userID и, если target
+// не nil, объект действия не изменился
+func (c *Confirmations) finish(id string, userID int64, target *[sha256.Size]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending, ok := c.pending[id]
+	if !ok || c.now().After(pending.ExpiresAt) {
+		delete(c.pending, id)
+		return ErrConfirmationExpired
+	}
+	if pending.UserID != userID {
+		return ErrConfirmationNotOwner
+	}
+
+	delete(c.pending, id)
+	if target != nil && *target != pending.target {
+		return ErrConfirmationChanged
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testConfirmations список подтверждений со временем, которым управляет тест через now
+func testConfirmations(now *time.Time) *Confirmations {
+	confirmations := NewConfirmations(time.Minute)
+	confirmations.now = func() time.Time { return *now }
+	return confirmations
+}
+
+func TestConfirmations(t *testing.T) {
+	const (
+		owner  int64 = 1
+		other  int64 = 2
+		target       = "release|v1.2.0|3|0|a,b,c"
+	)
+
+	tests := []struct {
+		name string
+		// confirm действия после Start; возвращает ошибку последнего из них
+		confirm func(t *testing.T, c *Confirmations, id string, now *time.Time) error
+		want    error
+	}{
+		{
+			name: "подтверждение владельцем",
+			confirm: func(_ *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				return c.Confirm(id, owner, target)
+			},
+		},
+		{
+			name: "подтверждение в последний момент",
+			confirm: func(_ *testing.T, c *Confirmations, id string, now *time.Time) error {
+				*now = now.Add(time.Minute)
+				return c.Confirm(id, owner, target)
+			},
+		},
+		{
+			name: "время истекло",
+			confirm: func(_ *testing.T, c *Confirmations, id string, now *time.Time) error {
+				*now = now.Add(time.Minute + time.Second)
+				return c.Confirm(id, owner, target)
+			},
+			want: ErrConfirmationExpired,
+		},
+		{
+			name: "подтверждает другой пользователь",
+			confirm: func(_ *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				return c.Confirm(id, other, target)
+			},
+			want: ErrConfirmationNotOwner,
+		},
+		{
+			name: "владелец подтверждает после попытки другого пользователя",
+			confirm: func(t *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				if err := c.Confirm(id, other, target); !errors.Is(err, ErrConfirmationNotOwner) {
+					t.Errorf("подтверждение другим пользователем: ошибка %v", err)
+				}
+				return c.Confirm(id, owner, target)
+			},
+		},
+		{
+			name: "повторное подтверждение",
+			confirm: func(t *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				if err := c.Confirm(id, owner, target); err != nil {
+					t.Errorf("первое подтверждение: %v", err)
+				}
+				return c.Confirm(id, owner, target)
+			},
+			want: ErrConfirmationExpired,
+		},
+		{
+			name: "объект изменился",
+			confirm: func(_ *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				return c.Confirm(id, owner, "release|v1.2.0|4|0|a,b,c,d")
+			},
+			want: ErrConfirmationChanged,
+		},
+		{
+			name: "после изменения объекта подтверждение недействительно",
+			confirm: func(t *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				if err := c.Confirm(id, owner, "release|v1.3.0|3|0|a,b,c"); !errors.Is(err, ErrConfirmationChanged) {
+					t.Errorf("подтверждение измененного объекта: ошибка %v", err)
+				}
+				return c.Confirm(id, owner, target)
+			},
+			want: ErrConfirmationExpired,
+		},
+		{
+			name: "подтверждение после отмены",
+			confirm: func(t *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				if err := c.Cancel(id, owner); err != nil {
+					t.Errorf("отмена: %v", err)
+				}
+				return c.Confirm(id, owner, target)
+			},
+			want: ErrConfirmationExpired,
+		},
+		{
+			name: "отмена другим пользователем",
+			confirm: func(_ *testing.T, c *Confirmations, id string, _ *time.Time) error {
+				return c.Cancel(id, other)
+			},
+			want: ErrConfirmationNotOwner,
+		},
+		{
+			name: "неизвестное подтверждение",
+			confirm: func(_ *testing.T, c *Confirmations, _ string, _ *time.Time) error {
+				return c.Confirm("неизвестное", owner, target)
+			},
+			want: ErrConfirmationExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			confirmations := testConfirmations(&now)
+			confirmation := confirmations.Start(owner, target)
+			if !confirmation.ExpiresAt.Equal(now.Add(time.Minute)) {
+				t.Errorf("срок подтверждения %v, ожидается %v", confirmation.ExpiresAt, now.Add(time.Minute))
+			}
+
+			err := tt.confirm(t, confirmations, confirmation.ID, &now)
+			if tt.want == nil && err != nil {
+				t.Errorf("ошибка %v, ожидается успех", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидается %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmationsPruneExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	confirmations := testConfirmations(&now)
+	expired := confirmations.Start(1, "branch_delete|feature/old")
+
+	now = now.Add(2 * time.Minute)
+	active := confirmations.Start(1, "branch_delete|feature/new")
+	if len(confirmations.pending) != 1 {
+		t.Errorf("ожидающих подтверждений %d, ожидается 1: истекшие удаляются при создании новых", len(confirmations.pending))
+	}
+	if expired.ID == active.ID {
+		t.Error("идентификаторы подтверждений совпадают")
+	}
+	if err := confirmations.Confirm(active.ID, 1, "branch_delete|feature/new"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"tgbot/pkg/types"
+)
+
+// Middleware оборачивает обработчик обновлений дополнительной логикой
+type Middleware func(next types.UpdateHandler) types.UpdateHandler
+
+// Chain оборачивает handler в middlewares. Первый middleware в списке внешний:
+// он получает обновление первым и завершается последним
+func Chain(handler types.UpdateHandler, middlewares ...Middleware) types.UpdateHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// UpdateUserID возвращает идентификатор пользователя, от которого пришло обновление
+func UpdateUserID(update types.Update) int64 {
+	switch {
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.UserID
+	case update.Message != nil:
+		return update.Message.UserID
+	default:
+		return 0
+	}
+}
+
+// UpdateChatID возвращает идентификатор чата, в котором пришло обновление
+func UpdateChatID(update types.Update) int64 {
+	switch {
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.ChatID
+	case update.Message != nil:
+		return update.Message.ChatID
+	default:
+		return 0
+	}
+}
+
+// updateKind возвращает тип обновления для логов
+func updateKind(update types.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		return "callback"
+	case update.Message != nil:
+		return "message"
+	default:
+		return "unknown"
+	}
+}
+
+type updateIDKey struct{}
+
+// UpdateID возвращает идентификатор обрабатываемого обновления, сохраненный middleware Logging
+func UpdateID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(updateIDKey{}).(int64)
+	return id, ok
+}
+
+// Logf записывает сообщение в лог с идентификатором обрабатываемого обновления
+func Logf(ctx context.Context, format string, args ...any) {
+	if id, ok := UpdateID(ctx); ok {
+		log.Printf("[update %d] %s", id, fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Recover перехватывает панику обработчика и записывает ее в лог вместе со стеком,
+// чтобы ошибка в одном обработчике не останавливала бота
+func Recover() Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					Logf(ctx, "Паника при обработке обновления: %v\n%s", recovered, debug.Stack())
+				}
+			}()
+			next(ctx, update)
+		}
+	}
+}
+
+// Logging сохраняет идентификатор обновления в контексте (см. Logf) и записывает
+// в лог тип обновления, пользователя и чат
+func Logging() Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			ctx = context.WithValue(ctx, updateIDKey{}, update.UpdateID)
+			Logf(ctx, "Получено обновление %s от пользователя %d в чате %d", updateKind(update), UpdateUserID(update), UpdateChatID(update))
+			next(ctx, update)
+		}
+	}
+}
+
+// Authorize пропускает к обработчику только обновления, для которых allow возвращает
+// true; для остальных вызывается denied (например, чтобы сообщить об отказе)
+func Authorize(allow func(update types.Update) bool, denied func(ctx context.Context, update types.Update)) Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			if !allow(update) {
+				Logf(ctx, "Доступ запрещен пользователю %d в чате %d", UpdateUserID(update), UpdateChatID(update))
+				if denied != nil {
+					denied(ctx, update)
+				}
+				return
+			}
+			next(ctx, update)
+		}
+	}
+}
+
+// Metrics статистика времени обработки обновлений
+type Metrics struct {
+	mu          sync.Mutex
+	started     time.Time
+	updates     int64
+	slow        int64
+	rateLimited int64
+	total       time.Duration
+	max         time.Duration
+}
+
+// MetricsSnapshot значения статистики на момент вызова Metrics.Snapshot
+type MetricsSnapshot struct {
+	// Uptime время с момента создания статистики
+	Uptime time.Duration
+	// Updates число обработанных обновлений
+	Updates int64
+	// Slow число обновлений, обработка которых заняла больше порога
+	Slow int64
+	// RateLimited число обновлений, отброшенных из-за ограничения частоты
+	RateLimited int64
+	// Average среднее время обработки
+	Average time.Duration
+	// Max максимальное время обработки
+	Max time.Duration
+}
+
+// NewMetrics создает пустую статистику
+func NewMetrics() *Metrics {
+	return &Metrics{started: time.Now()}
+}
+
+// observe учитывает время обработки одного обновления
+func (m *Metrics) observe(duration time.Duration, slow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates++
+	m.total += duration
+	if duration > m.max {
+		m.max = duration
+	}
+	if slow {
+		m.slow++
+	}
+}
+
+// observeRateLimited учитывает отброшенное обновление
+func (m *Metrics) observeRateLimited() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimited++
+}
+
+// Snapshot возвращает текущие значения статистики
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Uptime:      time.Since(m.started),
+		Updates:     m.updates,
+		Slow:        m.slow,
+		RateLimited: m.rateLimited,
+		Max:         m.max,
+	}
+	if m.updates > 0 {
+		snapshot.Average = m.total / time.Duration(m.updates)
+	}
+	return snapshot
+}
+
+// Timing измеряет время обработки обновлений и записывает в лог обновления,
+// обработка которых заняла больше slowThreshold (0 — не записывать). metrics может быть nil
+func Timing(metrics *Metrics, slowThreshold time.Duration) Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			start := time.Now()
+			defer func() {
+				duration := time.Since(start)
+				slow := slowThreshold > 0 && duration > slowThreshold
+				if slow {
+					Logf(ctx, "Медленная обработка обновления %s: %s", updateKind(update), duration.Round(time.Millisecond))
+				}
+				if metrics != nil {
+					metrics.observe(duration, slow)
+				}
+			}()
+			next(ctx, update)
+		}
+	}
+}
+
+// UserRateLimiter ограничивает частоту обновлений от одного пользователя
+// (token bucket: rate обновлений в минуту с запасом burst)
+type UserRateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[int64]*userBucket
+}
+
+type userBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxUserBuckets число пользователей, после которого удаляются заполненные корзины
+const maxUserBuckets = 1000
+
+// NewUserRateLimiter создает ограничитель: perMinute обновлений в минуту, но не больше burst подряд
+func NewUserRateLimiter(perMinute, burst int) *UserRateLimiter {
+	if perMinute <= 0 {
+		perMinute = 30
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &UserRateLimiter{
+		rate:    float64(perMinute) / float64(time.Minute),
+		burst:   float64(burst),
+		buckets: make(map[int64]*userBucket),
+	}
+}
+
+// Allow сообщает, можно ли обработать еще одно обновление от пользователя userID
+func (l *UserRateLimiter) Allow(userID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[userID]
+	if !ok {
+		if len(l.buckets) >= maxUserBuckets {
+			l.prune(now)
+		}
+		bucket = &userBucket{tokens: l.burst, last: now}
+		l.buckets[userID] = bucket
+	}
+
+	bucket.tokens += float64(now.Sub(bucket.last)) * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// prune удаляет корзины, которые успели заполниться (пользователь давно не писал)
+func (l *UserRateLimiter) prune(now time.Time) {
+	for userID, bucket := range l.buckets {
+		if bucket.tokens+float64(now.Sub(bucket.last))*l.rate >= l.burst {
+			delete(l.buckets, userID)
+		}
+	}
+}
+
+// RateLimit отбрасывает обновления пользователей, превысивших ограничение частоты;
+// для отброшенных обновлений вызывается limited. metrics может быть nil
+func RateLimit(limiter *UserRateLimiter, metrics *Metrics, limited func(ctx context.Context, update types.Update)) Middleware {
+	return func(next types.UpdateHandler) types.UpdateHandler {
+		return func(ctx context.Context, update types.Update) {
+			if !limiter.Allow(UpdateUserID(update)) {
+				Logf(ctx, "Превышена частота запросов пользователем %d", UpdateUserID(update))
+				if metrics != nil {
+					metrics.observeRateLimited()
+				}
+				if limited != nil {
+					limited(ctx, update)
+				}
+				return
+			}
+			next(ctx, update)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+// messageUpdate возвращает обновление с сообщением пользователя userID в чате chatID
+func messageUpdate(id, userID, chatID int64) types.Update {
+	return types.Update{
+		UpdateID: id,
+		Message:  &types.Message{UserID: userID, ChatID: chatID, Text: "привет"},
+	}
+}
+
+// recordingHandler обработчик-заглушка, запоминающий полученные обновления
+type recordingHandler struct {
+	updates []int64
+}
+
+func (h *recordingHandler) handle(_ context.Context, update types.Update) {
+	h.updates = append(h.updates, update.UpdateID)
+}
+
+// captureLog перенаправляет стандартный лог в буфер до конца теста
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	output, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(output)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next types.UpdateHandler) types.UpdateHandler {
+			return func(ctx context.Context, update types.Update) {
+				calls = append(calls, "до "+name)
+				next(ctx, update)
+				calls = append(calls, "после "+name)
+			}
+		}
+	}
+
+	tests := []struct {
+		name        string
+		middlewares []Middleware
+		want        []string
+	}{
+		{
+			name: "без middleware",
+			want: []string{"обработчик"},
+		},
+		{
+			name:        "первый middleware внешний",
+			middlewares: []Middleware{trace("a"), trace("b"), trace("c")},
+			want:        []string{"до a", "до b", "до c", "обработчик", "после c", "после b", "после a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			handler := Chain(func(context.Context, types.Update) {
+				calls = append(calls, "обработчик")
+			}, tt.middlewares...)
+			handler(context.Background(), messageUpdate(1, 10, 10))
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("порядок вызовов %q, ожидается %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   types.UpdateHandler
+		wantPanic bool
+	}{
+		{
+			name:    "обработчик без паники",
+			handler: func(context.Context, types.Update) {},
+		},
+		{
+			name:      "паника со строкой",
+			handler:   func(context.Context, types.Update) { panic("сломалось") },
+			wantPanic: true,
+		},
+		{
+			name: "паника с ошибкой времени выполнения",
+			handler: func(context.Context, types.Update) {
+				var message *types.Message
+				_ = message.Text
+			},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			handler := Chain(tt.handler, Logging(), Recover())
+
+			// Паника не должна выйти за пределы цепочки
+			handler(context.Background(), messageUpdate(7, 10, 10))
+
+			logged := strings.Contains(logs.String(), "[update 7] Паника при обработке обновления")
+			if logged != tt.wantPanic {
+				t.Errorf("запись о панике в логе: %v, ожидается %v; лог:\n%s", logged, tt.wantPanic, logs)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	const allowedUser = 10
+	allow := func(update types.Update) bool {
+		return UpdateUserID(update) == allowedUser
+	}
+
+	tests := []struct {
+		name        string
+		update      types.Update
+		wantHandled bool
+	}{
+		{"доступ разрешен", messageUpdate(1, allowedUser, -100), true},
+		{"доступ запрещен", messageUpdate(2, 20, -100), false},
+		{
+			name: "нажатие кнопки без доступа",
+			update: types.Update{
+				UpdateID:      3,
+				CallbackQuery: &types.CallbackQuery{UserID: 20, ChatID: -100},
+			},
+			wantHandled: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			handler := &recordingHandler{}
+			var denied []int64
+			chain := Chain(handler.handle, Authorize(allow, func(_ context.Context, update types.Update) {
+				denied = append(denied, update.UpdateID)
+			}))
+			chain(context.Background(), tt.update)
+
+			if handled := len(handler.updates) == 1; handled != tt.wantHandled {
+				t.Errorf("обновление передано обработчику: %v, ожидается %v", handled, tt.wantHandled)
+			}
+			if wasDenied := len(denied) == 1; wasDenied == tt.wantHandled {
+				t.Errorf("вызван denied: %v, ожидается %v", wasDenied, !tt.wantHandled)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	const burst = 3
+
+	tests := []struct {
+		name string
+		// users отправители обновлений по порядку
+		users       []int64
+		wantHandled []int64
+		wantLimited int64
+	}{
+		{
+			name:        "в пределах запаса",
+			users:       []int64{1, 1, 1},
+			wantHandled: []int64{1, 2, 3},
+		},
+		{
+			name:        "запас исчерпан",
+			users:       []int64{1, 1, 1, 1, 1},
+			wantHandled: []int64{1, 2, 3},
+			wantLimited: 2,
+		},
+		{
+			name:        "запас считается для каждого пользователя отдельно",
+			users:       []int64{1, 1, 1, 1, 2, 2},
+			wantHandled: []int64{1, 2, 3, 5, 6},
+			wantLimited: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			// Одно обновление в минуту: за время теста запас не пополняется
+			limiter := NewUserRateLimiter(1, burst)
+			metrics := NewMetrics()
+			handler := &recordingHandler{}
+			var limited int64
+			chain := Chain(handler.handle, RateLimit(limiter, metrics, func(context.Context, types.Update) {
+				limited++
+			}))
+
+			for i, userID := range tt.users {
+				chain(context.Background(), messageUpdate(int64(i+1), userID, userID))
+			}
+
+			if !reflect.DeepEqual(handler.updates, tt.wantHandled) {
+				t.Errorf("обработаны обновления %v, ожидаются %v", handler.updates, tt.wantHandled)
+			}
+			if limited != tt.wantLimited {
+				t.Errorf("отброшено %d обновлений, ожидается %d", limited, tt.wantLimited)
+			}
+			if got := metrics.Snapshot().RateLimited; got != tt.wantLimited {
+				t.Errorf("в статистике отброшено %d обновлений, ожидается %d", got, tt.wantLimited)
+			}
+		})
+	}
+}
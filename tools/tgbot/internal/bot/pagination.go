@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"fmt"
+
+	"tgbot/pkg/types"
+)
+
+// DefaultPageSize число элементов на странице по умолчанию
+const DefaultPageSize = 8
+
+// Paginator постраничный вывод списка в inline-клавиатуре. Кнопки навигации
+// содержат callback-данные с действием Prefix и номером страницы в аргументе
+type Paginator struct {
+	// Prefix действие callback-данных кнопок навигации
+	Prefix string
+	// PageSize число элементов на странице
+	PageSize int
+	// Total общее число элементов списка
+	Total int
+	// Encoder кодирует callback-данные кнопок навигации; если не задан, данные не подписываются
+	Encoder CallbackEncoder
+}
+
+// NewPaginator создает пагинатор для списка из total элементов
+func NewPaginator(prefix string, total int) Paginator {
+	return Paginator{Prefix: prefix, PageSize: DefaultPageSize, Total: total}
+}
+
+// Pages возвращает число страниц (не меньше одной)
+func (p Paginator) Pages() int {
+	if p.Total <= 0 || p.pageSize() <= 0 {
+		return 1
+	}
+	return (p.Total + p.pageSize() - 1) / p.pageSize()
+}
+
+// Clamp приводит номер страницы к допустимому диапазону
+func (p Paginator) Clamp(page int) int {
+	if page < 0 {
+		return 0
+	}
+	if last := p.Pages() - 1; page > last {
+		return last
+	}
+	return page
+}
+
+// Bounds возвращает индексы первого и следующего за последним элементов страницы
+func (p Paginator) Bounds(page int) (start, end int) {
+	page = p.Clamp(page)
+	start = page * p.pageSize()
+	end = start + p.pageSize()
+	if end > p.Total {
+		end = p.Total
+	}
+	return start, end
+}
+
+// PageOf возвращает номер страницы, на которой находится элемент с индексом index
+func (p Paginator) PageOf(index int) int {
+	return p.Clamp(index / p.pageSize())
+}
+
+// PageData формирует callback-данные перехода на страницу
+func (p Paginator) PageData(page int) string {
+	data := NewCallback(p.Prefix, page)
+	if p.Encoder != nil {
+		return p.Encoder.Encode(data)
+	}
+	return data.Encode()
+}
+
+// ParsePage извлекает номер страницы из callback-данных навигации.
+// Если номер не указан, возвращается первая страница
+func (p Paginator) ParsePage(data CallbackData) (int, bool) {
+	if data.Action != p.Prefix {
+		return 0, false
+	}
+	if len(data.Args) == 0 {
+		return 0, true
+	}
+	page, err := data.Int(0)
+	if err != nil {
+		return 0, false
+	}
+	return p.Clamp(page), true
+}
+
+// Navigation возвращает ряд кнопок навигации: назад, номер страницы и вперед.
+// Если страница одна, ряд пустой
+func (p Paginator) Navigation(page int) []types.InlineKeyboardButton {
+	pages := p.Pages()
+	if pages <= 1 {
+		return nil
+	}
+	page = p.Clamp(page)
+
+	var row []types.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, types.InlineKeyboardButton{Text: "◀️", CallbackData: p.PageData(page - 1)})
+	}
+	row = append(row, types.InlineKeyboardButton{
+		Text:         fmt.Sprintf("%d / %d", page+1, pages),
+		CallbackData: p.PageData(page),
+	})
+	if page < pages-1 {
+		row = append(row, types.InlineKeyboardButton{Text: "▶️", CallbackData: p.PageData(page + 1)})
+	}
+	return row
+}
+
+// Keyboard собирает клавиатуру страницы: по кнопке на элемент, ряд навигации и дополнительные ряды
+func (p Paginator) Keyboard(page int, items []types.InlineKeyboardButton, footer ...[]types.InlineKeyboardButton) [][]types.InlineKeyboardButton {
+	keyboard := make([][]types.InlineKeyboardButton, 0, len(items)+len(footer)+1)
+	for _, item := range items {
+		keyboard = append(keyboard, []types.InlineKeyboardButton{item})
+	}
+	if navigation := p.Navigation(page); len(navigation) > 0 {
+		keyboard = append(keyboard, navigation)
+	}
+	return append(keyboard, footer...)
+}
+
+func (p Paginator) pageSize() int {
+	if p.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return p.PageSize
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"testing"
+
+	"tgbot/pkg/types"
+)
+
+// buttonTexts возвращает надписи кнопок ряда
+func buttonTexts(row []types.InlineKeyboardButton) []string {
+	texts := make([]string, len(row))
+	for i, button := range row {
+		texts[i] = button.Text
+	}
+	return texts
+}
+
+func TestPaginatorBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		page      int
+		wantPages int
+		wantStart int
+		wantEnd   int
+		wantNav   []string
+	}{
+		{"пустой список", 0, 0, 1, 0, 0, nil},
+		{"пустой список, запрошена вторая страница", 0, 1, 1, 0, 0, nil},
+		{"одна неполная страница", 3, 0, 1, 0, 3, nil},
+		{"ровно одна страница", 8, 0, 1, 0, 8, nil},
+		{"первая страница", 19, 0, 3, 0, 8, []string{"1 / 3", "▶️"}},
+		{"средняя страница", 19, 1, 3, 8, 16, []string{"◀️", "2 / 3", "▶️"}},
+		{"последняя неполная страница", 19, 2, 3, 16, 19, []string{"◀️", "3 / 3"}},
+		{"последняя полная страница", 16, 1, 2, 8, 16, []string{"◀️", "2 / 2"}},
+		{"страница за концом списка", 19, 10, 3, 16, 19, []string{"◀️", "3 / 3"}},
+		{"отрицательная страница", 19, -1, 3, 0, 8, []string{"1 / 3", "▶️"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager := NewPaginator("page", tt.total)
+			if pages := pager.Pages(); pages != tt.wantPages {
+				t.Errorf("страниц %d, ожидается %d", pages, tt.wantPages)
+			}
+			if start, end := pager.Bounds(tt.page); start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("границы [%d, %d), ожидается [%d, %d)", start, end, tt.wantStart, tt.wantEnd)
+			}
+			texts := buttonTexts(pager.Navigation(tt.page))
+			if len(texts) != len(tt.wantNav) {
+				t.Fatalf("кнопки навигации %q, ожидается %q", texts, tt.wantNav)
+			}
+			for i := range texts {
+				if texts[i] != tt.wantNav[i] {
+					t.Errorf("кнопки навигации %q, ожидается %q", texts, tt.wantNav)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPaginatorPageOf(t *testing.T) {
+	pager := NewPaginator("page", 19)
+	for index, want := range map[int]int{0: 0, 7: 0, 8: 1, 18: 2, 25: 2} {
+		if page := pager.PageOf(index); page != want {
+			t.Errorf("элемент %d на странице %d, ожидается %d", index, page, want)
+		}
+	}
+}
+
+func TestPaginatorParsePage(t *testing.T) {
+	pager := NewPaginator("page", 19)
+	tests := []struct {
+		name     string
+		raw      string
+		wantPage int
+		wantOK   bool
+	}{
+		{"номер страницы", "1|page|1", 1, true},
+		{"без номера", "1|page", 0, true},
+		{"страница за концом списка", "1|page|99", 2, true},
+		{"отрицательная страница", "1|page|-3", 0, true},
+		{"номер не число", "1|page|два", 0, false},
+		{"другое действие", "1|menu|1", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := DecodeCallback(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, ok := pager.ParsePage(data)
+			if page != tt.wantPage || ok != tt.wantOK {
+				t.Errorf("страница %d, %v, ожидается %d, %v", page, ok, tt.wantPage, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPaginatorNavigationData(t *testing.T) {
+	pager := NewPaginator("page", 19)
+	for _, button := range pager.Navigation(1) {
+		data, err := DecodeCallback(button.CallbackData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, ok := pager.ParsePage(data)
+		if !ok {
+			t.Fatalf("кнопка %q: данные %q не разбираются", button.Text, button.CallbackData)
+		}
+		want := map[string]int{"◀️": 0, "2 / 3": 1, "▶️": 2}[button.Text]
+		if page != want {
+			t.Errorf("кнопка %q ведет на страницу %d, ожидается %d", button.Text, page, want)
+		}
+	}
+
+	// Клавиатура пустой страницы содержит только дополнительные ряды
+	footer := []types.InlineKeyboardButton{{Text: "Назад"}}
+	if keyboard := NewPaginator("page", 0).Keyboard(0, nil, footer); len(keyboard) != 1 {
+		t.Errorf("рядов клавиатуры пустого списка %d, ожидается 1", len(keyboard))
+	}
+}
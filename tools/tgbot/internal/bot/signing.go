@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// signedCallbackVersion версия формата подписанных callback-данных:
+	// "2|<срок действия>|<подпись>|действие|аргументы"
+	signedCallbackVersion = "2"
+	// signatureLength длина подписи в байтах до кодирования в base64 (64 бита)
+	signatureLength = 8
+
+	// DefaultSignedCallbackTTL срок действия подписанных кнопок по умолчанию
+	DefaultSignedCallbackTTL = 7 * 24 * time.Hour
+)
+
+// Ошибки проверки подписи callback-данных
+var (
+	// ErrCallbackUnsigned данные кнопки не подписаны
+	ErrCallbackUnsigned = errors.New("данные кнопки не подписаны")
+	// ErrCallbackSignature подпись данных кнопки не совпадает
+	ErrCallbackSignature = errors.New("неверная подпись данных кнопки")
+)
+
+// CallbackSigner подписывает callback-данные HMAC-SHA256 с секретом, известным только
+// серверу, и ограничивает срок их действия. Подпись усечена до 64 бит, чтобы данные
+// помещались в ограничение Telegram
+type CallbackSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewCallbackSigner создает подписывающий объект; ttl — срок действия кнопок по умолчанию
+func NewCallbackSigner(secret []byte, ttl time.Duration) *CallbackSigner {
+	if ttl <= 0 {
+		ttl = DefaultSignedCallbackTTL
+	}
+	return &CallbackSigner{
+		key: append([]byte(nil), secret...),
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// NewRandomSecret возвращает случайный секрет для подписи. Кнопки, подписанные им,
+// перестают действовать после перезапуска бота
+func NewRandomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("ошибка генерации секрета: %w", err)
+	}
+	return secret, nil
+}
+
+// TTL возвращает срок действия кнопок по умолчанию
+func (s *CallbackSigner) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign подписывает закодированные данные body (без версии) со сроком действия ttl
+func (s *CallbackSigner) Sign(body string, ttl time.Duration) string {
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+	expires := strconv.FormatInt(s.now().Add(ttl).Unix(), 36)
+	return signedCallbackVersion + callbackSeparator + expires + callbackSeparator + s.signature(expires, body) + callbackSeparator + body
+}
+
+// Verify проверяет подпись и срок действия данных и возвращает данные без подписи
+func (s *CallbackSigner) Verify(raw string) (string, error) {
+	signed, ok := strings.CutPrefix(raw, signedCallbackVersion+callbackSeparator)
+	if !ok {
+		return "", ErrCallbackUnsigned
+	}
+
+	parts := strings.SplitN(signed, callbackSeparator, 3)
+	if len(parts) != 3 {
+		return "", ErrCallbackSignature
+	}
+	expires, signature, body := parts[0], parts[1], parts[2]
+
+	if !hmac.Equal([]byte(signature), []byte(s.signature(expires, body))) {
+		return "", ErrCallbackSignature
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 36, 64)
+	if err != nil {
+		return "", ErrCallbackSignature
+	}
+	if s.now().Unix() > expiresAt {
+		return "", ErrCallbackExpired
+	}
+
+	return body, nil
+}
+
+// signature вычисляет усеченную подпись срока действия и данных
+func (s *CallbackSigner) signature(expires, body string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(expires + callbackSeparator + body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
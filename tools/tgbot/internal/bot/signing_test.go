@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigner возвращает подписывающий объект с управляемыми часами
+func testSigner(now *time.Time) *CallbackSigner {
+	signer := NewCallbackSigner([]byte("секрет"), time.Hour)
+	signer.now = func() time.Time { return *now }
+	return signer
+}
+
+func TestSignedCallbackRoundTrip(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	router := NewCallbackRouter(nil, testSigner(&now), nil)
+
+	tests := []struct {
+		name string
+		data CallbackData
+	}{
+		{"без аргументов", NewCallback("menu")},
+		{"с аргументами", NewCallback("release", "v1.2.3", 42)},
+		{"разделитель в аргументе", NewCallback("note", "a|b", "100%")},
+		{"длинные данные", NewCallback("search", strings.Repeat("запрос ", 20))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := router.Encode(tt.data)
+			if len(raw) > MaxCallbackDataLength {
+				t.Fatalf("длина данных %d больше %d", len(raw), MaxCallbackDataLength)
+			}
+			got, err := router.Decode(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.data) {
+				t.Errorf("разобрано %+v, ожидается %+v", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestSignedCallbackRejected(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	signer := testSigner(&now)
+	router := NewCallbackRouter(nil, signer, nil)
+	raw := router.Encode(NewCallback("approve", 7))
+
+	// Части подписанных данных: версия, срок действия, подпись, действие, аргументы
+	parts := strings.SplitN(raw, callbackSeparator, 4)
+	replace := func(i int, value string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = value
+		return strings.Join(changed, callbackSeparator)
+	}
+	// flip заменяет первый символ строки другим допустимым символом base64
+	flip := func(s string) string {
+		if s[0] == 'A' {
+			return "B" + s[1:]
+		}
+		return "A" + s[1:]
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		want error
+	}{
+		{"измененные аргументы", replace(3, "approve|8"), ErrCallbackSignature},
+		{"измененное действие", replace(3, "reject|7"), ErrCallbackSignature},
+		{"измененная подпись", replace(2, flip(parts[2])), ErrCallbackSignature},
+		{"продленный срок действия", replace(1, "zzzzzz"), ErrCallbackSignature},
+		{"подпись другим секретом", NewCallbackSigner([]byte("чужой"), time.Hour).Sign("approve|7", 0), ErrCallbackSignature},
+		{"обрезанные данные", "2|abc", ErrCallbackSignature},
+		{"неподписанные данные", NewCallback("approve", 7).Encode(), ErrCallbackUnsigned},
+		{"данные старого формата", "approve:7", ErrCallbackUnsigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := router.Decode(tt.raw); !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидается %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedCallbackExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	router := NewCallbackRouter(nil, testSigner(&now), nil)
+	router.Expire(time.Minute, "release")
+
+	release := router.Encode(NewCallback("release", "v1.0.0"))
+	menu := router.Encode(NewCallback("menu"))
+
+	tests := []struct {
+		name        string
+		after       time.Duration
+		wantRelease error
+		wantMenu    error
+	}{
+		{"в пределах срока", 30 * time.Second, nil, nil},
+		{"ровно в срок", time.Minute, nil, nil},
+		{"истек срок действия", time.Minute + time.Second, ErrCallbackExpired, nil},
+		{"истек срок по умолчанию", time.Hour + time.Second, ErrCallbackExpired, ErrCallbackExpired},
+	}
+	start := now
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.Add(tt.after)
+			if _, err := router.Decode(release); !errors.Is(err, tt.wantRelease) {
+				t.Errorf("кнопка релиза: ошибка %v, ожидается %v", err, tt.wantRelease)
+			}
+			if _, err := router.Decode(menu); !errors.Is(err, tt.wantMenu) {
+				t.Errorf("кнопка меню: ошибка %v, ожидается %v", err, tt.wantMenu)
+			}
+		})
+	}
+}
+
+func TestCallbackLengthBoundary(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	signer := testSigner(&now)
+
+	tests := []struct {
+		name   string
+		signer *CallbackSigner
+		// overhead длина закодированных данных с пустым аргументом
+		overhead int
+	}{
+		{"без подписи", nil, len(NewCallback("act", "").Encode())},
+		{"с подписью", signer, len(signer.Sign(NewCallback("act", "").encodeBody(), 0))},
+	}
+	for _, tt := range tests {
+		for _, extra := range []int{-1, 0, 1} {
+			length := MaxCallbackDataLength + extra
+			t.Run(fmt.Sprintf("%s, %d байт", tt.name, length), func(t *testing.T) {
+				store := NewCallbackStore(time.Hour)
+				router := NewCallbackRouter(store, tt.signer, nil)
+				data := NewCallback("act", strings.Repeat("x", length-tt.overhead))
+
+				raw := router.Encode(data)
+				if len(raw) > MaxCallbackDataLength {
+					t.Fatalf("длина данных %d больше %d", len(raw), MaxCallbackDataLength)
+				}
+				// До 64 байт включительно данные передаются в кнопке, длиннее — через хранилище
+				if inline := len(raw) == length; inline != (length <= MaxCallbackDataLength) {
+					t.Errorf("данные длиной %d закодированы в %q", length, raw)
+				}
+
+				got, err := router.Decode(raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, data) {
+					t.Errorf("разобрано %+v, ожидается %+v", got, data)
+				}
+			})
+		}
+	}
+}
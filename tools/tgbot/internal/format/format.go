@@ -0,0 +1,199 @@
+// Package format формирует тексты сообщений из типизированных элементов
+// (жирный текст, ссылки, код, списки) и выводит их в разметке MarkdownV2 или HTML
+// с корректным экранированием пользовательских данных
+package format
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Mode разметка, в которой выводится сообщение. Значения совпадают с parse_mode Telegram
+type Mode string
+
+const (
+	MarkdownV2 Mode = "MarkdownV2"
+	HTML       Mode = "HTML"
+)
+
+// Node элемент сообщения
+type Node interface {
+	render(mode Mode) string
+}
+
+type text string
+
+func (t text) render(mode Mode) string {
+	return Escape(mode, string(t))
+}
+
+// Text обычный текст
+func Text(s string) Node {
+	return text(s)
+}
+
+// Textf обычный текст, сформированный по шаблону
+func Textf(format string, args ...any) Node {
+	return text(fmt.Sprintf(format, args...))
+}
+
+// style тип оформления текста
+type style struct {
+	markdown string
+	tag      string
+}
+
+var (
+	styleBold          = style{markdown: "*", tag: "b"}
+	styleItalic        = style{markdown: "_", tag: "i"}
+	styleUnderline     = style{markdown: "__", tag: "u"}
+	styleStrikethrough = style{markdown: "~", tag: "s"}
+	styleSpoiler       = style{markdown: "||", tag: "tg-spoiler"}
+)
+
+type styled struct {
+	style    style
+	children []Node
+}
+
+func (s styled) render(mode Mode) string {
+	inner := renderNodes(mode, s.children)
+	if mode == HTML {
+		return "<" + s.style.tag + ">" + inner + "</" + s.style.tag + ">"
+	}
+	return s.style.markdown + inner + s.style.markdown
+}
+
+// Bold жирный текст
+func Bold(s string) Node {
+	return styled{style: styleBold, children: []Node{text(s)}}
+}
+
+// Boldf жирный текст, сформированный по шаблону
+func Boldf(format string, args ...any) Node {
+	return Bold(fmt.Sprintf(format, args...))
+}
+
+// Italic курсив
+func Italic(s string) Node {
+	return styled{style: styleItalic, children: []Node{text(s)}}
+}
+
+// Underline подчеркнутый текст
+func Underline(s string) Node {
+	return styled{style: styleUnderline, children: []Node{text(s)}}
+}
+
+// Strikethrough зачеркнутый текст
+func Strikethrough(s string) Node {
+	return styled{style: styleStrikethrough, children: []Node{text(s)}}
+}
+
+// Spoiler скрытый текст, который открывается по нажатию
+func Spoiler(s string) Node {
+	return styled{style: styleSpoiler, children: []Node{text(s)}}
+}
+
+type code string
+
+func (c code) render(mode Mode) string {
+	if mode == HTML {
+		return "<code>" + html.EscapeString(string(c)) + "</code>"
+	}
+	return "`" + escapeCode(string(c)) + "`"
+}
+
+// Code моноширинный текст внутри строки
+func Code(s string) Node {
+	return code(s)
+}
+
+type pre struct {
+	code     string
+	language string
+}
+
+func (p pre) render(mode Mode) string {
+	if mode == HTML {
+		if p.language != "" {
+			return `<pre><code class="language-` + html.EscapeString(p.language) + `">` + html.EscapeString(p.code) + "</code></pre>"
+		}
+		return "<pre>" + html.EscapeString(p.code) + "</pre>"
+	}
+	return "```" + p.language + "\n" + escapeCode(p.code) + "\n```"
+}
+
+// Pre блок кода; language может быть пустым
+func Pre(code, language string) Node {
+	return pre{code: code, language: language}
+}
+
+type link struct {
+	url      string
+	children []Node
+}
+
+func (l link) render(mode Mode) string {
+	inner := renderNodes(mode, l.children)
+	if mode == HTML {
+		return `<a href="` + html.EscapeString(l.url) + `">` + inner + "</a>"
+	}
+	return "[" + inner + "](" + escapeURL(l.url) + ")"
+}
+
+// Link ссылка с текстом s
+func Link(s, url string) Node {
+	return link{url: url, children: []Node{text(s)}}
+}
+
+type group []Node
+
+func (g group) render(mode Mode) string {
+	return renderNodes(mode, g)
+}
+
+// Join объединяет несколько элементов в один
+func Join(nodes ...Node) Node {
+	return group(nodes)
+}
+
+func renderNodes(mode Mode, nodes []Node) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		if node != nil {
+			b.WriteString(node.render(mode))
+		}
+	}
+	return b.String()
+}
+
+// markdownV2Special символы, которые нужно экранировать в тексте MarkdownV2
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// Escape экранирует обычный текст для указанной разметки
+func Escape(mode Mode, s string) string {
+	if mode == HTML {
+		return html.EscapeString(s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if strings.ContainsRune(markdownV2Special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeCode экранирует текст внутри `код` и ```блока``` MarkdownV2
+func escapeCode(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(s)
+}
+
+// escapeURL экранирует адрес ссылки MarkdownV2
+func escapeURL(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(s)
+}
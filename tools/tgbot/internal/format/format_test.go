@@ -0,0 +1,157 @@
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"обычный текст", "Релиз готов", "Релиз готов"},
+		{"ветка с подчеркиванием", "feature/snake_speed", `feature/snake\_speed`},
+		{"версия", "v1.2.3-rc.1", `v1\.2\.3\-rc\.1`},
+		{"обратная косая черта", `C:\path`, `C:\\path`},
+		{"markdown в имени PR", "*fix* [draft](x) `code`", "\\*fix\\* \\[draft\\]\\(x\\) \\`code\\`"},
+		{"эмодзи и кириллица", "🚀 Запуск!", `🚀 Запуск\!`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Escape(MarkdownV2, tt.in); got != tt.want {
+				t.Errorf("Escape(%q) = %q, ожидается %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	// Каждый специальный символ экранируется по отдельности
+	for _, r := range markdownV2Special {
+		if got, want := Escape(MarkdownV2, string(r)), `\`+string(r); got != want {
+			t.Errorf("Escape(%q) = %q, ожидается %q", r, got, want)
+		}
+	}
+}
+
+func TestEscapeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"обычный текст", "feature/snake_speed", "feature/snake_speed"},
+		{"теги", "<b>bold</b>", "&lt;b&gt;bold&lt;/b&gt;"},
+		{"амперсанд", "R&D", "R&amp;D"},
+		{"кавычки", `"a" 'b'`, "&#34;a&#34; &#39;b&#39;"},
+		{"символы markdown не экранируются", "*_`[]()\\", "*_`[]()\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Escape(HTML, tt.in); got != tt.want {
+				t.Errorf("Escape(%q) = %q, ожидается %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     Node
+		markdown string
+		html     string
+	}{
+		{
+			name:     "жирная ветка",
+			node:     Bold("feature/snake_speed"),
+			markdown: `*feature/snake\_speed*`,
+			html:     "<b>feature/snake_speed</b>",
+		},
+		{
+			name:     "курсив и подчеркивание",
+			node:     Join(Italic("a_b"), Underline("c")),
+			markdown: `_a\_b___c__`,
+			html:     "<i>a_b</i><u>c</u>",
+		},
+		{
+			name:     "зачеркивание и спойлер",
+			node:     Join(Strikethrough("~"), Spoiler("|")),
+			markdown: `~\~~||\|||`,
+			html:     "<s>~</s><tg-spoiler>|</tg-spoiler>",
+		},
+		{
+			name:     "код с обратной кавычкой и косой чертой",
+			node:     Code("a`b\\c_d"),
+			markdown: "`a\\`b\\\\c_d`",
+			html:     "<code>a`b\\c_d</code>",
+		},
+		{
+			name:     "код с тегами",
+			node:     Code("<tag>&"),
+			markdown: "`<tag>&`",
+			html:     "<code>&lt;tag&gt;&amp;</code>",
+		},
+		{
+			name:     "блок кода с языком",
+			node:     Pre("fmt.Println(`x`) // \\n", "go"),
+			markdown: "```go\nfmt.Println(\\`x\\`) // \\\\n\n```",
+			html:     "<pre><code class=\"language-go\">fmt.Println(`x`) // \\n</code></pre>",
+		},
+		{
+			name:     "блок кода без языка",
+			node:     Pre("<a>", ""),
+			markdown: "```\n<a>\n```",
+			html:     "<pre>&lt;a&gt;</pre>",
+		},
+		{
+			name:     "ссылка со скобкой и косой чертой в адресе",
+			node:     Link("PR #1 (draft)", `https://example.com/a_(b)\c`),
+			markdown: `[PR \#1 \(draft\)](https://example.com/a_(b\)\\c)`,
+			html:     `<a href="https://example.com/a_(b)\c">PR #1 (draft)</a>`,
+		},
+		{
+			name:     "кавычки и амперсанд в адресе",
+			node:     Link("x", `https://example.com/?a=1&b="2"`),
+			markdown: `[x](https://example.com/?a=1&b="2")`,
+			html:     `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;">x</a>`,
+		},
+		{
+			name:     "форматированный текст",
+			node:     Textf("%d%%", 50),
+			markdown: "50%",
+			html:     "50%",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.render(MarkdownV2); got != tt.markdown {
+				t.Errorf("MarkdownV2: %q, ожидается %q", got, tt.markdown)
+			}
+			if got := tt.node.render(HTML); got != tt.html {
+				t.Errorf("HTML: %q, ожидается %q", got, tt.html)
+			}
+		})
+	}
+}
+
+func TestMessageRender(t *testing.T) {
+	message := New().
+		Line(Bold("Ветки:")).
+		Item(Code("feature/snake_speed")).
+		Blank().
+		Append(Plain("v1.0 > v0.9\nготово!"))
+
+	if message.Len() != 5 {
+		t.Errorf("строк %d, ожидается 5", message.Len())
+	}
+
+	wantMarkdown := strings.Join([]string{"*Ветки:*", "• `feature/snake_speed`", "", `v1\.0 \> v0\.9`, `готово\!`}, "\n")
+	if got := message.Render(MarkdownV2); got != wantMarkdown {
+		t.Errorf("MarkdownV2:\n%s\nожидается\n%s", got, wantMarkdown)
+	}
+	wantHTML := strings.Join([]string{"<b>Ветки:</b>", "• <code>feature/snake_speed</code>", "", "v1.0 &gt; v0.9", "готово!"}, "\n")
+	if got := message.Render(HTML); got != wantHTML {
+		t.Errorf("HTML:\n%s\nожидается\n%s", got, wantHTML)
+	}
+}
@@ -0,0 +1,60 @@
+package format
+
+import "strings"
+
+// Message сообщение, состоящее из строк
+type Message struct {
+	lines [][]Node
+}
+
+// New создает пустое сообщение
+func New() *Message {
+	return &Message{}
+}
+
+// Plain создает сообщение из обычного текста
+func Plain(s string) *Message {
+	m := New()
+	for _, line := range strings.Split(s, "\n") {
+		m.Line(Text(line))
+	}
+	return m
+}
+
+// Line добавляет строку из элементов
+func (m *Message) Line(nodes ...Node) *Message {
+	m.lines = append(m.lines, nodes)
+	return m
+}
+
+// Blank добавляет пустую строку
+func (m *Message) Blank() *Message {
+	m.lines = append(m.lines, nil)
+	return m
+}
+
+// Item добавляет элемент маркированного списка
+func (m *Message) Item(nodes ...Node) *Message {
+	m.lines = append(m.lines, append([]Node{Text("• ")}, nodes...))
+	return m
+}
+
+// Append добавляет в конец строки другого сообщения
+func (m *Message) Append(other *Message) *Message {
+	m.lines = append(m.lines, other.lines...)
+	return m
+}
+
+// Len возвращает число строк сообщения
+func (m *Message) Len() int {
+	return len(m.lines)
+}
+
+// Render выводит сообщение в указанной разметке
+func (m *Message) Render(mode Mode) string {
+	lines := make([]string, len(m.lines))
+	for i, line := range m.lines {
+		lines[i] = renderNodes(mode, line)
+	}
+	return strings.Join(lines, "\n")
+}
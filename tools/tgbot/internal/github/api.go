@@ -1,10 +1,13 @@
 package github
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
 
 	"tgbot/pkg/types"
@@ -12,6 +15,8 @@ import (
 
 const (
 	githubAPIBaseURL = "https://api.github.com"
+	// maxPerPage максимальное число элементов, которое GitHub возвращает за один запрос
+	maxPerPage = 100
 )
 
 // API реализация GitHubAPI
@@ -36,7 +41,7 @@ func NewAPI(token, owner, repo string) *API {
 
 // GetBranches получает список веток
 func (g *API) GetBranches() ([]types.Branch, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/branches", githubAPIBaseURL, g.owner, g.repo)
+	url := fmt.Sprintf("%s/repos/%s/%s/branches?per_page=%d", githubAPIBaseURL, g.owner, g.repo, maxPerPage)
 
 	resp, err := g.httpClient.Get(url)
 	if err != nil {
@@ -59,7 +64,7 @@ func (g *API) GetBranches() ([]types.Branch, error) {
 
 // GetPullRequests получает список pull requests
 func (g *API) GetPullRequests() ([]types.PullRequest, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls", githubAPIBaseURL, g.owner, g.repo)
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?per_page=%d", githubAPIBaseURL, g.owner, g.repo, maxPerPage)
 
 	resp, err := g.httpClient.Get(url)
 	if err != nil {
@@ -80,6 +85,98 @@ func (g *API) GetPullRequests() ([]types.PullRequest, error) {
 	return prs, nil
 }
 
+// GetBranch получает информацию о ветке и ее последнем коммите
+func (g *API) GetBranch(name string) (*types.Branch, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/branches/%s", githubAPIBaseURL, g.owner, g.repo, neturl.PathEscape(name))
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ветки: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var branch types.Branch
+	if err := json.NewDecoder(resp.Body).Decode(&branch); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+
+	return &branch, nil
+}
+
+// GetPullRequest получает pull request по номеру
+func (g *API) GetPullRequest(number int) (*types.PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPIBaseURL, g.owner, g.repo, number)
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения pull request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var pr types.PullRequest
+	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+
+	return &pr, nil
+}
+
+// GetReleases получает список релизов, начиная с самого нового
+func (g *API) GetReleases() ([]types.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d", githubAPIBaseURL, g.owner, g.repo, maxPerPage)
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения релизов: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var releases []types.Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+
+	return releases, nil
+}
+
+// GetReleaseByTag получает релиз по тегу
+func (g *API) GetReleaseByTag(tag string) (*types.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", githubAPIBaseURL, g.owner, g.repo, neturl.PathEscape(tag))
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения релиза: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var release types.Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+
+	return &release, nil
+}
+
 // GetLatestRelease получает информацию о последнем релизе
 func (g *API) GetLatestRelease() (*types.Release, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", githubAPIBaseURL, g.owner, g.repo)
@@ -133,3 +230,70 @@ func (api *API) GetLatestPreRelease() (*types.Release, error) {
 
 	return nil, fmt.Errorf("pre-release не найден")
 }
+
+// CompareBranches получает коммиты, которые есть в ветке head и отсутствуют в base
+func (g *API) CompareBranches(base, head string) (*types.Comparison, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s?per_page=%d", githubAPIBaseURL, g.owner, g.repo,
+		neturl.PathEscape(base), neturl.PathEscape(head), maxPerPage)
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сравнения веток: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var comparison types.Comparison
+	if err := json.NewDecoder(resp.Body).Decode(&comparison); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+
+	return &comparison, nil
+}
+
+// versionFile файл с версией приложения в репозитории
+const versionFile = ".github/version.json"
+
+// GetVersion читает версию приложения из .github/version.json в ветке или теге ref
+func (g *API) GetVersion(ref string) (*types.Version, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", githubAPIBaseURL, g.owner, g.repo, versionFile, neturl.QueryEscape(ref))
+
+	resp, err := g.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения файла версии: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	}
+
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+	if file.Encoding != "base64" {
+		return nil, fmt.Errorf("неподдерживаемая кодировка файла версии: %s", file.Encoding)
+	}
+
+	// GitHub разбивает base64 на строки
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования файла версии: %v", err)
+	}
+
+	var version types.Version
+	if err := json.Unmarshal(content, &version); err != nil {
+		return nil, fmt.Errorf("ошибка разбора файла версии: %v", err)
+	}
+
+	return &version, nil
+}
@@ -7,7 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	
+
 	"tgbot/pkg/types"
 )
 
@@ -25,7 +25,7 @@ func NewClient(token, repo string) *Client {
 	// Разделяем repo на owner/name
 	parts := strings.Split(repo, "/")
 	owner := parts[0]
-	
+
 	return &Client{
 		token: token,
 		httpClient: &http.Client{
@@ -39,7 +39,7 @@ func NewClient(token, repo string) *Client {
 
 // TriggerWorkflow запускает пайплайн
 func (c *Client) TriggerWorkflow(workflowFile string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/actions/workflows/%s/dispatches", c.repo, workflowFile)
+	url := fmt.Sprintf("%s/repos/%s/actions/workflows/%s/dispatches", c.baseURL, c.repo, workflowFile)
 
 	payload := map[string]interface{}{
 		"ref": "develop",
@@ -78,7 +78,7 @@ func (c *Client) TriggerWorkflow(workflowFile string) error {
 
 // DeleteBranch удаляет ветку в репозитории
 func (c *Client) DeleteBranch(branchName string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/git/refs/heads/%s", c.repo, branchName)
+	url := fmt.Sprintf("%s/repos/%s/git/refs/heads/%s", c.baseURL, c.repo, branchName)
 
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
@@ -103,68 +103,68 @@ func (c *Client) DeleteBranch(branchName string) error {
 
 // FindPullRequest ищет открытый PR из указанной ветки
 func (c *Client) FindPullRequest(headBranch string) (*types.PullRequest, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls?head=%s:%s&state=open", c.baseURL, c.owner, c.repo, c.owner, headBranch)
-	
+	url := fmt.Sprintf("%s/repos/%s/pulls?head=%s:%s&state=open", c.baseURL, c.repo, c.owner, headBranch)
+
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %v", err)
 	}
-	
+
 	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка отправки запроса: %v", err)
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("неверный статус ответа: %d", resp.StatusCode)
 	}
-	
+
 	var prs []*types.PullRequest
 	if err := json.NewDecoder(resp.Body).Decode(&prs); err != nil {
 		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
 	}
-	
+
 	if len(prs) == 0 {
 		return nil, nil
 	}
-	
+
 	return prs[0], nil
 }
 
 // ClosePullRequest закрывает указанный PR
 func (c *Client) ClosePullRequest(number int) error {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, c.owner, c.repo, number)
-	
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d", c.baseURL, c.repo, number)
+
 	data := map[string]string{
 		"state": "closed",
 	}
-	
+
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("ошибка маршалинга запроса: %v", err)
 	}
-	
+
 	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("ошибка создания запроса: %v", err)
 	}
-	
+
 	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("ошибка отправки запроса: %v", err)
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("неверный статус ответа: %d", resp.StatusCode)
 	}
-	
+
 	return nil
 }
@@ -0,0 +1,136 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// request запрос, полученный тестовым сервером GitHub
+type request struct {
+	method string
+	uri    string
+	body   map[string]interface{}
+	auth   string
+}
+
+// fakeGitHub запускает тестовый сервер, который запоминает запросы и отвечает
+// статусом status и телом body
+func fakeGitHub(t *testing.T, status int, body string) (*Client, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := request{method: r.Method, uri: r.URL.RequestURI(), auth: r.Header.Get("Authorization")}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&received.body); err != nil {
+				t.Errorf("тело запроса: %v", err)
+			}
+		}
+		requests = append(requests, received)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("токен", "octo/app")
+	client.baseURL = server.URL
+	return client, &requests
+}
+
+func TestClientURLs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		call   func(c *Client) error
+		method string
+		uri    string
+	}{
+		{
+			name:   "поиск PR",
+			status: http.StatusOK,
+			body:   `[{"number": 7}]`,
+			call: func(c *Client) error {
+				pr, err := c.FindPullRequest("feature/login")
+				if err == nil && (pr == nil || pr.Number != 7) {
+					t.Errorf("найден PR %+v, ожидается #7", pr)
+				}
+				return err
+			},
+			method: http.MethodGet,
+			uri:    "/repos/octo/app/pulls?head=octo:feature/login&state=open",
+		},
+		{
+			name:   "закрытие PR",
+			status: http.StatusOK,
+			body:   `{}`,
+			call:   func(c *Client) error { return c.ClosePullRequest(7) },
+			method: http.MethodPatch,
+			uri:    "/repos/octo/app/pulls/7",
+		},
+		{
+			name:   "удаление ветки",
+			status: http.StatusNoContent,
+			call:   func(c *Client) error { return c.DeleteBranch("feature/login") },
+			method: http.MethodDelete,
+			uri:    "/repos/octo/app/git/refs/heads/feature/login",
+		},
+		{
+			name:   "запуск пайплайна",
+			status: http.StatusNoContent,
+			call:   func(c *Client) error { return c.TriggerWorkflow("merge.yml") },
+			method: http.MethodPost,
+			uri:    "/repos/octo/app/actions/workflows/merge.yml/dispatches",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := fakeGitHub(t, tt.status, tt.body)
+			if err := tt.call(client); err != nil {
+				t.Fatal(err)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("запросов %d, ожидается 1", len(*requests))
+			}
+			got := (*requests)[0]
+			if got.method != tt.method || got.uri != tt.uri {
+				t.Errorf("запрос %s %s, ожидается %s %s", got.method, got.uri, tt.method, tt.uri)
+			}
+			if got.auth != "token токен" {
+				t.Errorf("Authorization: %q", got.auth)
+			}
+		})
+	}
+}
+
+func TestClosePullRequestBody(t *testing.T) {
+	client, requests := fakeGitHub(t, http.StatusOK, `{}`)
+	if err := client.ClosePullRequest(7); err != nil {
+		t.Fatal(err)
+	}
+	if state := (*requests)[0].body["state"]; state != "closed" {
+		t.Errorf("state = %v, ожидается closed", state)
+	}
+}
+
+func TestClientErrorStatus(t *testing.T) {
+	client, _ := fakeGitHub(t, http.StatusNotFound, `{"message": "Not Found"}`)
+	if _, err := client.FindPullRequest("feature/login"); err == nil {
+		t.Error("FindPullRequest: ожидается ошибка")
+	}
+	if err := client.ClosePullRequest(7); err == nil {
+		t.Error("ClosePullRequest: ожидается ошибка")
+	}
+	if err := client.DeleteBranch("feature/login"); err == nil {
+		t.Error("DeleteBranch: ожидается ошибка")
+	}
+}
+
+func TestFindPullRequestNone(t *testing.T) {
+	client, _ := fakeGitHub(t, http.StatusOK, `[]`)
+	pr, err := client.FindPullRequest("feature/login")
+	if err != nil || pr != nil {
+		t.Errorf("FindPullRequest = %+v, %v; ожидается nil, nil", pr, err)
+	}
+}
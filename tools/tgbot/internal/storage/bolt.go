@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTimeout время ожидания блокировки файла, занятого другим процессом
+const openTimeout = time.Second
+
+// BoltStore хранилище во встроенной базе bbolt: один файл, транзакции
+// переживают сбой процесса
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// Open открывает или создает базу в файле path
+func Open(path string) (*BoltStore, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога %s: %w", dir, err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия хранилища %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// View выполняет fn в транзакции только для чтения
+func (s *BoltStore) View(fn func(tx Tx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+// Update выполняет fn в транзакции записи
+func (s *BoltStore) Update(fn func(tx Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+// Close закрывает базу
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// boltTx транзакция bbolt
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// Get возвращает значение ключа или ErrNotFound
+func (t boltTx) Get(bucket, key string) ([]byte, error) {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	value := b.Get([]byte(key))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Put сохраняет значение, создавая бакет при необходимости
+func (t boltTx) Put(bucket, key string, value []byte) error {
+	b, err := t.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return fmt.Errorf("ошибка создания бакета %s: %w", bucket, err)
+	}
+	if err := b.Put([]byte(key), value); err != nil {
+		return fmt.Errorf("ошибка записи %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Delete удаляет ключ
+func (t boltTx) Delete(bucket, key string) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	if err := b.Delete([]byte(key)); err != nil {
+		return fmt.Errorf("ошибка удаления %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// ForEach обходит ключи бакета по возрастанию
+func (t boltTx) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(key, value []byte) error {
+		return fn(string(key), value)
+	})
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+)
+
+// errReadOnly изменение данных в транзакции только для чтения
+var errReadOnly = errors.New("транзакция только для чтения")
+
+// MemoryStore хранилище в памяти: данные теряются при перезапуске. Подходит для
+// тестов и запуска без файла хранилища
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemory создает пустое хранилище в памяти
+func NewMemory() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+// View выполняет fn в транзакции только для чтения
+func (s *MemoryStore) View(fn func(tx Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(&memoryTx{buckets: s.buckets})
+}
+
+// Update выполняет fn в транзакции записи. Транзакции записи выполняются по одной;
+// при ошибке восстанавливается прежнее содержимое
+func (s *MemoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Значения не изменяются на месте, поэтому для отката достаточно копий бакетов
+	backup := make(map[string]map[string][]byte, len(s.buckets))
+	for name, bucket := range s.buckets {
+		backup[name] = maps.Clone(bucket)
+	}
+	if err := fn(&memoryTx{buckets: s.buckets, writable: true}); err != nil {
+		s.buckets = backup
+		return err
+	}
+	return nil
+}
+
+// Close ничего не делает: хранилищу в памяти нечего освобождать
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// memoryTx транзакция хранилища в памяти
+type memoryTx struct {
+	buckets  map[string]map[string][]byte
+	writable bool
+}
+
+// Get возвращает значение ключа или ErrNotFound
+func (t *memoryTx) Get(bucket, key string) ([]byte, error) {
+	value, ok := t.buckets[bucket][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Put сохраняет копию значения
+func (t *memoryTx) Put(bucket, key string, value []byte) error {
+	if !t.writable {
+		return errReadOnly
+	}
+	b := t.buckets[bucket]
+	if b == nil {
+		b = make(map[string][]byte)
+		t.buckets[bucket] = b
+	}
+	b[key] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete удаляет ключ
+func (t *memoryTx) Delete(bucket, key string) error {
+	if !t.writable {
+		return errReadOnly
+	}
+	delete(t.buckets[bucket], key)
+	return nil
+}
+
+// ForEach обходит ключи бакета по возрастанию
+func (t *memoryTx) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	b := t.buckets[bucket]
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, b[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
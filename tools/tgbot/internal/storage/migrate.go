@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Служебный бакет и ключ, в которых хранится версия схемы
+const (
+	metaBucket       = "meta"
+	schemaVersionKey = "schema_version"
+)
+
+// Migration изменение схемы хранилища. Миграции применяются по возрастанию версий,
+// каждая в своей транзакции вместе с записью новой версии
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(tx Tx) error
+}
+
+// SchemaVersion возвращает версию схемы хранилища; 0 — миграции не применялись
+func SchemaVersion(store Store) (int, error) {
+	var version int
+	err := store.View(func(tx Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// schemaVersion читает версию схемы внутри транзакции
+func schemaVersion(tx Tx) (int, error) {
+	data, err := tx.Get(metaBucket, schemaVersionKey)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("некорректная версия схемы хранилища %q", data)
+	}
+	return version, nil
+}
+
+// Migrate применяет миграции, версия которых больше текущей версии схемы, и
+// возвращает число примененных. Хранилище с версией новее последней миграции
+// не открывается: его создала более новая версия бота
+func Migrate(store Store, migrations []Migration) (int, error) {
+	latest := 0
+	for _, migration := range migrations {
+		if migration.Version <= latest {
+			return 0, fmt.Errorf("миграции должны идти по возрастанию версий: %d после %d", migration.Version, latest)
+		}
+		latest = migration.Version
+	}
+
+	current, err := SchemaVersion(store)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения версии схемы: %w", err)
+	}
+	if current > latest {
+		return 0, fmt.Errorf("версия схемы хранилища %d новее поддерживаемой %d", current, latest)
+	}
+
+	applied := 0
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		err := store.Update(func(tx Tx) error {
+			if err := migration.Apply(tx); err != nil {
+				return err
+			}
+			return tx.Put(metaBucket, schemaVersionKey, []byte(strconv.Itoa(migration.Version)))
+		})
+		if err != nil {
+			return applied, fmt.Errorf("ошибка миграции %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// ImportFile переносит содержимое файла path в ключ key бакета bucket, если ключ
+// еще не задан. Используется в миграциях для переноса состояния из прежних
+// файлов; отсутствующий файл пропускается. Возвращает true, если файл перенесен
+func ImportFile(tx Tx, bucket, key, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	if _, err := tx.Get(bucket, key); err == nil {
+		return false, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+	if err := tx.Put(bucket, key, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
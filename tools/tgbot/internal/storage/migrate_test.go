@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// versionMigration миграция, которая отмечает свое применение в бакете applied
+func versionMigration(version int) Migration {
+	return Migration{
+		Version:     version,
+		Description: "миграция " + strconv.Itoa(version),
+		Apply: func(tx Tx) error {
+			return tx.Put("applied", strconv.Itoa(version), []byte("1"))
+		},
+	}
+}
+
+// appliedVersions возвращает версии миграций, отмеченные в бакете applied
+func appliedVersions(t *testing.T, store Store) []string {
+	t.Helper()
+	var versions []string
+	err := store.View(func(tx Tx) error {
+		return tx.ForEach("applied", func(key string, _ []byte) error {
+			versions = append(versions, key)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return versions
+}
+
+func TestMigrateOrdering(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    bool
+	}{
+		{"по возрастанию", []Migration{versionMigration(1), versionMigration(2), versionMigration(5)}, false},
+		{"пустой список", nil, false},
+		{"не по порядку", []Migration{versionMigration(2), versionMigration(1)}, true},
+		{"повтор версии", []Migration{versionMigration(1), versionMigration(1)}, true},
+		{"нулевая версия", []Migration{versionMigration(0)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemory()
+			_, err := Migrate(store, tt.migrations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидается ошибка: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(appliedVersions(t, store)) > 0 {
+				t.Errorf("при ошибке в списке применены миграции %v", appliedVersions(t, store))
+			}
+		})
+	}
+}
+
+func TestMigrateRerun(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		migrations := []Migration{versionMigration(1), versionMigration(2)}
+		applied, err := Migrate(store, migrations)
+		if err != nil || applied != 2 {
+			t.Fatalf("первый запуск: применено %d, ошибка %v; ожидается 2", applied, err)
+		}
+
+		// Повторный запуск ничего не применяет
+		applied, err = Migrate(store, migrations)
+		if err != nil || applied != 0 {
+			t.Fatalf("повторный запуск: применено %d, ошибка %v; ожидается 0", applied, err)
+		}
+
+		// Новая версия бота добавляет миграцию в конец — применяется только она
+		if err := store.Update(func(tx Tx) error { return tx.Delete("applied", "1") }); err != nil {
+			t.Fatal(err)
+		}
+		applied, err = Migrate(store, append(migrations, versionMigration(3)))
+		if err != nil || applied != 1 {
+			t.Fatalf("новая миграция: применено %d, ошибка %v; ожидается 1", applied, err)
+		}
+		if got, want := appliedVersions(t, store), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("применены миграции %v, ожидаются %v", got, want)
+		}
+		if version, err := SchemaVersion(store); err != nil || version != 3 {
+			t.Errorf("версия схемы %d, %v; ожидается 3", version, err)
+		}
+
+		// Хранилище, созданное более новой версией бота, не открывается
+		if _, err := Migrate(store, migrations); err == nil {
+			t.Error("более новая схема не вернула ошибку")
+		}
+	})
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		failure := errors.New("сбой")
+		migrations := []Migration{
+			versionMigration(1),
+			{
+				Version:     2,
+				Description: "неудачная",
+				Apply: func(tx Tx) error {
+					if err := tx.Put("applied", "2", []byte("1")); err != nil {
+						return err
+					}
+					return failure
+				},
+			},
+			versionMigration(3),
+		}
+
+		applied, err := Migrate(store, migrations)
+		if !errors.Is(err, failure) || applied != 1 {
+			t.Fatalf("применено %d, ошибка %v; ожидается 1 и ошибка миграции", applied, err)
+		}
+		if got, want := appliedVersions(t, store), []string{"1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("применены миграции %v, ожидаются %v", got, want)
+		}
+		if version, _ := SchemaVersion(store); version != 1 {
+			t.Errorf("версия схемы %d, ожидается 1", version)
+		}
+	})
+}
+
+func TestImportFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(legacy, []byte(`{"offset":42}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		existing     []byte
+		path         string
+		wantImported bool
+		want         string
+	}{
+		{"файл переносится", nil, legacy, true, `{"offset":42}`},
+		{"существующее значение не перезаписывается", []byte(`{"offset":7}`), legacy, false, `{"offset":7}`},
+		{"отсутствующий файл пропускается", nil, filepath.Join(dir, "missing.json"), false, ""},
+		{"пустой путь пропускается", nil, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemory()
+			if tt.existing != nil {
+				if err := Put(store, "telegram", "offset", tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var imported bool
+			err := store.Update(func(tx Tx) error {
+				var err error
+				imported, err = ImportFile(tx, "telegram", "offset", tt.path)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported != tt.wantImported {
+				t.Errorf("перенесен: %v, ожидается %v", imported, tt.wantImported)
+			}
+			value, err := Get(store, "telegram", "offset")
+			if tt.want == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("значение %q, %v; ожидается отсутствие", value, err)
+				}
+				return
+			}
+			if string(value) != tt.want {
+				t.Errorf("значение %q, ожидается %q", value, tt.want)
+			}
+		})
+	}
+}
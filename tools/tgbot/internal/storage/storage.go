@@ -0,0 +1,117 @@
+// Package storage хранит состояние бота: смещение обновлений, изменения доступа,
+// секреты второго фактора и другие данные, которые должны переживать перезапуск.
+// Данные разложены по бакетам и ключам; изменения выполняются в транзакциях
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound ключ или бакет не найден
+var ErrNotFound = errors.New("запись не найдена")
+
+// Tx операции с данными внутри транзакции. Значения, полученные через Tx,
+// действуют только до конца транзакции; изменять их нельзя
+type Tx interface {
+	// Get возвращает значение ключа key в бакете bucket или ErrNotFound
+	Get(bucket, key string) ([]byte, error)
+	// Put сохраняет значение, создавая бакет при необходимости
+	Put(bucket, key string, value []byte) error
+	// Delete удаляет ключ; отсутствующий ключ ошибкой не считается
+	Delete(bucket, key string) error
+	// ForEach вызывает fn для каждого ключа бакета в порядке возрастания ключей
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+}
+
+// Store хранилище состояния
+type Store interface {
+	// View выполняет fn в транзакции только для чтения
+	View(fn func(tx Tx) error) error
+	// Update выполняет fn в транзакции: если fn возвращает ошибку, ни одно из
+	// ее изменений не применяется
+	Update(fn func(tx Tx) error) error
+	// Close закрывает хранилище
+	Close() error
+}
+
+// Get возвращает копию значения ключа key в бакете bucket или ErrNotFound
+func Get(store Store, bucket, key string) ([]byte, error) {
+	var value []byte
+	err := store.View(func(tx Tx) error {
+		data, err := tx.Get(bucket, key)
+		if err != nil {
+			return err
+		}
+		value = append([]byte(nil), data...)
+		return nil
+	})
+	return value, err
+}
+
+// Put сохраняет значение ключа key в бакете bucket
+func Put(store Store, bucket, key string, value []byte) error {
+	return store.Update(func(tx Tx) error {
+		return tx.Put(bucket, key, value)
+	})
+}
+
+// Repository хранит значения типа T в бакете в формате JSON
+type Repository[T any] struct {
+	store  Store
+	bucket string
+}
+
+// NewRepository создает репозиторий значений в бакете bucket
+func NewRepository[T any](store Store, bucket string) *Repository[T] {
+	return &Repository[T]{store: store, bucket: bucket}
+}
+
+// Get возвращает значение ключа key или ErrNotFound
+func (r *Repository[T]) Get(key string) (T, error) {
+	var value T
+	err := r.store.View(func(tx Tx) error {
+		data, err := tx.Get(r.bucket, key)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("ошибка декодирования %s/%s: %w", r.bucket, key, err)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Put сохраняет значение ключа key
+func (r *Repository[T]) Put(key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга %s/%s: %w", r.bucket, key, err)
+	}
+	return Put(r.store, r.bucket, key, data)
+}
+
+// Delete удаляет значение ключа key
+func (r *Repository[T]) Delete(key string) error {
+	return r.store.Update(func(tx Tx) error {
+		return tx.Delete(r.bucket, key)
+	})
+}
+
+// All возвращает все значения бакета по ключам
+func (r *Repository[T]) All() (map[string]T, error) {
+	values := make(map[string]T)
+	err := r.store.View(func(tx Tx) error {
+		return tx.ForEach(r.bucket, func(key string, data []byte) error {
+			var value T
+			if err := json.Unmarshal(data, &value); err != nil {
+				return fmt.Errorf("ошибка декодирования %s/%s: %w", r.bucket, key, err)
+			}
+			values[key] = value
+			return nil
+		})
+	})
+	return values, err
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// backends возвращает реализации хранилища, на которых выполняются общие тесты
+func backends(t *testing.T) map[string]func(t *testing.T) Store {
+	t.Helper()
+	return map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			return NewMemory()
+		},
+		"bolt": func(t *testing.T) Store {
+			store, err := Open(filepath.Join(t.TempDir(), "state", "bot.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+}
+
+// forEachBackend выполняет тест на каждой реализации хранилища
+func forEachBackend(t *testing.T, test func(t *testing.T, store Store)) {
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+func TestGetPutDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		if _, err := Get(store, "b", "k"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("отсутствующий бакет: ошибка %v, ожидается ErrNotFound", err)
+		}
+		if err := Put(store, "b", "k", []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Get(store, "b", "other"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("отсутствующий ключ: ошибка %v, ожидается ErrNotFound", err)
+		}
+		value, err := Get(store, "b", "k")
+		if err != nil || string(value) != "1" {
+			t.Fatalf("Get = %q, %v; ожидается \"1\"", value, err)
+		}
+
+		err = store.Update(func(tx Tx) error {
+			if err := tx.Delete("b", "k"); err != nil {
+				return err
+			}
+			return tx.Delete("missing", "k")
+		})
+		if err != nil {
+			t.Fatalf("удаление: %v", err)
+		}
+		if _, err := Get(store, "b", "k"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("после удаления: ошибка %v, ожидается ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdateRollback(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		if err := Put(store, "b", "k", []byte("старое")); err != nil {
+			t.Fatal(err)
+		}
+
+		failure := errors.New("сбой")
+		err := store.Update(func(tx Tx) error {
+			if err := tx.Put("b", "k", []byte("новое")); err != nil {
+				return err
+			}
+			if err := tx.Put("new", "k", []byte("1")); err != nil {
+				return err
+			}
+			if err := tx.Delete("b", "k"); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Update вернул %v, ожидается ошибка fn", err)
+		}
+
+		value, err := Get(store, "b", "k")
+		if err != nil || string(value) != "старое" {
+			t.Errorf("после отката: %q, %v; ожидается прежнее значение", value, err)
+		}
+		if _, err := Get(store, "new", "k"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("бакет из отмененной транзакции остался: %v", err)
+		}
+	})
+}
+
+func TestViewIsReadOnly(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		err := store.View(func(tx Tx) error {
+			return tx.Put("b", "k", []byte("1"))
+		})
+		if err == nil {
+			t.Fatal("запись в транзакции только для чтения не вернула ошибку")
+		}
+		if _, err := Get(store, "b", "k"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("значение записано в транзакции только для чтения: %v", err)
+		}
+	})
+}
+
+func TestMemoryRollbackKeepsBucketsIndependent(t *testing.T) {
+	store := NewMemory()
+	if err := Put(store, "b", "a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Update(func(tx Tx) error {
+		tx.Put("b", "b", []byte("2"))
+		return errors.New("сбой")
+	})
+	// Успешная транзакция после отката не должна менять сохраненную копию бакета
+	if err := Put(store, "b", "c", []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	store.View(func(tx Tx) error {
+		return tx.ForEach("b", func(key string, _ []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if want := []string{"a", "c"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ключи %v, ожидаются %v", keys, want)
+	}
+}
+
+type record struct {
+	Name  string  `json:"name"`
+	Count int     `json:"count"`
+	IDs   []int64 `json:"ids"`
+}
+
+func TestRepositoryRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		repo := NewRepository[record](store, "records")
+		if _, err := repo.Get("a"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("пустой репозиторий: ошибка %v, ожидается ErrNotFound", err)
+		}
+
+		want := map[string]record{
+			"a": {Name: "первая", Count: 1, IDs: []int64{-1001234567890}},
+			"b": {Name: "вторая", Count: 2},
+		}
+		for key, value := range want {
+			if err := repo.Put(key, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := repo.Get("a")
+		if err != nil || !reflect.DeepEqual(got, want["a"]) {
+			t.Errorf("Get(a) = %+v, %v; ожидается %+v", got, err, want["a"])
+		}
+		all, err := repo.All()
+		if err != nil || !reflect.DeepEqual(all, want) {
+			t.Errorf("All() = %+v, %v; ожидается %+v", all, err, want)
+		}
+
+		if err := repo.Delete("a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Get("a"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("после удаления: ошибка %v, ожидается ErrNotFound", err)
+		}
+	})
+}
+
+func TestRepositoryDecodeError(t *testing.T) {
+	store := NewMemory()
+	if err := Put(store, "records", "a", []byte("не json")); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository[record](store, "records")
+	if _, err := repo.Get("a"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("поврежденное значение: ошибка %v, ожидается ошибка декодирования", err)
+	}
+}
+
+func TestBoltPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRepository[record](store, "records").Put("a", record{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("права файла %o, ожидается 600", perm)
+	}
+
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	got, err := NewRepository[record](store, "records").Get("a")
+	if err != nil || got.Name != "a" {
+		t.Errorf("после повторного открытия: %+v, %v", got, err)
+	}
+}
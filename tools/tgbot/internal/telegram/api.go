@@ -1,20 +1,18 @@
 package telegram
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"tgbot/pkg/types"
 )
 
 const (
-	telegramAPIBaseURL = "https://api.telegram.org/bot%s"
-	pollTimeout        = 30
+	telegramAPIBaseURL     = "https://api.telegram.org/bot%s"
+	pollTimeout            = 30
+	defaultShutdownTimeout = time.Second * 30
 )
 
 // API реализация BotAPI для Telegram
@@ -22,29 +20,104 @@ type API struct {
 	token      string
 	httpClient *http.Client
 	baseURL    string
-	offset     int64
+	// offsets принятые и обработанные обновления long polling
+	offsets *offsetTracker
+	// savedOffset последнее сохраненное смещение
+	savedOffset     int64
+	shutdownTimeout time.Duration
+	workers         int
+	queueSize       int
+	offsetStore     OffsetStore
+	skipBacklog     bool
+	limiter         *RateLimiter
+	parseMode       string
+	// sleep ожидание перед повтором запроса; подменяется в тестах
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option настраивает экземпляр API
+type Option func(*API)
+
+// WithShutdownTimeout задает время ожидания завершения обработчиков при остановке
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(t *API) {
+		if timeout > 0 {
+			t.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithConcurrency задает число параллельных обработчиков и размер очереди обновлений.
+// Когда очередь заполнена, прием новых обновлений приостанавливается
+func WithConcurrency(workers, queueSize int) Option {
+	return func(t *API) {
+		t.workers = workers
+		t.queueSize = queueSize
+	}
+}
+
+// WithOffsetStore задает хранилище, в котором смещение getUpdates сохраняется между перезапусками
+func WithOffsetStore(store OffsetStore) Option {
+	return func(t *API) {
+		t.offsetStore = store
+	}
+}
+
+// WithSkipBacklog включает пропуск обновлений, накопившихся, пока бот был остановлен
+func WithSkipBacklog(skip bool) Option {
+	return func(t *API) {
+		t.skipBacklog = skip
+	}
+}
+
+// WithRateLimiter задает планировщик исходящих сообщений; nil отключает ограничение частоты
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(t *API) {
+		t.limiter = limiter
+	}
+}
+
+// WithParseMode задает разметку текстов сообщений (Markdown, MarkdownV2 или HTML)
+func WithParseMode(parseMode string) Option {
+	return func(t *API) {
+		t.parseMode = parseMode
+	}
 }
 
 // NewAPI создает новый экземпляр Telegram API
-func NewAPI(token string) *API {
-	return &API{
+func NewAPI(token string, opts ...Option) *API {
+	t := &API{
 		token: token,
 		httpClient: &http.Client{
 			Timeout: time.Second * 60,
 		},
-		baseURL: fmt.Sprintf(telegramAPIBaseURL, token),
-		offset:  0,
-	}
+		baseURL:         fmt.Sprintf(telegramAPIBaseURL, token),
+		offsets:         newOffsetTracker(0),
+		savedOffset:     -1,
+		shutdownTimeout: defaultShutdownTimeout,
+		limiter:         NewRateLimiter(),
+		parseMode:       ParseModeMarkdown,
+		sleep:           sleep,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // SendMessage отправляет сообщение в указанный чат
-func (t *API) SendMessage(chatID int64, text string, keyboard [][]types.InlineKeyboardButton) error {
-	url := fmt.Sprintf("%s/sendMessage", t.baseURL)
+func (t *API) SendMessage(ctx context.Context, chatID int64, text string, keyboard [][]types.InlineKeyboardButton) error {
+	_, err := t.SendMessageID(ctx, chatID, text, keyboard)
+	return err
+}
 
+// SendMessageID отправляет сообщение и возвращает его идентификатор, чтобы сообщение
+// можно было потом изменить через EditMessageText
+func (t *API) SendMessageID(ctx context.Context, chatID int64, text string, keyboard [][]types.InlineKeyboardButton) (int, error) {
 	message := types.SendMessageRequest{
 		ChatID:    chatID,
 		Text:      text,
-		ParseMode: "Markdown",
+		ParseMode: t.parseMode,
 	}
 
 	if keyboard != nil {
@@ -53,137 +126,274 @@ func (t *API) SendMessage(chatID int64, text string, keyboard [][]types.InlineKe
 		}
 	}
 
-	body, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("ошибка маршалинга запроса: %w", err)
+	var sent struct {
+		MessageID int `json:"message_id"`
 	}
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("ошибка отправки запроса: %w", err)
+	if err := t.callChat(ctx, chatID, "sendMessage", message, &sent); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
+	return sent.MessageID, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+// AnswerCallbackQuery отвечает на callback-запрос
+func (t *API) AnswerCallbackQuery(ctx context.Context, callbackQueryID string, text string) error {
+	message := types.AnswerCallbackQueryRequest{
+		CallbackQueryID: callbackQueryID,
+		Text:            text,
 	}
 
-	var response types.Response
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("ошибка декодирования ответа: %w", err)
-	}
+	return t.call(ctx, "answerCallbackQuery", message, nil)
+}
 
-	if !response.Ok {
-		return fmt.Errorf("ошибка API: %s", response.Description)
-	}
+// HandleUpdates обрабатывает обновления от Telegram до отмены ctx.
+// После отмены опрос прекращается, а метод ждет завершения текущих обработчиков
+// не дольше shutdownTimeout.
+func (t *API) HandleUpdates(ctx context.Context, handler types.UpdateHandler) error {
+	fmt.Printf("Запуск обработки обновлений от Telegram API...\n")
 
-	return nil
+	// getUpdates не работает, пока у бота зарегистрирован webhook
+	if err := t.DeleteWebhook(ctx); err != nil {
+		fmt.Printf("Ошибка удаления webhook: %v\n", err)
+	}
+
+	t.restoreOffset(ctx)
+
+	// Обработчики не должны прерываться сразу после сигнала остановки,
+	// поэтому их контекст отменяется только по истечении shutdownTimeout
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	// Обновление подтверждается только после того, как обработчик завершится
+	dispatcher := NewDispatcher(func(ctx context.Context, update types.Update) {
+		defer t.offsets.Finish(update.UpdateID)
+		handler(ctx, update)
+	}, t.workers, t.queueSize)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t.pollUpdates(ctx, handlerCtx, dispatcher)
+	}()
+
+	<-ctx.Done()
+	dispatcher.Stop()
+	fmt.Printf("Остановка опроса, ожидание завершения обработчиков...\n")
+
+	finished := make(chan struct{})
+	go func() {
+		<-done
+		dispatcher.Wait()
+		close(finished)
+	}()
+
+	timer := time.NewTimer(t.shutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-finished:
+		t.saveOffset()
+		return nil
+	case <-timer.C:
+		cancelHandlers()
+		// Незавершенные обновления не подтверждаются и будут получены после перезапуска
+		<-done
+		t.saveOffset()
+		return fmt.Errorf("обработчики не завершились за %s", t.shutdownTimeout)
+	}
 }
 
-// AnswerCallbackQuery отвечает на callback-запрос
-func (t *API) AnswerCallbackQuery(callbackQueryID string, text string) error {
-	url := fmt.Sprintf("%s/answerCallbackQuery", t.baseURL)
+// pollUpdates получает обновления через long polling и передает их диспетчеру, пока не отменен ctx.
+// getUpdates запрашивается со смещения первого необработанного обновления, поэтому Telegram
+// хранит обновления до завершения их обработки; уже принятые обновления пропускаются.
+// Обновление, которое обрабатывается дольше maxCommitHold, перестает удерживать смещение,
+// чтобы зависший обработчик не останавливал прием обновлений
+func (t *API) pollUpdates(ctx, handlerCtx context.Context, dispatcher *Dispatcher) {
+	for ctx.Err() == nil {
+		for _, updateID := range t.offsets.ReleaseStuck() {
+			fmt.Printf("Обновление %d обрабатывается дольше %s, смещение подтверждается без него\n", updateID, t.offsets.hold)
+		}
+		progress := t.offsets.Progress()
+		updates, err := t.getUpdates(ctx, t.offsets.Committed(), pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("Ошибка получения обновлений: %v\n", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second * 5):
+			}
+			continue
+		}
 
-	message := types.AnswerCallbackQueryRequest{
-		CallbackQueryID: callbackQueryID,
-		Text:            text,
+		accepted := 0
+		for _, update := range updates {
+			if update.UpdateID < t.offsets.Next() {
+				continue
+			}
+			// Не принятые диспетчером обновления не подтверждаются и будут получены повторно
+			if ctx.Err() != nil {
+				return
+			}
+			accepted++
+			converted, ok := convertUpdate(update)
+			if !ok {
+				t.offsets.Skip(update.UpdateID)
+				continue
+			}
+			t.offsets.Start(update.UpdateID)
+			if err := dispatcher.Submit(handlerCtx, converted); err != nil {
+				return
+			}
+		}
+		t.saveOffset()
+
+		// Telegram сразу возвращает еще обрабатываемые обновления: чтобы не опрашивать
+		// его впустую, ждем завершения обработки, но не дольше секунды, чтобы новые
+		// обновления других чатов не задерживались
+		if len(updates) > 0 && accepted == 0 {
+			select {
+			case <-ctx.Done():
+			case <-progress:
+			case <-time.After(time.Second):
+			}
+		}
 	}
+}
 
-	body, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("ошибка маршалинга запроса: %w", err)
+// restoreOffset загружает сохраненное смещение и при необходимости пропускает накопившиеся обновления
+func (t *API) restoreOffset(ctx context.Context) {
+	if t.offsetStore != nil {
+		offset, err := t.offsetStore.LoadOffset()
+		if err != nil {
+			fmt.Printf("Ошибка загрузки смещения обновлений: %v\n", err)
+		} else if offset > t.offsets.Next() {
+			t.offsets.Reset(offset)
+			t.savedOffset = offset
+			fmt.Printf("Обработка обновлений продолжится со смещения %d\n", offset)
+		}
 	}
 
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("ошибка отправки запроса: %w", err)
+	if !t.skipBacklog {
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
+	// С offset=-1 Telegram возвращает только последнее обновление; все более ранние
+	// будут подтверждены следующим запросом getUpdates
+	updates, err := t.getUpdates(ctx, -1, 0)
+	if err != nil {
+		fmt.Printf("Ошибка пропуска накопившихся обновлений: %v\n", err)
+		return
+	}
+	if len(updates) == 0 {
+		return
 	}
 
-	var response types.Response
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("ошибка декодирования ответа: %w", err)
+	last := updates[len(updates)-1].UpdateID
+	if next := t.offsets.Next(); last >= next {
+		fmt.Printf("Пропущены накопившиеся обновления с %d по %d\n", next, last)
+		t.offsets.Reset(last + 1)
+		t.saveOffset()
 	}
+}
 
-	if !response.Ok {
-		return fmt.Errorf("ошибка API: %s", response.Description)
+// saveOffset сохраняет смещение первого необработанного обновления, если задано
+// хранилище и смещение изменилось с последнего сохранения
+func (t *API) saveOffset() {
+	offset := t.offsets.Committed()
+	if t.offsetStore == nil || offset == t.savedOffset {
+		return
+	}
+	if err := t.offsetStore.SaveOffset(offset); err != nil {
+		fmt.Printf("Ошибка сохранения смещения обновлений: %v\n", err)
+		return
 	}
+	t.savedOffset = offset
+}
 
-	return nil
+// convertUpdate преобразует обновление Telegram во внутренний формат
+func convertUpdate(update telegramUpdate) (types.Update, bool) {
+	// Обрабатываем сообщения
+	if update.Message != nil {
+		return types.Update{
+			UpdateID: update.UpdateID,
+			Message:  convertMessage(update.Message),
+		}, true
+	}
+
+	// Обрабатываем callback-запросы
+	if update.CallbackQuery != nil {
+		return types.Update{
+			UpdateID: update.UpdateID,
+			CallbackQuery: &types.CallbackQuery{
+				ID:        update.CallbackQuery.ID,
+				From:      update.CallbackQuery.From,
+				UserID:    update.CallbackQuery.From.ID,
+				ChatID:    update.CallbackQuery.Message.Chat.ID,
+				MessageID: update.CallbackQuery.Message.MessageID,
+				Message: &types.Message{
+					ChatID: update.CallbackQuery.Message.Chat.ID,
+					UserID: update.CallbackQuery.From.ID,
+					Text:   update.CallbackQuery.Message.Text,
+				},
+				Data: update.CallbackQuery.Data,
+			},
+		}, true
+	}
+
+	return types.Update{}, false
 }
 
-// HandleUpdates обрабатывает обновления от Telegram
-func (t *API) HandleUpdates(handler func(update types.Update)) error {
-	fmt.Printf("Запуск обработки обновлений от Telegram API...\n")
+// convertMessage преобразует сообщение Telegram во внутренний формат
+func convertMessage(message *telegramMessage) *types.Message {
+	converted := &types.Message{
+		MessageID: message.MessageID,
+		ChatID:    message.Chat.ID,
+		Text:      message.Text,
+		Chat:      &types.Chat{ID: message.Chat.ID, Type: message.Chat.Type, Title: message.Chat.Title},
+	}
+	if message.From != nil {
+		converted.From = message.From
+		converted.UserID = message.From.ID
+	}
 
-	for {
-		updates, err := t.getUpdates()
-		if err != nil {
-			fmt.Printf("Ошибка получения обновлений: %v\n", err)
-			time.Sleep(time.Second * 5)
-			continue
-		}
+	// Новые версии Bot API сообщают автора пересланного сообщения в forward_origin
+	converted.Forwarded = message.ForwardDate != 0 || message.ForwardOrigin != nil
+	converted.ForwardFrom = message.ForwardFrom
+	if converted.ForwardFrom == nil && message.ForwardOrigin != nil {
+		converted.ForwardFrom = message.ForwardOrigin.SenderUser
+	}
 
-		for _, update := range updates {
-			if update.UpdateID >= t.offset {
-				t.offset = update.UpdateID + 1
-
-				// Обрабатываем сообщения
-				if update.Message != nil {
-					handler(types.Update{
-						Message: &types.Message{
-							ChatID: update.Message.Chat.ID,
-							UserID: update.Message.From.ID,
-							Text:   update.Message.Text,
-						},
-					})
-				}
-
-				// Обрабатываем callback-запросы
-				if update.CallbackQuery != nil {
-					handler(types.Update{
-						CallbackQuery: &types.CallbackQuery{
-							ID:        update.CallbackQuery.ID,
-							UserID:    update.CallbackQuery.From.ID,
-							ChatID:    update.CallbackQuery.Message.Chat.ID,
-							MessageID: update.CallbackQuery.Message.MessageID,
-							Message: &types.Message{
-								ChatID: update.CallbackQuery.Message.Chat.ID,
-								UserID: update.CallbackQuery.From.ID,
-								Text:   update.CallbackQuery.Message.Text,
-							},
-							Data: update.CallbackQuery.Data,
-						},
-					})
-				}
-			}
-		}
+	if message.ReplyToMessage != nil {
+		converted.ReplyTo = convertMessage(message.ReplyToMessage)
 	}
+	return converted
+}
+
+type telegramMessage struct {
+	MessageID int `json:"message_id"`
+	Chat      struct {
+		ID    int64  `json:"id"`
+		Type  string `json:"type"`
+		Title string `json:"title"`
+	} `json:"chat"`
+	From          *types.User `json:"from"`
+	Text          string      `json:"text"`
+	ForwardDate   int64       `json:"forward_date"`
+	ForwardFrom   *types.User `json:"forward_from"`
+	ForwardOrigin *struct {
+		Type       string      `json:"type"`
+		SenderUser *types.User `json:"sender_user"`
+	} `json:"forward_origin"`
+	ReplyToMessage *telegramMessage `json:"reply_to_message"`
 }
 
 type telegramUpdate struct {
-	UpdateID int64 `json:"update_id"`
-	Message  *struct {
-		Chat struct {
-			ID int64 `json:"id"`
-		} `json:"chat"`
-		From struct {
-			ID int64 `json:"id"`
-		} `json:"from"`
-		Text      string `json:"text"`
-		MessageID int    `json:"message_id"`
-	} `json:"message"`
+	UpdateID      int64            `json:"update_id"`
+	Message       *telegramMessage `json:"message"`
 	CallbackQuery *struct {
-		ID   string `json:"id"`
-		From struct {
-			ID int64 `json:"id"`
-		} `json:"from"`
+		ID      string     `json:"id"`
+		From    types.User `json:"from"`
 		Message struct {
 			Chat struct {
 				ID int64 `json:"id"`
@@ -195,46 +405,28 @@ type telegramUpdate struct {
 	} `json:"callback_query"`
 }
 
-func (t *API) getUpdates() ([]telegramUpdate, error) {
-	url := fmt.Sprintf("%s/getUpdates?offset=%d&timeout=%d", t.baseURL, t.offset, pollTimeout)
-
-	resp, err := t.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка получения обновлений: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения ответа: %v", err)
-	}
-
-	var result struct {
-		OK          bool             `json:"ok"`
-		Result      []telegramUpdate `json:"result"`
-		Description string           `json:"description"`
+func (t *API) getUpdates(ctx context.Context, offset int64, timeout int) ([]telegramUpdate, error) {
+	request := getUpdatesRequest{
+		Offset:         offset,
+		Timeout:        timeout,
+		AllowedUpdates: []string{"message", "callback_query"},
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	var updates []telegramUpdate
+	if err := t.call(ctx, "getUpdates", request, &updates); err != nil {
+		return nil, fmt.Errorf("ошибка получения обновлений: %w", err)
 	}
 
-	if !result.OK {
-		return nil, fmt.Errorf("неуспешный ответ от API: %s", result.Description)
-	}
-
-	return result.Result, nil
+	return updates, nil
 }
 
 // EditMessageText редактирует текст сообщения
-func (t *API) EditMessageText(chatID int64, messageID int, text string, keyboard [][]types.InlineKeyboardButton) error {
-	url := fmt.Sprintf("%s/editMessageText", t.baseURL)
-
+func (t *API) EditMessageText(ctx context.Context, chatID int64, messageID int, text string, keyboard [][]types.InlineKeyboardButton) error {
 	message := types.EditMessageTextRequest{
 		ChatID:    chatID,
 		MessageID: messageID,
 		Text:      text,
-		ParseMode: "Markdown",
+		ParseMode: t.parseMode,
 	}
 
 	if keyboard != nil {
@@ -247,98 +439,36 @@ func (t *API) EditMessageText(chatID int64, messageID int, text string, keyboard
 		}
 	}
 
-	body, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("ошибка маршалинга запроса: %w", err)
-	}
-
-	// Логируем тело запроса для отладки
-	fmt.Printf("Отправляем запрос: %s\n", string(body))
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("ошибка отправки запроса: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
-	}
-
-	var response types.Response
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("ошибка декодирования ответа: %w", err)
-	}
-
-	if !response.Ok {
-		return fmt.Errorf("ошибка API: %s", response.Description)
-	}
-
-	return nil
+	return t.callChat(ctx, chatID, "editMessageText", message, nil)
 }
 
 // ShowAlert показывает alert dialog в Telegram
-func (t *API) ShowAlert(callbackQueryID string, text string) error {
-	url := fmt.Sprintf("%s/answerCallbackQuery", t.baseURL)
-
+func (t *API) ShowAlert(ctx context.Context, callbackQueryID string, text string) error {
 	message := types.AnswerCallbackQueryRequest{
 		CallbackQueryID: callbackQueryID,
 		Text:            text,
 		ShowAlert:       true, // Включаем режим alert dialog
 	}
 
-	body, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("ошибка маршалинга запроса: %w", err)
-	}
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("ошибка отправки запроса: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("неуспешный статус ответа: %d, тело: %s", resp.StatusCode, string(body))
-	}
-
-	var response types.Response
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("ошибка декодирования ответа: %w", err)
-	}
-
-	if !response.Ok {
-		return fmt.Errorf("ошибка API: %s", response.Description)
-	}
-
-	return nil
+	return t.call(ctx, "answerCallbackQuery", message, nil)
 }
 
 // DeleteMessage удаляет сообщение в чате
-func (t *API) DeleteMessage(chatID int64, messageID int) error {
-	url := fmt.Sprintf("%s/bot%s/deleteMessage", t.baseURL, t.token)
-	
+func (t *API) DeleteMessage(ctx context.Context, chatID int64, messageID int) error {
 	req := DeleteMessageRequest{
 		ChatID:    chatID,
 		MessageID: messageID,
 	}
-	
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("ошибка маршалинга запроса: %v", err)
-	}
-	
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("ошибка отправки запроса: %v", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("неверный статус ответа: %d", resp.StatusCode)
+
+	return t.call(ctx, "deleteMessage", req, nil)
+}
+
+// GetMe возвращает информацию о боте, в том числе его имя пользователя
+func (t *API) GetMe(ctx context.Context) (*types.User, error) {
+	var me types.User
+	if err := t.call(ctx, "getMe", struct{}{}, &me); err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о боте: %w", err)
 	}
-	
-	return nil
+
+	return &me, nil
 }
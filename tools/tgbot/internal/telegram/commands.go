@@ -0,0 +1,114 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"tgbot/pkg/types"
+)
+
+// Типы областей видимости команд (BotCommandScope)
+const (
+	ScopeTypeDefault               = "default"
+	ScopeTypeAllPrivateChats       = "all_private_chats"
+	ScopeTypeAllGroupChats         = "all_group_chats"
+	ScopeTypeAllChatAdministrators = "all_chat_administrators"
+	ScopeTypeChat                  = "chat"
+	ScopeTypeChatAdministrators    = "chat_administrators"
+	ScopeTypeChatMember            = "chat_member"
+)
+
+// BotCommandScope область видимости списка команд. Telegram показывает пользователю
+// список из самой узкой подходящей области: участник чата, администраторы чата, чат,
+// все администраторы, все группы или личные чаты, область по умолчанию
+type BotCommandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+	UserID int64  `json:"user_id,omitempty"`
+}
+
+// ScopeDefault область по умолчанию
+func ScopeDefault() BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeDefault}
+}
+
+// ScopeAllPrivateChats все личные чаты
+func ScopeAllPrivateChats() BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeAllPrivateChats}
+}
+
+// ScopeAllGroupChats все группы и супергруппы
+func ScopeAllGroupChats() BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeAllGroupChats}
+}
+
+// ScopeChat конкретный чат
+func ScopeChat(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeChat, ChatID: chatID}
+}
+
+// ScopeChatAdministrators администраторы конкретной группы
+func ScopeChatAdministrators(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeChatAdministrators, ChatID: chatID}
+}
+
+// ScopeChatMember конкретный участник группы
+func ScopeChatMember(chatID, userID int64) BotCommandScope {
+	return BotCommandScope{Type: ScopeTypeChatMember, ChatID: chatID, UserID: userID}
+}
+
+// String возвращает описание области для логов
+func (s BotCommandScope) String() string {
+	switch {
+	case s.UserID != 0:
+		return fmt.Sprintf("%s(%d, %d)", s.Type, s.ChatID, s.UserID)
+	case s.ChatID != 0:
+		return fmt.Sprintf("%s(%d)", s.Type, s.ChatID)
+	default:
+		return s.Type
+	}
+}
+
+type setMyCommandsRequest struct {
+	Commands     []types.BotCommand `json:"commands"`
+	Scope        BotCommandScope    `json:"scope"`
+	LanguageCode string             `json:"language_code,omitempty"`
+}
+
+type deleteMyCommandsRequest struct {
+	Scope        BotCommandScope `json:"scope"`
+	LanguageCode string          `json:"language_code,omitempty"`
+}
+
+// SetMyCommands задает список команд для области scope и языка languageCode
+// (пустой код — для пользователей, для языка которых нет отдельного списка).
+// Пустой список удаляет команды области
+func (t *API) SetMyCommands(ctx context.Context, commands []types.BotCommand, scope BotCommandScope, languageCode string) error {
+	if len(commands) == 0 {
+		return t.DeleteMyCommands(ctx, scope, languageCode)
+	}
+
+	request := setMyCommandsRequest{
+		Commands:     commands,
+		Scope:        scope,
+		LanguageCode: languageCode,
+	}
+	if err := t.call(ctx, "setMyCommands", request, nil); err != nil {
+		return fmt.Errorf("ошибка установки команд для %s: %w", scope, err)
+	}
+
+	return nil
+}
+
+// DeleteMyCommands удаляет список команд области scope и языка languageCode
+func (t *API) DeleteMyCommands(ctx context.Context, scope BotCommandScope, languageCode string) error {
+	request := deleteMyCommandsRequest{
+		Scope:        scope,
+		LanguageCode: languageCode,
+	}
+	if err := t.call(ctx, "deleteMyCommands", request, nil); err != nil {
+		return fmt.Errorf("ошибка удаления команд для %s: %w", scope, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"tgbot/pkg/types"
+)
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 100
+)
+
+// Ошибки постановки обновления в очередь
+var (
+	// ErrDispatcherStopped возвращается при попытке поставить обновление в очередь остановленного диспетчера
+	ErrDispatcherStopped = errors.New("диспетчер обновлений остановлен")
+	// ErrQueueFull возвращается TrySubmit, если в очереди нет места
+	ErrQueueFull = errors.New("очередь обновлений заполнена")
+)
+
+// Dispatcher распределяет обновления между обработчиками: обновления разных чатов
+// обрабатываются параллельно, а обновления одного чата — строго в порядке поступления
+type Dispatcher struct {
+	handler types.UpdateHandler
+	// workers ограничивает число одновременно работающих обработчиков
+	workers chan struct{}
+	// slots ограничивает число принятых, но еще не обработанных обновлений
+	slots chan struct{}
+
+	mu      sync.Mutex
+	chats   map[int64][]dispatchJob
+	stopped bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type dispatchJob struct {
+	ctx    context.Context
+	update types.Update
+}
+
+// NewDispatcher создает диспетчер с ограничением на число параллельных обработчиков
+// и размер очереди. Нулевые значения заменяются значениями по умолчанию
+func NewDispatcher(handler types.UpdateHandler, workers, queueSize int) *Dispatcher {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	return &Dispatcher{
+		handler: handler,
+		workers: make(chan struct{}, workers),
+		slots:   make(chan struct{}, queueSize),
+		chats:   make(map[int64][]dispatchJob),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Submit ставит обновление в очередь чата. Если очередь заполнена, вызов блокируется,
+// пока не освободится место, не будет отменен ctx или не остановится диспетчер.
+// Обработчик получит переданный ctx
+func (d *Dispatcher) Submit(ctx context.Context, update types.Update) error {
+	select {
+	case d.slots <- struct{}{}:
+	case <-d.stop:
+		return ErrDispatcherStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return d.enqueue(ctx, update)
+}
+
+// TrySubmit ставит обновление в очередь чата, не дожидаясь места: если очередь
+// заполнена, сразу возвращается ErrQueueFull. Обработчик получит переданный ctx
+func (d *Dispatcher) TrySubmit(ctx context.Context, update types.Update) error {
+	select {
+	case d.slots <- struct{}{}:
+	case <-d.stop:
+		return ErrDispatcherStopped
+	default:
+		return ErrQueueFull
+	}
+	return d.enqueue(ctx, update)
+}
+
+// enqueue добавляет обновление в очередь чата; место в очереди (slots) уже занято
+func (d *Dispatcher) enqueue(ctx context.Context, update types.Update) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		<-d.slots
+		return ErrDispatcherStopped
+	}
+
+	d.wg.Add(1)
+	key := chatKey(update)
+	queue, active := d.chats[key]
+	d.chats[key] = append(queue, dispatchJob{ctx: ctx, update: update})
+	if !active {
+		go d.runChat(key)
+	}
+
+	return nil
+}
+
+// Stop прекращает прием новых обновлений. Уже принятые обновления будут обработаны
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.stopped {
+		d.stopped = true
+		close(d.stop)
+	}
+}
+
+// Wait ждет обработки всех принятых обновлений
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// runChat последовательно обрабатывает очередь одного чата, пока она не опустеет
+func (d *Dispatcher) runChat(key int64) {
+	for {
+		d.mu.Lock()
+		queue := d.chats[key]
+		if len(queue) == 0 {
+			delete(d.chats, key)
+			d.mu.Unlock()
+			return
+		}
+		job := queue[0]
+		d.chats[key] = queue[1:]
+		d.mu.Unlock()
+
+		d.workers <- struct{}{}
+		d.handler(job.ctx, job.update)
+		<-d.workers
+
+		<-d.slots
+		d.wg.Done()
+	}
+}
+
+// chatKey возвращает идентификатор чата, в пределах которого сохраняется порядок обработки
+func chatKey(update types.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.ChatID
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.ChatID
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"tgbot/pkg/types"
+)
+
+// testTimeout время, за которое заблокированный обработчик должен дождаться события
+const testTimeout = 5 * time.Second
+
+// chatUpdate возвращает обновление с сообщением в чате chatID
+func chatUpdate(id, chatID int64) types.Update {
+	return types.Update{UpdateID: id, Message: &types.Message{ChatID: chatID}}
+}
+
+// waitFor ждет закрытия канала или проваливает тест по таймауту
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(testTimeout):
+		t.Fatalf("не дождались: %s", what)
+	}
+}
+
+func TestDispatcherSameChatInOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		order   []int64
+		running int
+		overlap bool
+	)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	dispatcher := NewDispatcher(func(_ context.Context, update types.Update) {
+		mu.Lock()
+		running++
+		overlap = overlap || running > 1
+		order = append(order, update.UpdateID)
+		mu.Unlock()
+
+		// Первое обновление блокируется, пока тест не поставит в очередь остальные
+		if update.UpdateID == 1 {
+			close(started)
+			<-release
+		}
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}, 4, 10)
+
+	for id := int64(1); id <= 5; id++ {
+		if err := dispatcher.Submit(context.Background(), chatUpdate(id, 100)); err != nil {
+			t.Fatal(err)
+		}
+		if id == 1 {
+			waitFor(t, started, "начало обработки первого обновления")
+		}
+	}
+
+	// Пока первое обновление обрабатывается, остальные обновления чата ждут
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	if len(order) != 1 {
+		t.Errorf("во время обработки первого обновления начаты %v", order)
+	}
+	mu.Unlock()
+
+	close(release)
+	dispatcher.Stop()
+	dispatcher.Wait()
+
+	if want := []int64{1, 2, 3, 4, 5}; !reflect.DeepEqual(order, want) {
+		t.Errorf("порядок обработки %v, ожидается %v", order, want)
+	}
+	if overlap {
+		t.Error("обновления одного чата обрабатывались одновременно")
+	}
+}
+
+func TestDispatcherChatsInParallel(t *testing.T) {
+	// Обработчик чата 1 блокируется, пока не будет обработано обновление чата 2:
+	// при последовательной обработке тест завершится по таймауту
+	chat2Done := make(chan struct{})
+	failed := make(chan struct{})
+	dispatcher := NewDispatcher(func(_ context.Context, update types.Update) {
+		switch update.Message.ChatID {
+		case 1:
+			select {
+			case <-chat2Done:
+			case <-time.After(testTimeout):
+				close(failed)
+			}
+		case 2:
+			close(chat2Done)
+		}
+	}, 2, 10)
+
+	if err := dispatcher.Submit(context.Background(), chatUpdate(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Submit(context.Background(), chatUpdate(2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Stop()
+	dispatcher.Wait()
+
+	select {
+	case <-failed:
+		t.Error("обновление другого чата не обработано, пока первый чат занят")
+	default:
+	}
+}
+
+func TestDispatcherWorkersLimit(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		running    int
+		maxRunning int
+	)
+	dispatcher := NewDispatcher(func(context.Context, types.Update) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}, 2, 20)
+
+	for id := int64(1); id <= 10; id++ {
+		if err := dispatcher.Submit(context.Background(), chatUpdate(id, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dispatcher.Stop()
+	dispatcher.Wait()
+
+	if maxRunning > 2 {
+		t.Errorf("одновременно работало %d обработчиков, ограничение 2", maxRunning)
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	dispatcher := NewDispatcher(func(context.Context, types.Update) {
+		<-release
+	}, 1, 2)
+
+	for id := int64(1); id <= 2; id++ {
+		if err := dispatcher.Submit(context.Background(), chatUpdate(id, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Очередь заполнена: Submit ждет места, пока не отменен контекст
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := dispatcher.Submit(ctx, chatUpdate(3, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit в заполненную очередь: ошибка %v, ожидается DeadlineExceeded", err)
+	}
+	if err := dispatcher.TrySubmit(context.Background(), chatUpdate(3, 1)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("TrySubmit в заполненную очередь: ошибка %v, ожидается ErrQueueFull", err)
+	}
+
+	close(release)
+	dispatcher.Stop()
+	if err := dispatcher.Submit(context.Background(), chatUpdate(4, 1)); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("Submit после остановки: ошибка %v, ожидается ErrDispatcherStopped", err)
+	}
+	if err := dispatcher.TrySubmit(context.Background(), chatUpdate(4, 1)); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("TrySubmit после остановки: ошибка %v, ожидается ErrDispatcherStopped", err)
+	}
+	dispatcher.Wait()
+}
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"tgbot/internal/storage"
+)
+
+// OffsetStore хранит смещение getUpdates между перезапусками бота
+type OffsetStore interface {
+	// LoadOffset возвращает сохраненное смещение или 0, если оно еще не сохранялось
+	LoadOffset() (int64, error)
+	// SaveOffset сохраняет смещение следующего ожидаемого обновления
+	SaveOffset(offset int64) error
+}
+
+// Бакет и ключ, в которых хранится смещение
+const (
+	offsetBucket = "telegram"
+	offsetKey    = "offset"
+)
+
+// StorageOffsetStore хранит смещение в хранилище состояния бота
+type StorageOffsetStore struct {
+	repo *storage.Repository[offsetState]
+}
+
+type offsetState struct {
+	Offset    int64     `json:"offset"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewStorageOffsetStore создает хранилище смещения в store
+func NewStorageOffsetStore(store storage.Store) *StorageOffsetStore {
+	return &StorageOffsetStore{repo: storage.NewRepository[offsetState](store, offsetBucket)}
+}
+
+// LoadOffset читает сохраненное смещение
+func (s *StorageOffsetStore) LoadOffset() (int64, error) {
+	state, err := s.repo.Get(offsetKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения смещения: %w", err)
+	}
+	return state.Offset, nil
+}
+
+// SaveOffset сохраняет смещение
+func (s *StorageOffsetStore) SaveOffset(offset int64) error {
+	if err := s.repo.Put(offsetKey, offsetState{Offset: offset, UpdatedAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("ошибка сохранения смещения: %w", err)
+	}
+	return nil
+}
+
+// ImportOffsetFile переносит смещение из файла состояния прежних версий бота
+func ImportOffsetFile(tx storage.Tx, path string) (bool, error) {
+	return storage.ImportFile(tx, offsetBucket, offsetKey, path)
+}
+
+// maxCommitHold сколько необработанное обновление может удерживать подтверждение смещения.
+// getUpdates возвращает не больше 100 обновлений, поэтому зависший обработчик иначе
+// остановил бы прием обновлений, как только за ним накопится 100 других
+const maxCommitHold = time.Minute
+
+// offsetTracker отслеживает обновления, переданные обработчикам, и вычисляет смещение,
+// которое можно подтвердить: все обновления до него обработаны. Пока обновление
+// обрабатывается, Telegram продолжает его хранить и после сбоя пришлет повторно,
+// но не дольше hold
+type offsetTracker struct {
+	mu sync.Mutex
+	// next смещение после последнего принятого обновления
+	next int64
+	// pending время начала обработки обновлений, которые еще не завершились
+	pending map[int64]time.Time
+	// progress закрывается, когда завершается обработка обновления
+	progress chan struct{}
+	hold     time.Duration
+	now      func() time.Time
+}
+
+func newOffsetTracker(offset int64) *offsetTracker {
+	return &offsetTracker{
+		next:     offset,
+		pending:  make(map[int64]time.Time),
+		progress: make(chan struct{}),
+		hold:     maxCommitHold,
+		now:      time.Now,
+	}
+}
+
+// Next возвращает смещение после последнего принятого обновления; обновления
+// с меньшими номерами уже переданы обработчикам
+func (o *offsetTracker) Next() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.next
+}
+
+// Reset начинает отсчет со смещения offset, например сохраненного до перезапуска
+func (o *offsetTracker) Reset(offset int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next = offset
+}
+
+// Start отмечает, что обновление передано обработчику
+func (o *offsetTracker) Start(updateID int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[updateID] = o.now()
+	o.next = max(o.next, updateID+1)
+}
+
+// Skip отмечает обновление, которое не требует обработки
+func (o *offsetTracker) Skip(updateID int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next = max(o.next, updateID+1)
+}
+
+// Finish отмечает, что обработка обновления завершилась
+func (o *offsetTracker) Finish(updateID int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.pending, updateID)
+	close(o.progress)
+	o.progress = make(chan struct{})
+}
+
+// ReleaseStuck перестает удерживать подтверждение смещения ради обновлений, которые
+// обрабатываются дольше hold, и возвращает их номера. Обработка продолжается, но после
+// сбоя Telegram эти обновления уже не пришлет
+func (o *offsetTracker) ReleaseStuck() []int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var released []int64
+	for updateID, started := range o.pending {
+		if o.now().Sub(started) > o.hold {
+			delete(o.pending, updateID)
+			released = append(released, updateID)
+		}
+	}
+	sort.Slice(released, func(i, j int) bool { return released[i] < released[j] })
+	return released
+}
+
+// Committed возвращает смещение, до которого все принятые обновления обработаны
+func (o *offsetTracker) Committed() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	committed := o.next
+	for updateID := range o.pending {
+		committed = min(committed, updateID)
+	}
+	return committed
+}
+
+// Progress возвращает канал, который закроется, когда завершится обработка
+// очередного обновления
+func (o *offsetTracker) Progress() <-chan struct{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.progress
+}
@@ -0,0 +1,309 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"tgbot/internal/storage"
+	"tgbot/pkg/types"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker(10)
+	steps := []struct {
+		name string
+		step func()
+		want int64
+	}{
+		{"начальное смещение", func() {}, 10},
+		{"обновление передано обработчику", func() { tracker.Start(10) }, 10},
+		{"следующие обновления переданы", func() { tracker.Start(11); tracker.Start(12) }, 10},
+		{"завершено не первое обновление", func() { tracker.Finish(11) }, 10},
+		{"завершено первое обновление", func() { tracker.Finish(10) }, 12},
+		{"пропущено обновление без обработки", func() { tracker.Skip(13) }, 12},
+		{"завершены все обновления", func() { tracker.Finish(12) }, 14},
+		{"пропуск номеров", func() { tracker.Start(20); tracker.Finish(20) }, 21},
+	}
+	for _, step := range steps {
+		step.step()
+		if got := tracker.Committed(); got != step.want {
+			t.Fatalf("%s: подтверждаемое смещение %d, ожидается %d", step.name, got, step.want)
+		}
+	}
+	if next := tracker.Next(); next != 21 {
+		t.Errorf("следующее смещение %d, ожидается 21", next)
+	}
+}
+
+func TestOffsetTrackerReleaseStuck(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newOffsetTracker(1)
+	tracker.hold = time.Minute
+	tracker.now = func() time.Time { return now }
+
+	tracker.Start(1)
+	now = now.Add(30 * time.Second)
+	tracker.Start(2)
+	tracker.Start(3)
+	tracker.Finish(3)
+
+	if released := tracker.ReleaseStuck(); len(released) != 0 {
+		t.Errorf("до истечения срока освобождены обновления %v", released)
+	}
+	if got := tracker.Committed(); got != 1 {
+		t.Errorf("подтверждаемое смещение %d, ожидается 1", got)
+	}
+
+	// Обновление 1 обрабатывается дольше минуты и больше не удерживает смещение
+	now = now.Add(31 * time.Second)
+	if released := tracker.ReleaseStuck(); len(released) != 1 || released[0] != 1 {
+		t.Errorf("освобождены обновления %v, ожидается [1]", released)
+	}
+	if got := tracker.Committed(); got != 2 {
+		t.Errorf("подтверждаемое смещение %d, ожидается 2", got)
+	}
+
+	// Завершение освобожденного обновления ничего не меняет
+	tracker.Finish(1)
+	now = now.Add(time.Minute)
+	if released := tracker.ReleaseStuck(); len(released) != 1 || released[0] != 2 {
+		t.Errorf("освобождены обновления %v, ожидается [2]", released)
+	}
+	if got := tracker.Committed(); got != 4 {
+		t.Errorf("подтверждаемое смещение %d, ожидается 4", got)
+	}
+}
+
+func TestOffsetTrackerProgress(t *testing.T) {
+	tracker := newOffsetTracker(1)
+	tracker.Start(1)
+	progress := tracker.Progress()
+	select {
+	case <-progress:
+		t.Fatal("канал закрыт до завершения обработки")
+	default:
+	}
+	tracker.Finish(1)
+	waitFor(t, progress, "сигнал о завершении обработки")
+}
+
+// fakeTelegram сервер, отдающий getUpdates из фиксированного списка обновлений
+// и запоминающий запрошенные смещения
+type fakeTelegram struct {
+	updates []string
+
+	mu      sync.Mutex
+	offsets []int64
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := "true"
+	if r.URL.Path == "/getUpdates" {
+		var request getUpdatesRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.offsets = append(f.offsets, request.Offset)
+		f.mu.Unlock()
+
+		result = "["
+		returned := 0
+		for i, update := range f.updates {
+			// Как и Telegram, отдаем не больше 100 обновлений за раз
+			if int64(i+1) < request.Offset || returned == 100 {
+				continue
+			}
+			returned++
+			if result != "[" {
+				result += ","
+			}
+			result += update
+		}
+		result += "]"
+		// Имитация long polling, когда новых обновлений нет
+		if result == "[]" {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+}
+
+// maxOffset возвращает наибольшее смещение, с которым запрашивались обновления
+func (f *fakeTelegram) maxOffset() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var offset int64
+	for _, requested := range f.offsets {
+		offset = max(offset, requested)
+	}
+	return offset
+}
+
+func TestHandleUpdatesCommitsAfterHandler(t *testing.T) {
+	telegram := &fakeTelegram{updates: []string{
+		`{"update_id":1,"message":{"message_id":1,"chat":{"id":10},"text":"a"}}`,
+		`{"update_id":2,"message":{"message_id":2,"chat":{"id":20},"text":"b"}}`,
+		`{"update_id":3,"message":{"message_id":3,"chat":{"id":30},"text":"c"}}`,
+		// Обновление неподдерживаемого типа подтверждается без обработки
+		`{"update_id":4}`,
+	}}
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+
+	offsets := NewStorageOffsetStore(storage.NewMemory())
+	api := NewAPI("token", WithOffsetStore(offsets), WithRateLimiter(nil))
+	api.baseURL = server.URL
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := make(map[int64]int)
+	handler := func(_ context.Context, update types.Update) {
+		if update.UpdateID == 2 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		handled[update.UpdateID]++
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- api.HandleUpdates(ctx, handler) }()
+
+	waitFor(t, started, "начало обработки обновления 2")
+	// Даем боту время обработать остальные обновления и несколько раз опросить Telegram
+	time.Sleep(100 * time.Millisecond)
+
+	// Пока обновление 2 обрабатывается, оно не подтверждается ни Telegram, ни в хранилище
+	if offset := telegram.maxOffset(); offset > 2 {
+		t.Errorf("getUpdates запрошен со смещением %d до завершения обработки обновления 2", offset)
+	}
+	if saved, _ := offsets.LoadOffset(); saved > 2 {
+		t.Errorf("сохранено смещение %d до завершения обработки обновления 2", saved)
+	}
+
+	close(release)
+	deadline := time.Now().Add(testTimeout)
+	for telegram.maxOffset() < 5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	if saved, _ := offsets.LoadOffset(); saved != 5 {
+		t.Errorf("после обработки сохранено смещение %d, ожидается 5", saved)
+	}
+	// Повторно полученные Telegram обновления не обрабатываются повторно
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range []int64{1, 2, 3} {
+		if handled[id] != 1 {
+			t.Errorf("обновление %d обработано %d раз, ожидается 1", id, handled[id])
+		}
+	}
+}
+
+func TestHandleUpdatesShutdownKeepsUnfinished(t *testing.T) {
+	telegram := &fakeTelegram{updates: []string{
+		`{"update_id":1,"message":{"message_id":1,"chat":{"id":10},"text":"a"}}`,
+		`{"update_id":2,"message":{"message_id":2,"chat":{"id":20},"text":"b"}}`,
+	}}
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+
+	offsets := NewStorageOffsetStore(storage.NewMemory())
+	api := NewAPI("token", WithOffsetStore(offsets), WithRateLimiter(nil), WithShutdownTimeout(50*time.Millisecond))
+	api.baseURL = server.URL
+
+	first := make(chan struct{})
+	started := make(chan struct{})
+	// Обработчик обновления 2 не реагирует на отмену контекста и завершается после теста
+	stuck := make(chan struct{})
+	defer close(stuck)
+	handler := func(_ context.Context, update types.Update) {
+		switch update.UpdateID {
+		case 1:
+			close(first)
+		case 2:
+			close(started)
+			<-stuck
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- api.HandleUpdates(ctx, handler) }()
+	waitFor(t, first, "обработка обновления 1")
+	waitFor(t, started, "начало обработки обновления 2")
+	cancel()
+
+	if err := <-result; err == nil {
+		t.Error("остановка с незавершенным обработчиком не вернула ошибку")
+	}
+	// Незавершенное обновление будет получено снова после перезапуска
+	if saved, _ := offsets.LoadOffset(); saved != 2 {
+		t.Errorf("сохранено смещение %d, ожидается 2", saved)
+	}
+}
+
+func TestHandleUpdatesStuckHandler(t *testing.T) {
+	// За зависшим обновлением 1 накапливается больше обновлений, чем getUpdates отдает за раз
+	const total = 250
+	telegram := &fakeTelegram{}
+	for id := 1; id <= total; id++ {
+		telegram.updates = append(telegram.updates, fmt.Sprintf(
+			`{"update_id":%d,"message":{"message_id":%d,"chat":{"id":%d},"text":"a"}}`, id, id, id))
+	}
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+
+	offsets := NewStorageOffsetStore(storage.NewMemory())
+	api := NewAPI("token", WithOffsetStore(offsets), WithRateLimiter(nil), WithShutdownTimeout(50*time.Millisecond))
+	api.baseURL = server.URL
+	api.offsets.hold = 50 * time.Millisecond
+
+	// Обработчик обновления 1 не реагирует на отмену контекста и завершается после теста
+	stuck := make(chan struct{})
+	defer close(stuck)
+	done := make(chan struct{})
+	var mu sync.Mutex
+	handled := 0
+	handler := func(_ context.Context, update types.Update) {
+		if update.UpdateID == 1 {
+			<-stuck
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if handled++; handled == total-1 {
+			close(done)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- api.HandleUpdates(ctx, handler) }()
+
+	waitFor(t, done, "обработка обновлений после зависшего")
+	cancel()
+	if err := <-result; err == nil {
+		t.Error("остановка с зависшим обработчиком не вернула ошибку")
+	}
+
+	// Зависшее обновление больше не удерживает смещение
+	if saved, _ := offsets.LoadOffset(); saved != total+1 {
+		t.Errorf("сохранено смещение %d, ожидается %d", saved, total+1)
+	}
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ограничения Telegram на отправку сообщений
+// (https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this)
+const (
+	// globalMessagesPerSecond общее число сообщений в секунду для всех чатов
+	globalMessagesPerSecond = 30
+	// privateMessagesPerSecond число сообщений в секунду в одном личном чате
+	privateMessagesPerSecond = 1
+	// groupMessagesPerMinute число сообщений в минуту в одной группе
+	groupMessagesPerMinute = 20
+	// chatBurst число сообщений, которые можно отправить в чат подряд без ожидания
+	chatBurst = 3
+
+	// idleBucketTTL через сколько удаляется корзина чата, в который давно не отправлялись сообщения
+	idleBucketTTL = time.Minute * 10
+	// pruneThreshold число корзин, после которого начинается удаление неактивных
+	pruneThreshold = 1000
+)
+
+// tokenBucket корзина токенов. Токены могут уходить в минус: так каждый следующий
+// запрос получает свое место в очереди, и запросы выполняются в порядке поступления
+type tokenBucket struct {
+	rate   float64 // токенов в секунду
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// reserve забирает токен и возвращает время, которое нужно подождать до его появления
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel возвращает токен, если запрос не дождался своей очереди
+func (b *tokenBucket) cancel() {
+	b.tokens++
+}
+
+// RateLimiter планировщик исходящих запросов с ограничением частоты
+// для каждого чата и для бота в целом
+type RateLimiter struct {
+	mu     sync.Mutex
+	global *tokenBucket
+	chats  map[int64]*tokenBucket
+}
+
+// NewRateLimiter создает планировщик с ограничениями Telegram по умолчанию
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		global: newTokenBucket(globalMessagesPerSecond, globalMessagesPerSecond, time.Now()),
+		chats:  make(map[int64]*tokenBucket),
+	}
+}
+
+// Wait блокируется, пока запрос в чат chatID не уложится в ограничения чата и общее ограничение бота
+func (l *RateLimiter) Wait(ctx context.Context, chatID int64) error {
+	chat, err := l.wait(ctx, func(now time.Time) *tokenBucket { return l.chatBucket(chatID, now) })
+	if err != nil {
+		return err
+	}
+	if _, err := l.wait(ctx, func(time.Time) *tokenBucket { return l.global }); err != nil {
+		// Запрос не будет отправлен, поэтому токен чата тоже возвращается
+		l.mu.Lock()
+		chat.cancel()
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// wait резервирует токен в корзине и ждет его появления. Если ctx отменен раньше,
+// токен возвращается в корзину
+func (l *RateLimiter) wait(ctx context.Context, bucket func(now time.Time) *tokenBucket) (*tokenBucket, error) {
+	l.mu.Lock()
+	now := time.Now()
+	b := bucket(now)
+	delay := b.reserve(now)
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return b, nil
+	}
+
+	if err := sleep(ctx, delay); err != nil {
+		l.mu.Lock()
+		b.cancel()
+		l.mu.Unlock()
+		return nil, err
+	}
+	return b, nil
+}
+
+// chatBucket возвращает корзину чата, создавая ее при необходимости. Вызывается под l.mu
+func (l *RateLimiter) chatBucket(chatID int64, now time.Time) *tokenBucket {
+	if b, ok := l.chats[chatID]; ok {
+		return b
+	}
+
+	if len(l.chats) >= pruneThreshold {
+		for id, b := range l.chats {
+			if now.Sub(b.last) > idleBucketTTL {
+				delete(l.chats, id)
+			}
+		}
+	}
+
+	// У групп и каналов отрицательные идентификаторы
+	rate := float64(privateMessagesPerSecond)
+	if chatID < 0 {
+		rate = float64(groupMessagesPerMinute) / 60
+	}
+
+	b := newTokenBucket(rate, chatBurst, now)
+	l.chats[chatID] = b
+	return b
+}
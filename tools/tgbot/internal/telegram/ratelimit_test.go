@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// bucketStep резервирование токена в момент at с ожидаемой задержкой want;
+// cancel возвращает токен перед резервированием
+type bucketStep struct {
+	at     time.Duration
+	cancel bool
+	want   time.Duration
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	tests := []struct {
+		name  string
+		steps []bucketStep
+	}{
+		{
+			name: "запас расходуется без ожидания, дальше очередь",
+			steps: []bucketStep{
+				{0, false, 0},
+				{0, false, 0},
+				{0, false, 0},
+				{0, false, time.Second},
+				{0, false, 2 * time.Second},
+			},
+		},
+		{
+			name: "токены пополняются со временем",
+			steps: []bucketStep{
+				{0, false, 0},
+				{0, false, 0},
+				{0, false, 0},
+				{1500 * time.Millisecond, false, 0},
+				{1500 * time.Millisecond, false, 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "пополнение не превышает запас",
+			steps: []bucketStep{
+				{time.Hour, false, 0},
+				{time.Hour, false, 0},
+				{time.Hour, false, 0},
+				{time.Hour, false, time.Second},
+			},
+		},
+		{
+			name: "отмененный запрос освобождает место в очереди",
+			steps: []bucketStep{
+				{0, false, 0},
+				{0, false, 0},
+				{0, false, 0},
+				{0, false, time.Second},
+				{0, true, time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := newTokenBucket(1, chatBurst, start)
+			for i, step := range tt.steps {
+				if step.cancel {
+					bucket.cancel()
+				}
+				if got := bucket.reserve(at(step.at)); got != step.want {
+					t.Errorf("шаг %d: задержка %s, ожидается %s", i, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestChatBucketRates(t *testing.T) {
+	limiter := NewRateLimiter()
+	now := time.Now()
+	tests := []struct {
+		name   string
+		chatID int64
+		// want задержка после исчерпания запаса
+		want time.Duration
+	}{
+		{"личный чат", 42, time.Second},
+		{"группа", -100, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := limiter.chatBucket(tt.chatID, now)
+			for i := 0; i < chatBurst; i++ {
+				bucket.reserve(now)
+			}
+			if got := bucket.reserve(now); got != tt.want {
+				t.Errorf("задержка %s, ожидается %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefundsChatToken(t *testing.T) {
+	limiter := NewRateLimiter()
+	// Общий лимит исчерпан надолго: запрос будет ждать токен дольше, чем живет контекст
+	limiter.global.tokens = -1000
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, 42); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait вернул %v, ожидается DeadlineExceeded", err)
+	}
+
+	// Ни чат, ни общий лимит не должны потерять токен из-за неотправленного запроса
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if tokens := limiter.chats[42].tokens; tokens != chatBurst {
+		t.Errorf("токенов чата %v, ожидается %d", tokens, chatBurst)
+	}
+	if tokens := limiter.global.tokens; math.Abs(tokens+1000) > 1 {
+		t.Errorf("токенов общего лимита %v, ожидается около -1000", tokens)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter()
+	ctx := context.Background()
+	for i := 0; i < chatBurst; i++ {
+		start := time.Now()
+		if err := limiter.Wait(ctx, 42); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("запрос %d в пределах запаса ждал %s", i, elapsed)
+		}
+	}
+
+	// Запас исчерпан: следующий запрос ждет около секунды
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, 42); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait после исчерпания запаса вернул %v, ожидается DeadlineExceeded", err)
+	}
+}
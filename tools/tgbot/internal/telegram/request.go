@@ -0,0 +1,218 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRequestAttempts максимальное число попыток выполнения запроса
+	maxRequestAttempts = 4
+	baseRetryDelay     = time.Millisecond * 500
+	maxRetryDelay      = time.Second * 10
+	// maxRetryAfter ограничивает ожидание, которое может запросить Telegram при ответе 429
+	maxRetryAfter = time.Minute
+)
+
+// Error ошибка, возвращенная Telegram Bot API
+type Error struct {
+	// Method метод Bot API, при вызове которого произошла ошибка
+	Method string
+	// Code код ошибки (error_code или HTTP-статус, если тело ответа не удалось разобрать)
+	Code int
+	// Description описание ошибки от Telegram
+	Description string
+	// RetryAfter через сколько секунд можно повторить запрос (для ошибки 429)
+	RetryAfter int
+	// MigrateToChatID новый идентификатор чата, если группа была преобразована в супергруппу
+	MigrateToChatID int64
+}
+
+// Error реализует интерфейс error
+func (e *Error) Error() string {
+	return fmt.Sprintf("ошибка API %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// IsTooManyRequests сообщает, что запрос был отклонен из-за превышения лимитов
+func (e *Error) IsTooManyRequests() bool {
+	return e.Code == http.StatusTooManyRequests
+}
+
+// IsMessageNotModified сообщает, что при редактировании сообщения его текст и клавиатура не изменились
+func IsMessageNotModified(err error) bool {
+	apiErr, ok := AsError(err)
+	return ok && apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Description, "message is not modified")
+}
+
+// AsError извлекает *Error из цепочки ошибок
+func AsError(err error) (*Error, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// apiResponse общий формат ответа Telegram Bot API
+type apiResponse struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter      int   `json:"retry_after"`
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+// call выполняет метод Bot API с JSON-телом payload и декодирует поле result в result
+// (если он не nil). Ответы 429 повторяются после паузы retry_after, ответы 5xx —
+// с экспоненциальной задержкой и случайным разбросом
+func (t *API) call(ctx context.Context, method string, payload any, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга запроса %s: %w", method, err)
+	}
+	return t.send(ctx, method, "application/json", body, result)
+}
+
+// send отправляет готовое тело запроса с типом contentType, повторяя его при ошибках,
+// и декодирует поле result в result (если он не nil)
+func (t *API) send(ctx context.Context, method, contentType string, body []byte, result any) error {
+	for attempt := 1; ; attempt++ {
+		response, err := t.doRequest(ctx, method, contentType, body)
+		if err != nil {
+			return err
+		}
+
+		if response.Ok {
+			if result == nil || len(response.Result) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("ошибка декодирования ответа %s: %w", method, err)
+			}
+			return nil
+		}
+
+		apiErr := response.toError(method)
+		delay, retry := retryDelay(apiErr, attempt)
+		if !retry || attempt >= maxRequestAttempts {
+			return apiErr
+		}
+
+		fmt.Printf("Повтор запроса %s через %s (попытка %d): %v\n", method, delay, attempt+1, apiErr)
+		if err := t.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// callChat выполняет метод, отправляющий сообщение в чат chatID, с учетом ограничений частоты
+func (t *API) callChat(ctx context.Context, chatID int64, method string, payload any, result any) error {
+	if t.limiter != nil {
+		if err := t.limiter.Wait(ctx, chatID); err != nil {
+			return fmt.Errorf("ошибка ожидания очереди отправки %s: %w", method, err)
+		}
+	}
+	return t.call(ctx, method, payload, result)
+}
+
+// doRequest отправляет запрос и разбирает ответ; ошибки HTTP без JSON-тела
+// преобразуются в *Error с HTTP-статусом в качестве кода
+func (t *API) doRequest(ctx context.Context, method, contentType string, body []byte) (*apiResponse, error) {
+	url := fmt.Sprintf("%s/%s", t.baseURL, method)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса %s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка отправки запроса %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа %s: %w", method, err)
+	}
+
+	var response apiResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &apiResponse{ErrorCode: resp.StatusCode, Description: string(data)}, nil
+		}
+		return nil, fmt.Errorf("ошибка декодирования ответа %s: %w", method, err)
+	}
+	if !response.Ok && response.ErrorCode == 0 {
+		response.ErrorCode = resp.StatusCode
+	}
+
+	return &response, nil
+}
+
+// toError преобразует неуспешный ответ в *Error
+func (r *apiResponse) toError(method string) *Error {
+	apiErr := &Error{
+		Method:      method,
+		Code:        r.ErrorCode,
+		Description: r.Description,
+	}
+	if r.Parameters != nil {
+		apiErr.RetryAfter = r.Parameters.RetryAfter
+		apiErr.MigrateToChatID = r.Parameters.MigrateToChatID
+	}
+	return apiErr
+}
+
+// retryDelay определяет, нужно ли повторять запрос, и сколько ждать перед повтором
+func retryDelay(apiErr *Error, attempt int) (time.Duration, bool) {
+	switch {
+	case apiErr.IsTooManyRequests():
+		delay := time.Duration(apiErr.RetryAfter) * time.Second
+		if delay <= 0 {
+			delay = backoff(attempt)
+		}
+		if delay > maxRetryAfter {
+			return 0, false
+		}
+		return delay, true
+	case apiErr.Code >= http.StatusInternalServerError:
+		return backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// backoff возвращает экспоненциальную задержку со случайным разбросом
+func backoff(attempt int) time.Duration {
+	delay := baseRetryDelay << (attempt - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// sleep ждет указанное время или отмены ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,259 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reply ответ тестового сервера Bot API
+type reply struct {
+	status int
+	body   string
+}
+
+// scriptedTelegram сервер, отвечающий на запросы по порядку ответами из replies;
+// последний ответ повторяется
+type scriptedTelegram struct {
+	replies []reply
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (s *scriptedTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	reply := s.replies[min(s.requests, len(s.replies)-1)]
+	s.requests++
+	s.mu.Unlock()
+
+	w.WriteHeader(reply.status)
+	fmt.Fprint(w, reply.body)
+}
+
+func (s *scriptedTelegram) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newScriptedAPI возвращает API, отправляющий запросы на тестовый сервер с ответами
+// replies, и список пауз, которые API выдержал бы перед повторами
+func newScriptedAPI(t *testing.T, replies ...reply) (*API, *scriptedTelegram, *[]time.Duration) {
+	t.Helper()
+	telegram := &scriptedTelegram{replies: replies}
+	server := httptest.NewServer(telegram)
+	t.Cleanup(server.Close)
+
+	var delays []time.Duration
+	api := NewAPI("token", WithRateLimiter(nil))
+	api.baseURL = server.URL
+	api.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return api, telegram, &delays
+}
+
+var (
+	replyOK          = reply{http.StatusOK, `{"ok":true,"result":{"message_id":42}}`}
+	replyServerError = reply{http.StatusInternalServerError, `{"ok":false,"error_code":500,"description":"Internal Server Error"}`}
+	replyBadGateway  = reply{http.StatusBadGateway, `<html>502 Bad Gateway</html>`}
+)
+
+// tooManyRequests ответ 429 с паузой retryAfter секунд
+func tooManyRequests(retryAfter int) reply {
+	return reply{http.StatusTooManyRequests, fmt.Sprintf(
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d","parameters":{"retry_after":%d}}`,
+		retryAfter, retryAfter)}
+}
+
+func TestSendRetries(t *testing.T) {
+	// Пауза перед повтором ответа 5xx: от половины до полной экспоненциальной задержки
+	backoffRange := func(attempt int) [2]time.Duration {
+		delay := baseRetryDelay << (attempt - 1)
+		return [2]time.Duration{delay / 2, delay}
+	}
+
+	tests := []struct {
+		name     string
+		replies  []reply
+		wantCode int
+		// wantDelays допустимые диапазоны пауз перед каждым повтором
+		wantDelays [][2]time.Duration
+	}{
+		{
+			name:    "успех с первой попытки",
+			replies: []reply{replyOK},
+		},
+		{
+			name:       "429 с retry_after",
+			replies:    []reply{tooManyRequests(3), replyOK},
+			wantDelays: [][2]time.Duration{{3 * time.Second, 3 * time.Second}},
+		},
+		{
+			name:       "429 без retry_after",
+			replies:    []reply{{http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests"}`}, replyOK},
+			wantDelays: [][2]time.Duration{backoffRange(1)},
+		},
+		{
+			name:     "429 с паузой дольше допустимой",
+			replies:  []reply{tooManyRequests(int(maxRetryAfter/time.Second) + 1)},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:       "5xx, затем успех",
+			replies:    []reply{replyServerError, replyServerError, replyOK},
+			wantDelays: [][2]time.Duration{backoffRange(1), backoffRange(2)},
+		},
+		{
+			name:       "5xx без JSON",
+			replies:    []reply{replyBadGateway, replyOK},
+			wantDelays: [][2]time.Duration{backoffRange(1)},
+		},
+		{
+			name:       "попытки исчерпаны",
+			replies:    []reply{replyServerError},
+			wantCode:   http.StatusInternalServerError,
+			wantDelays: [][2]time.Duration{backoffRange(1), backoffRange(2), backoffRange(3)},
+		},
+		{
+			name:     "4xx не повторяется",
+			replies:  []reply{{http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`}},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, telegram, delays := newScriptedAPI(t, tt.replies...)
+
+			var result struct {
+				MessageID int `json:"message_id"`
+			}
+			err := api.call(context.Background(), "sendMessage", map[string]any{"chat_id": 1}, &result)
+
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.MessageID != 42 {
+					t.Errorf("message_id = %d, ожидается 42", result.MessageID)
+				}
+			} else {
+				apiErr, ok := AsError(err)
+				if !ok {
+					t.Fatalf("ошибка %v, ожидается *Error", err)
+				}
+				if apiErr.Code != tt.wantCode || apiErr.Method != "sendMessage" {
+					t.Errorf("ошибка %s %d, ожидается sendMessage %d", apiErr.Method, apiErr.Code, tt.wantCode)
+				}
+			}
+
+			if len(*delays) != len(tt.wantDelays) {
+				t.Fatalf("паузы %v, ожидается %d", *delays, len(tt.wantDelays))
+			}
+			for i, delay := range *delays {
+				if want := tt.wantDelays[i]; delay < want[0] || delay > want[1] {
+					t.Errorf("пауза перед повтором %d: %s, ожидается от %s до %s", i+1, delay, want[0], want[1])
+				}
+			}
+			if want := len(tt.wantDelays) + 1; telegram.count() != want {
+				t.Errorf("запросов %d, ожидается %d", telegram.count(), want)
+			}
+		})
+	}
+}
+
+func TestSendCancelledDuringBackoff(t *testing.T) {
+	api, telegram, _ := newScriptedAPI(t, tooManyRequests(30), replyOK)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleep(ctx, d)
+	}
+
+	start := time.Now()
+	err := api.call(ctx, "sendMessage", map[string]any{"chat_id": 1}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ошибка %v, ожидается %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > testTimeout {
+		t.Errorf("отмена заняла %s", elapsed)
+	}
+	if telegram.count() != 1 {
+		t.Errorf("запросов %d, ожидается 1: после отмены запрос не повторяется", telegram.count())
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       reply
+		want        Error
+		notModified bool
+	}{
+		{
+			name:  "ошибка с параметрами",
+			reply: tooManyRequests(61),
+			want:  Error{Method: "sendMessage", Code: 429, Description: "Too Many Requests: retry after 61", RetryAfter: 61},
+		},
+		{
+			name:  "группа преобразована в супергруппу",
+			reply: reply{http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-1001234}}`},
+			want:  Error{Method: "sendMessage", Code: 400, Description: "Bad Request: group chat was upgraded to a supergroup chat", MigrateToChatID: -1001234},
+		},
+		{
+			name:        "сообщение не изменилось",
+			reply:       reply{http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: message is not modified"}`},
+			want:        Error{Method: "sendMessage", Code: 400, Description: "Bad Request: message is not modified"},
+			notModified: true,
+		},
+		{
+			name:  "нет error_code в ответе",
+			reply: reply{http.StatusForbidden, `{"ok":false,"description":"Forbidden: bot was blocked by the user"}`},
+			want:  Error{Method: "sendMessage", Code: 403, Description: "Forbidden: bot was blocked by the user"},
+		},
+		{
+			name:  "ответ не JSON",
+			reply: reply{http.StatusNotFound, `404 page not found`},
+			want:  Error{Method: "sendMessage", Code: 404, Description: "404 page not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, _, _ := newScriptedAPI(t, tt.reply)
+			err := api.call(context.Background(), "sendMessage", map[string]any{"chat_id": 1}, nil)
+
+			apiErr, ok := AsError(fmt.Errorf("обертка: %w", err))
+			if !ok {
+				t.Fatalf("ошибка %v, ожидается *Error", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("ошибка %+v, ожидается %+v", *apiErr, tt.want)
+			}
+			if apiErr.IsTooManyRequests() != (tt.want.Code == http.StatusTooManyRequests) {
+				t.Errorf("IsTooManyRequests = %v", apiErr.IsTooManyRequests())
+			}
+			if IsMessageNotModified(err) != tt.notModified {
+				t.Errorf("IsMessageNotModified = %v, ожидается %v", IsMessageNotModified(err), tt.notModified)
+			}
+		})
+	}
+}
+
+func TestSendInvalidResponse(t *testing.T) {
+	api, _, _ := newScriptedAPI(t, reply{http.StatusOK, `не JSON`})
+	err := api.call(context.Background(), "getMe", nil, nil)
+	if err == nil {
+		t.Fatal("ожидается ошибка декодирования ответа")
+	}
+	if _, ok := AsError(err); ok {
+		t.Errorf("ошибка декодирования %v не должна быть ошибкой API", err)
+	}
+}
@@ -0,0 +1,351 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"tgbot/pkg/types"
+)
+
+// MaxMessageLength максимальная длина текста сообщения в Telegram
+const MaxMessageLength = 4096
+
+// Режимы разметки Telegram (parse_mode)
+const (
+	ParseModeMarkdown   = "Markdown"
+	ParseModeMarkdownV2 = "MarkdownV2"
+	ParseModeHTML       = "HTML"
+)
+
+// markdownPre маркер блока кода в разметке Markdown
+const markdownPre = "```"
+
+// SendLongMessage отправляет текст, разбивая его на несколько сообщений, если он
+// не помещается в одно. Клавиатура прикрепляется к последнему сообщению
+func (t *API) SendLongMessage(ctx context.Context, chatID int64, text string, keyboard [][]types.InlineKeyboardButton) error {
+	chunks := SplitText(text, t.parseMode, MaxMessageLength)
+	for i, chunk := range chunks {
+		var chunkKeyboard [][]types.InlineKeyboardButton
+		if i == len(chunks)-1 {
+			chunkKeyboard = keyboard
+		}
+		if err := t.SendMessage(ctx, chatID, chunk, chunkKeyboard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EditLongMessage заменяет текст сообщения первой частью текста, а остальные части
+// отправляет следующими сообщениями. Клавиатура прикрепляется к последней части
+func (t *API) EditLongMessage(ctx context.Context, chatID int64, messageID int, text string, keyboard [][]types.InlineKeyboardButton) error {
+	chunks := SplitText(text, t.parseMode, MaxMessageLength)
+	if len(chunks) == 1 {
+		return t.EditMessageText(ctx, chatID, messageID, chunks[0], keyboard)
+	}
+
+	if err := t.EditMessageText(ctx, chatID, messageID, chunks[0], nil); err != nil {
+		return err
+	}
+	for i, chunk := range chunks[1:] {
+		var chunkKeyboard [][]types.InlineKeyboardButton
+		if i == len(chunks)-2 {
+			chunkKeyboard = keyboard
+		}
+		if err := t.SendMessage(ctx, chatID, chunk, chunkKeyboard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitText разбивает текст в разметке parseMode на части не длиннее limit по границам строк.
+// Если на границе частей остаются незакрытые сущности (жирный текст, курсив, код, блоки кода),
+// они закрываются в конце части и открываются заново в начале следующей.
+// Строки длиннее limit разбиваются по пробелам
+func SplitText(text, parseMode string, limit int) []string {
+	if textLength(text) <= limit {
+		return []string{text}
+	}
+
+	var (
+		chunks []string
+		chunk  strings.Builder
+		// open сущности, незакрытые к началу текущей строки
+		open []string
+		// prefix разметка, которой начинается текущая часть
+		prefix string
+	)
+
+	flush := func() {
+		body := strings.TrimRight(chunk.String(), "\n")
+		if body != prefix && strings.TrimSpace(body) != "" {
+			chunks = append(chunks, body+closingMarkup(parseMode, open))
+		}
+		chunk.Reset()
+		prefix = openingMarkup(parseMode, open)
+		chunk.WriteString(prefix)
+	}
+
+	// Запас под разметку, которая добавляется при закрытии и повторном открытии сущностей.
+	// При очень малом limit запас уменьшается, но строка все равно режется хотя бы по символу
+	const markupReserve = 64
+	lineLimit := max(limit-markupReserve, limit/2, 1)
+
+	for _, line := range splitLines(text, lineLimit) {
+		after := scanMarkup(parseMode, open, line)
+		if chunk.Len() > 0 && textLength(chunk.String())+textLength(line)+textLength(closingMarkup(parseMode, after)) > limit {
+			flush()
+		}
+		chunk.WriteString(line)
+		open = after
+	}
+
+	flush()
+
+	return chunks
+}
+
+// splitLines разбивает текст на строки (с сохранением переводов строк),
+// дополнительно разрезая строки длиннее limit
+func splitLines(text string, limit int) []string {
+	var lines []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		for textLength(line) > limit {
+			cut := cutIndex(line, limit)
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// cutIndex возвращает байтовую позицию, по которой строку можно разрезать,
+// не превышая limit: по последнему пробелу, а если его нет — по границе символа.
+// Экранирующий \ не отделяется от следующего за ним символа. Позиция всегда
+// больше нуля, чтобы от строки отрезался хотя бы один символ
+func cutIndex(line string, limit int) int {
+	length, lastSpace, cut := 0, -1, 0
+	for i, r := range line {
+		size := runeLength(r)
+		if length+size > limit {
+			break
+		}
+		length += size
+		cut = i + len(string(r))
+		if r == ' ' {
+			lastSpace = cut
+		}
+	}
+	if lastSpace > 0 {
+		cut = lastSpace
+	}
+	if cut > 0 && cut < len(line) && escapedAt(line, cut) {
+		cut--
+	}
+	if cut == 0 {
+		// Даже первый символ не помещается в limit или разрез пришелся на \ в начале
+		// строки: отрезаем первый символ вместе с экранируемым им символом
+		_, size := utf8.DecodeRuneInString(line)
+		cut = size
+		if line[0] == '\\' && len(line) > 1 {
+			_, next := utf8.DecodeRuneInString(line[1:])
+			cut += next
+		}
+	}
+	return cut
+}
+
+// escapedAt сообщает, что символ на позиции i экранирован: перед ним нечетное число \
+func escapedAt(line string, i int) bool {
+	backslashes := 0
+	for j := i - 1; j >= 0 && line[j] == '\\'; j-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// scanMarkup возвращает сущности, которые остаются открытыми после строки line,
+// если до нее были открыты сущности open
+func scanMarkup(parseMode string, open []string, line string) []string {
+	open = append([]string(nil), open...)
+
+	switch parseMode {
+	case ParseModeHTML:
+		return scanHTML(open, line)
+	case ParseModeMarkdownV2:
+		return scanMarkdownV2(open, line)
+	default:
+		return scanMarkdown(open, line)
+	}
+}
+
+// scanMarkdown разбирает устаревшую разметку Markdown, в которой сущности не вкладываются друг в друга
+func scanMarkdown(open []string, line string) []string {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case len(open) > 0 && open[0] == markdownPre:
+			if strings.HasPrefix(line[i:], markdownPre) {
+				open = nil
+				i += len(markdownPre) - 1
+			}
+		case len(open) > 0:
+			if line[i] == open[0][0] {
+				open = nil
+			}
+		case line[i] == '\\':
+			i++
+		case strings.HasPrefix(line[i:], markdownPre):
+			open = []string{markdownPre}
+			i += len(markdownPre) - 1
+		case line[i] == '*' || line[i] == '_' || line[i] == '`':
+			open = []string{string(line[i])}
+		}
+	}
+	return open
+}
+
+// scanMarkdownV2 разбирает разметку MarkdownV2 с вложенными сущностями
+func scanMarkdownV2(open []string, line string) []string {
+	top := func() string {
+		if len(open) == 0 {
+			return ""
+		}
+		return open[len(open)-1]
+	}
+
+	for i := 0; i < len(line); i++ {
+		// Внутри кода разметка не действует, экранируются только ` и \
+		if t := top(); t == markdownPre || t == "`" {
+			switch {
+			case line[i] == '\\':
+				i++
+			case strings.HasPrefix(line[i:], t):
+				open = open[:len(open)-1]
+				i += len(t) - 1
+			}
+			continue
+		}
+
+		switch {
+		case line[i] == '\\':
+			i++
+		case strings.HasPrefix(line[i:], "]("):
+			// Адрес ссылки пропускаем целиком: в нем могут быть неэкранированные _ и *
+			for i += 2; i < len(line) && line[i] != ')'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(line[i:], markdownPre):
+			open = append(open, markdownPre)
+			i += len(markdownPre) - 1
+		case line[i] == '`':
+			open = append(open, "`")
+		case strings.HasPrefix(line[i:], "||"), strings.HasPrefix(line[i:], "__"):
+			open = toggleMarkup(open, line[i:i+2])
+			i++
+		case line[i] == '*' || line[i] == '_' || line[i] == '~':
+			open = toggleMarkup(open, line[i:i+1])
+		}
+	}
+	return open
+}
+
+// toggleMarkup закрывает сущность, если она открыта, иначе открывает ее
+func toggleMarkup(open []string, marker string) []string {
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] == marker {
+			return append(open[:i], open[i+1:]...)
+		}
+	}
+	return append(open, marker)
+}
+
+// scanHTML разбирает HTML-разметку; в open хранятся открывающие теги целиком
+func scanHTML(open []string, line string) []string {
+	for {
+		start := strings.IndexByte(line, '<')
+		if start < 0 {
+			return open
+		}
+		end := strings.IndexByte(line[start:], '>')
+		if end < 0 {
+			return open
+		}
+		tag := line[start : start+end+1]
+		line = line[start+end+1:]
+
+		if strings.HasPrefix(tag, "</") {
+			name := htmlTagName(tag)
+			for i := len(open) - 1; i >= 0; i-- {
+				if htmlTagName(open[i]) == name {
+					open = append(open[:i], open[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		open = append(open, tag)
+	}
+}
+
+// htmlTagName возвращает имя тега без атрибутов
+func htmlTagName(tag string) string {
+	name := strings.Trim(tag, "</>")
+	if i := strings.IndexAny(name, " \t\n"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// openingMarkup возвращает разметку, повторно открывающую сущности в начале новой части
+func openingMarkup(parseMode string, open []string) string {
+	var b strings.Builder
+	for _, entity := range open {
+		b.WriteString(entity)
+		if parseMode != ParseModeHTML && entity == markdownPre {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// closingMarkup возвращает разметку, закрывающую сущности в конце части
+func closingMarkup(parseMode string, open []string) string {
+	var b strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		switch {
+		case parseMode == ParseModeHTML:
+			b.WriteString("</" + htmlTagName(open[i]) + ">")
+		case open[i] == markdownPre:
+			b.WriteString("\n" + markdownPre)
+		default:
+			b.WriteString(open[i])
+		}
+	}
+	return b.String()
+}
+
+// textLength возвращает длину текста так, как ее считает Telegram (в кодовых единицах UTF-16)
+func textLength(text string) int {
+	length := 0
+	for _, r := range text {
+		length += runeLength(r)
+	}
+	return length
+}
+
+// runeLength возвращает число кодовых единиц UTF-16, занимаемых символом
+func runeLength(r rune) int {
+	if utf16.IsSurrogate(r) || r < 0x10000 {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,207 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var parseModes = []string{ParseModeMarkdown, ParseModeMarkdownV2, ParseModeHTML}
+
+// checkChunks проверяет общие свойства частей: длину по правилам Telegram, целостность
+// символов UTF-8 и то, что все сущности закрыты в конце каждой части
+func checkChunks(t *testing.T, parseMode string, chunks []string, limit int) {
+	t.Helper()
+	for i, chunk := range chunks {
+		// Длина считается независимо от textLength: в кодовых единицах UTF-16, как в Telegram
+		if length := len(utf16.Encode([]rune(chunk))); length > limit {
+			t.Errorf("часть %d: длина %d больше %d", i, length, limit)
+		}
+		if !utf8.ValidString(chunk) {
+			t.Errorf("часть %d: символ разрезан посередине", i)
+		}
+		if open := scanMarkup(parseMode, nil, chunk); len(open) > 0 {
+			t.Errorf("часть %d: незакрытые сущности %q", i, open)
+		}
+	}
+}
+
+func TestSplitTextShort(t *testing.T) {
+	for _, parseMode := range parseModes {
+		text := "*коротко*"
+		if chunks := SplitText(text, parseMode, MaxMessageLength); len(chunks) != 1 || chunks[0] != text {
+			t.Errorf("%s: текст разбит на %q", parseMode, chunks)
+		}
+	}
+}
+
+func TestSplitTextSurrogatePairs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		// Строка режется по длине без пробелов: граница попадает на середину суррогатной пары
+		{"эмодзи без пробелов", "a" + strings.Repeat("😀", 3000)},
+		{"эмодзи и кириллица", strings.Repeat("ж😀", 2000)},
+		{"эмодзи со строками", strings.Repeat(strings.Repeat("👍", 100)+"\n", 50)},
+	}
+	for _, tt := range tests {
+		for _, parseMode := range parseModes {
+			t.Run(tt.name+" "+parseMode, func(t *testing.T) {
+				chunks := SplitText(tt.text, parseMode, MaxMessageLength)
+				if len(chunks) < 2 {
+					t.Fatalf("текст длиной %d не разбит", textLength(tt.text))
+				}
+				checkChunks(t, parseMode, chunks, MaxMessageLength)
+
+				joined := strings.Join(chunks, "")
+				if strings.Count(joined, "😀")+strings.Count(joined, "👍") != strings.Count(tt.text, "😀")+strings.Count(tt.text, "👍") {
+					t.Error("после разбиения потеряны символы")
+				}
+			})
+		}
+	}
+}
+
+func TestSplitTextEntitySpansCut(t *testing.T) {
+	// lines строки, которые не помещаются в одно сообщение
+	lines := func(n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "строка %d с текстом\n", i)
+		}
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+
+	tests := []struct {
+		name      string
+		parseMode string
+		text      string
+		// reopen разметка, с которой должна начинаться вторая часть
+		reopen string
+	}{
+		{"жирный HTML", ParseModeHTML, "<b>" + lines(400) + "</b>", "<b>"},
+		{"ссылка и курсив HTML", ParseModeHTML, `<a href="https://example.com"><i>` + lines(400) + "</i></a>", `<a href="https://example.com"><i>`},
+		{"блок кода HTML", ParseModeHTML, "<pre><code>" + lines(400) + "</code></pre>", "<pre><code>"},
+		{"жирный Markdown", ParseModeMarkdown, "*" + lines(400) + "*", "*"},
+		{"блок кода Markdown", ParseModeMarkdown, "```\n" + lines(400) + "\n```", "```\n"},
+		{"вложенные сущности MarkdownV2", ParseModeMarkdownV2, "*жирный _курсив\n" + lines(400) + "_*", "*_"},
+		{"спойлер MarkdownV2", ParseModeMarkdownV2, "||" + lines(400) + "||", "||"},
+		{"блок кода MarkdownV2", ParseModeMarkdownV2, "```go\n" + lines(400) + "\n```", "```\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitText(tt.text, tt.parseMode, MaxMessageLength)
+			if len(chunks) < 2 {
+				t.Fatalf("текст длиной %d не разбит", textLength(tt.text))
+			}
+			checkChunks(t, tt.parseMode, chunks, MaxMessageLength)
+
+			for i, chunk := range chunks[1:] {
+				if !strings.HasPrefix(chunk, tt.reopen) {
+					t.Errorf("часть %d начинается с %q, ожидается %q", i+1, chunk[:min(len(chunk), 40)], tt.reopen)
+				}
+			}
+
+			// Ни одна строка не потеряна и не повторена
+			joined := strings.Join(chunks, "\n")
+			for i := 1; i <= 400; i++ {
+				if n := strings.Count(joined, fmt.Sprintf("строка %d с", i)); n != 1 {
+					t.Fatalf("строка %d встречается %d раз", i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTextLimit(t *testing.T) {
+	// Текст с длинными строками без пробелов, пробелами, эмодзи и сущностями
+	var b strings.Builder
+	for i := 0; i < 30; i++ {
+		b.WriteString(strings.Repeat("x", 5000) + "\n")
+		b.WriteString(strings.Repeat("слово ", 900) + "\n")
+		b.WriteString(strings.Repeat("🚀", 2500) + "\n")
+	}
+	body := b.String()
+
+	texts := map[string]string{
+		ParseModeMarkdown:   "*" + body + "*\n```\n" + body + "```",
+		ParseModeMarkdownV2: "*_" + body + "_*\n~" + body + "~",
+		ParseModeHTML:       "<b><i>" + body + "</i></b>\n<pre>" + body + "</pre>",
+	}
+	for _, limit := range []int{MaxMessageLength, 1000, 200} {
+		for _, parseMode := range parseModes {
+			t.Run(fmt.Sprintf("%s %d", parseMode, limit), func(t *testing.T) {
+				chunks := SplitText(texts[parseMode], parseMode, limit)
+				if len(chunks) < 2 {
+					t.Fatal("текст не разбит")
+				}
+				checkChunks(t, parseMode, chunks, limit)
+			})
+		}
+	}
+}
+
+func TestSplitTextSmallLimit(t *testing.T) {
+	texts := []string{
+		strings.Repeat("word ", 100),
+		strings.Repeat("x", 300),
+		strings.Repeat("😀", 100),
+		strings.Repeat(`\*`, 100),
+	}
+	for _, limit := range []int{1, 2, 3, 10, 50, 63, 64, 65, 100} {
+		for _, text := range texts {
+			t.Run(fmt.Sprintf("%d %.10s", limit, text), func(t *testing.T) {
+				done := make(chan []string, 1)
+				go func() { done <- SplitText(text, ParseModeMarkdownV2, limit) }()
+
+				var chunks []string
+				select {
+				case chunks = <-done:
+				case <-time.After(testTimeout):
+					t.Fatalf("SplitText с limit=%d не завершился", limit)
+				}
+				if len(chunks) < 2 {
+					t.Fatalf("текст длиной %d не разбит", textLength(text))
+				}
+				joined := strings.ReplaceAll(strings.Join(chunks, ""), " ", "")
+				if want := strings.ReplaceAll(text, " ", ""); joined != want {
+					t.Errorf("после разбиения текст изменился: %q", joined)
+				}
+			})
+		}
+	}
+}
+
+func TestSplitTextKeepsEscapes(t *testing.T) {
+	// Экранированные символы без пробелов: разрез может прийтись на любую позицию
+	texts := map[string]string{
+		"экранирование":           strings.Repeat(`a\_b\*c\.`, 700),
+		"экранированный \\":       strings.Repeat(`x\\\_`, 1500),
+		"экранирование в строках": strings.Repeat(strings.Repeat(`\-`, 150)+"\n", 40),
+	}
+	for name, text := range texts {
+		for _, limit := range []int{MaxMessageLength, 1001, 100, 65, 7, 2} {
+			t.Run(fmt.Sprintf("%s %d", name, limit), func(t *testing.T) {
+				chunks := SplitText(text, ParseModeMarkdownV2, limit)
+				if len(chunks) < 2 {
+					t.Fatal("текст не разбит")
+				}
+				for i, chunk := range chunks {
+					if escapedAt(chunk, len(chunk)) {
+						t.Errorf("часть %d заканчивается неэкранированным \\: %q", i, chunk[max(0, len(chunk)-10):])
+					}
+					if strings.HasPrefix(chunk, "_") || strings.HasPrefix(chunk, "*") || strings.HasPrefix(chunk, ".") {
+						t.Errorf("часть %d начинается с символа, отделенного от \\: %q", i, chunk[:min(len(chunk), 10)])
+					}
+				}
+				if joined := strings.Join(chunks, ""); strings.ReplaceAll(joined, "\n", "") != strings.ReplaceAll(text, "\n", "") {
+					t.Error("после разбиения текст изменился")
+				}
+			})
+		}
+	}
+}
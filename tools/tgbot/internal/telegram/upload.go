@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"strconv"
+)
+
+// InputFile файл, загружаемый в Telegram вместе с запросом
+type InputFile struct {
+	// Name имя файла, которое увидит пользователь
+	Name string
+	Data []byte
+}
+
+// SendPhoto отправляет изображение с подписью caption и возвращает идентификатор сообщения
+func (t *API) SendPhoto(ctx context.Context, chatID int64, photo InputFile, caption string) (int, error) {
+	fields := map[string]string{"caption": caption}
+	if caption != "" && t.parseMode != "" {
+		fields["parse_mode"] = t.parseMode
+	}
+
+	var sent struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := t.upload(ctx, chatID, "sendPhoto", fields, "photo", photo, &sent); err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// SendDocument отправляет файл с подписью caption
+func (t *API) SendDocument(ctx context.Context, chatID int64, document InputFile, caption string) error {
+	fields := map[string]string{"caption": caption}
+	if caption != "" && t.parseMode != "" {
+		fields["parse_mode"] = t.parseMode
+	}
+	return t.upload(ctx, chatID, "sendDocument", fields, "document", document, nil)
+}
+
+// upload выполняет метод, загружающий файл file в поле fileField, с учетом ограничений
+// частоты отправки в чат chatID. Тело запроса передается как multipart/form-data
+func (t *API) upload(ctx context.Context, chatID int64, method string, fields map[string]string, fileField string, file InputFile, result any) error {
+	if t.limiter != nil {
+		if err := t.limiter.Wait(ctx, chatID); err != nil {
+			return fmt.Errorf("ошибка ожидания очереди отправки %s: %w", method, err)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("chat_id", strconv.FormatInt(chatID, 10)); err != nil {
+		return fmt.Errorf("ошибка формирования запроса %s: %w", method, err)
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return fmt.Errorf("ошибка формирования запроса %s: %w", method, err)
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, file.Name)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования запроса %s: %w", method, err)
+	}
+	if _, err := part.Write(file.Data); err != nil {
+		return fmt.Errorf("ошибка формирования запроса %s: %w", method, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("ошибка формирования запроса %s: %w", method, err)
+	}
+
+	return t.send(ctx, method, writer.FormDataContentType(), body.Bytes(), result)
+}
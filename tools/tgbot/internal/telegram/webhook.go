@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"tgbot/pkg/types"
+)
+
+const (
+	// secretTokenHeader заголовок, в котором Telegram передает секрет webhook
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// maxWebhookBodySize ограничение размера тела входящего обновления
+	maxWebhookBodySize = 1 << 20
+	// webhookRetryAfter через сколько секунд повторить доставку, если очередь заполнена
+	webhookRetryAfter = 1
+)
+
+// WebhookConfig параметры работы в режиме webhook
+type WebhookConfig struct {
+	// URL публичный адрес, который регистрируется в Telegram через setWebhook
+	URL string
+	// ListenAddr адрес, на котором запускается встроенный сервер (например, ":8443")
+	ListenAddr string
+	// SecretToken секрет, который Telegram передает в заголовке X-Telegram-Bot-Api-Secret-Token
+	SecretToken string
+	// CertFile и KeyFile включают HTTPS; без них сервер работает по HTTP (например, за reverse proxy)
+	CertFile string
+	KeyFile  string
+}
+
+type setWebhookRequest struct {
+	URL                string   `json:"url"`
+	SecretToken        string   `json:"secret_token,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty"`
+}
+
+type deleteWebhookRequest struct {
+	DropPendingUpdates bool `json:"drop_pending_updates"`
+}
+
+// HandleWebhook регистрирует webhook и обрабатывает обновления, приходящие на встроенный сервер,
+// до отмены ctx. При остановке сервер перестает принимать запросы и ждет завершения
+// текущих обработчиков не дольше shutdownTimeout.
+func (t *API) HandleWebhook(ctx context.Context, cfg WebhookConfig, handler types.UpdateHandler) error {
+	if cfg.URL == "" {
+		return fmt.Errorf("не указан URL webhook")
+	}
+	if cfg.ListenAddr == "" {
+		return fmt.Errorf("не указан адрес для запуска сервера webhook")
+	}
+
+	webhookURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("некорректный URL webhook: %w", err)
+	}
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	dispatcher := NewDispatcher(handler, t.workers, t.queueSize)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, t.webhookHandler(handlerCtx, cfg.SecretToken, dispatcher))
+
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	if err := t.SetWebhook(ctx, cfg.URL, cfg.SecretToken); err != nil {
+		return err
+	}
+
+	fmt.Printf("Запуск сервера webhook на %s (путь %s)...\n", cfg.ListenAddr, path)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.CertFile != "" && cfg.KeyFile != "" {
+			serveErr <- server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		dispatcher.Stop()
+		return fmt.Errorf("ошибка сервера webhook: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Printf("Остановка сервера webhook, ожидание завершения обработчиков...\n")
+
+	// Новые обновления отклоняются, и Telegram доставит их повторно после перезапуска
+	dispatcher.Stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), t.shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		cancelHandlers()
+		return fmt.Errorf("обработчики не завершились за %s: %w", t.shutdownTimeout, err)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		dispatcher.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-shutdownCtx.Done():
+		cancelHandlers()
+		return fmt.Errorf("обработчики не завершились за %s", t.shutdownTimeout)
+	}
+}
+
+// webhookHandler проверяет секрет, декодирует обновление и передает его диспетчеру
+func (t *API) webhookHandler(ctx context.Context, secretToken string, dispatcher *Dispatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if secretToken != "" {
+			got := r.Header.Get(secretTokenHeader)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(secretToken)) != 1 {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		var update telegramUpdate
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWebhookBodySize)).Decode(&update); err != nil {
+			fmt.Printf("Ошибка декодирования обновления webhook: %v\n", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		if converted, ok := convertUpdate(update); ok {
+			// Запрос не удерживается, пока очередь заполнена: Telegram повторит доставку
+			// позже, как и после остановки бота. Обработчик получает ctx сервера, а не
+			// запроса, чтобы не прерываться после ответа Telegram
+			err := dispatcher.TrySubmit(ctx, converted)
+			switch {
+			case errors.Is(err, ErrQueueFull):
+				w.Header().Set("Retry-After", strconv.Itoa(webhookRetryAfter))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			case err != nil:
+				http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// SetWebhook регистрирует URL, на который Telegram будет отправлять обновления
+func (t *API) SetWebhook(ctx context.Context, webhookURL, secretToken string) error {
+	message := setWebhookRequest{
+		URL:            webhookURL,
+		SecretToken:    secretToken,
+		AllowedUpdates: []string{"message", "callback_query"},
+		// Накопившиеся за время простоя обновления отбрасываются самим Telegram
+		DropPendingUpdates: t.skipBacklog,
+	}
+
+	return t.call(ctx, "setWebhook", message, nil)
+}
+
+// DeleteWebhook удаляет webhook, чтобы бот мог получать обновления через getUpdates
+func (t *API) DeleteWebhook(ctx context.Context) error {
+	return t.call(ctx, "deleteWebhook", deleteWebhookRequest{}, nil)
+}
@@ -0,0 +1,161 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tgbot/pkg/types"
+)
+
+const (
+	testSecret    = "секрет-webhook"
+	messageUpdate = `{"update_id": 10, "message": {"message_id": 1, "chat": {"id": 5, "type": "private"}, "from": {"id": 7}, "text": "/help"}}`
+)
+
+// webhookRequest отправляет запрос с телом body на адрес webhook с секретом secret
+// (пустой — без заголовка)
+func webhookRequest(t *testing.T, method, url, secret, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "" {
+		req.Header.Set(secretTokenHeader, secret)
+	}
+	client := &http.Client{Timeout: testTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		secret     string
+		body       string
+		wantStatus int
+		// wantUpdate обновление должно попасть в обработчик
+		wantUpdate bool
+	}{
+		{"обновление с сообщением", http.MethodPost, testSecret, messageUpdate, http.StatusOK, true},
+		{
+			name:       "обновление с нажатием кнопки",
+			method:     http.MethodPost,
+			secret:     testSecret,
+			body:       `{"update_id": 10, "callback_query": {"id": "q", "from": {"id": 7}, "message": {"chat": {"id": 5}, "message_id": 1}, "data": "1|menu"}}`,
+			wantStatus: http.StatusOK,
+			wantUpdate: true,
+		},
+		{"неподдерживаемое обновление", http.MethodPost, testSecret, `{"update_id": 10, "edited_message": {}}`, http.StatusOK, false},
+		{"нет секрета", http.MethodPost, "", messageUpdate, http.StatusForbidden, false},
+		{"неверный секрет", http.MethodPost, "чужой", messageUpdate, http.StatusForbidden, false},
+		{"секрет другой длины", http.MethodPost, testSecret + "x", messageUpdate, http.StatusForbidden, false},
+		{"некорректное тело", http.MethodPost, testSecret, `{"update_id": `, http.StatusBadRequest, false},
+		{"тело не JSON", http.MethodPost, testSecret, `update`, http.StatusBadRequest, false},
+		{"метод GET", http.MethodGet, testSecret, "", http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan types.Update, 1)
+			dispatcher := NewDispatcher(func(_ context.Context, update types.Update) {
+				received <- update
+			}, 1, 1)
+			api := NewAPI("token", WithRateLimiter(nil))
+			server := httptest.NewServer(api.webhookHandler(context.Background(), testSecret, dispatcher))
+			defer server.Close()
+
+			resp := webhookRequest(t, tt.method, server.URL, tt.secret, tt.body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("статус %d, ожидается %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			dispatcher.Stop()
+			dispatcher.Wait()
+			select {
+			case update := <-received:
+				if !tt.wantUpdate {
+					t.Errorf("обработчик получил обновление %+v", update)
+				} else if update.UpdateID != 10 || chatKey(update) != 5 {
+					t.Errorf("обработчик получил обновление %d из чата %d, ожидается 10 из чата 5", update.UpdateID, chatKey(update))
+				}
+			default:
+				if tt.wantUpdate {
+					t.Error("обновление не передано обработчику")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerWithoutSecret(t *testing.T) {
+	received := make(chan types.Update, 1)
+	dispatcher := NewDispatcher(func(_ context.Context, update types.Update) {
+		received <- update
+	}, 1, 1)
+	api := NewAPI("token", WithRateLimiter(nil))
+	server := httptest.NewServer(api.webhookHandler(context.Background(), "", dispatcher))
+	defer server.Close()
+
+	if resp := webhookRequest(t, http.MethodPost, server.URL, "", messageUpdate); resp.StatusCode != http.StatusOK {
+		t.Errorf("статус %d, ожидается %d", resp.StatusCode, http.StatusOK)
+	}
+	dispatcher.Stop()
+	dispatcher.Wait()
+	if len(received) != 1 {
+		t.Error("обновление не передано обработчику")
+	}
+}
+
+func TestWebhookHandlerQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	dispatcher := NewDispatcher(func(ctx context.Context, _ types.Update) {
+		close(started)
+		<-release
+		handlerErr <- ctx.Err()
+	}, 1, 1)
+	api := NewAPI("token", WithRateLimiter(nil))
+	server := httptest.NewServer(api.webhookHandler(context.Background(), testSecret, dispatcher))
+	defer server.Close()
+
+	if resp := webhookRequest(t, http.MethodPost, server.URL, testSecret, messageUpdate); resp.StatusCode != http.StatusOK {
+		t.Fatalf("статус %d, ожидается %d", resp.StatusCode, http.StatusOK)
+	}
+	waitFor(t, started, "запуск обработчика")
+
+	// Очередь заполнена: запрос не удерживается, а сразу отклоняется с Retry-After
+	start := time.Now()
+	resp := webhookRequest(t, http.MethodPost, server.URL, testSecret, messageUpdate)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("статус %d, ожидается %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("нет заголовка Retry-After")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ответ на запрос при заполненной очереди занял %s", elapsed)
+	}
+
+	// Ответ Telegram уже отправлен, но контекст обработчика не отменен
+	close(release)
+	dispatcher.Stop()
+	dispatcher.Wait()
+	if err := <-handlerErr; err != nil {
+		t.Errorf("контекст обработчика отменен после ответа: %v", err)
+	}
+
+	// После остановки диспетчера обновления отклоняются, и Telegram доставит их повторно
+	if resp := webhookRequest(t, http.MethodPost, server.URL, testSecret, messageUpdate); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("статус после остановки %d, ожидается %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
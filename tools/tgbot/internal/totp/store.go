@@ -0,0 +1,269 @@
+package totp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"tgbot/internal/storage"
+)
+
+// Ошибки проверки кодов
+var (
+	// ErrNotEnrolled у пользователя не подключен второй фактор
+	ErrNotEnrolled = errors.New("второй фактор не подключен")
+	// ErrAlreadyEnrolled второй фактор уже подключен
+	ErrAlreadyEnrolled = errors.New("второй фактор уже подключен")
+	// ErrInvalidCode код неверный или устарел
+	ErrInvalidCode = errors.New("неверный код")
+	// ErrCodeReused код уже был использован
+	ErrCodeReused = errors.New("код уже использован, дождитесь следующего")
+)
+
+// Ограничение подбора кодов: после каждых MaxFailures неверных кодов подряд ввод
+// кодов блокируется, и каждая следующая блокировка вдвое длиннее предыдущей
+const (
+	MaxFailures = 3
+	LockoutBase = 5 * time.Minute
+	LockoutMax  = 24 * time.Hour
+)
+
+// LockedError ввод кодов заблокирован после серии неверных кодов
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("слишком много неверных кодов, ввод заблокирован до %s", e.Until.Local().Format("02.01.2006 15:04"))
+}
+
+// Бакет и ключ, в которых хранятся секреты
+const (
+	storeBucket = "totp"
+	storeKey    = "users"
+)
+
+// Store секреты пользователей. Секрет сначала создается неактивным и включается,
+// только когда пользователь введет код из приложения: так бот убеждается, что
+// секрет сохранен. Для каждого пользователя запоминается интервал последнего
+// принятого кода, поэтому один и тот же код нельзя использовать дважды
+type Store struct {
+	mu    sync.Mutex
+	repo  *storage.Repository[map[int64]*enrollment]
+	users map[int64]*enrollment
+	now   func() time.Time
+}
+
+// enrollment секрет пользователя
+type enrollment struct {
+	Secret string `json:"secret"`
+	// Active секрет подтвержден кодом из приложения
+	Active bool `json:"active"`
+	// LastCounter интервал последнего принятого кода
+	LastCounter int64     `json:"last_counter"`
+	EnrolledAt  time.Time `json:"enrolled_at"`
+	// Failures неверные коды подряд; сбрасывается после верного кода
+	Failures int `json:"failures,omitempty"`
+	// LockedUntil до какого времени коды не принимаются
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// NewStore создает хранилище секретов в store
+func NewStore(store storage.Store) *Store {
+	return &Store{
+		repo:  storage.NewRepository[map[int64]*enrollment](store, storeBucket),
+		users: make(map[int64]*enrollment),
+		now:   time.Now,
+	}
+}
+
+// ImportFile переносит секреты из файла прежних версий бота
+func ImportFile(tx storage.Tx, path string) (bool, error) {
+	return storage.ImportFile(tx, storeBucket, storeKey, path)
+}
+
+// Load читает сохраненные секреты
+func (s *Store) Load() error {
+	users, err := s.repo.Get(storeKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения секретов: %w", err)
+	}
+	if users == nil {
+		users = make(map[int64]*enrollment)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = users
+	return nil
+}
+
+// Enrolled сообщает, подключен ли у пользователя второй фактор
+func (s *Store) Enrolled(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	return ok && user.Active
+}
+
+// Remaining возвращает, сколько неверных кодов пользователь может ввести до блокировки
+func (s *Store) Remaining(userID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	if !ok {
+		return MaxFailures
+	}
+	return MaxFailures - user.Failures%MaxFailures
+}
+
+// Locked возвращает время окончания блокировки, если ввод кодов пользователю заблокирован
+func (s *Store) Locked(userID int64) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userID]
+	if !ok || !s.now().Before(user.LockedUntil) {
+		return time.Time{}, false
+	}
+	return user.LockedUntil, true
+}
+
+// Enroll создает новый неактивный секрет пользователя и возвращает его. Ранее
+// созданный, но не подтвержденный секрет заменяется
+func (s *Store) Enroll(userID int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userID]; ok && user.Active {
+		return "", ErrAlreadyEnrolled
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	// Повторное подключение не снимает блокировку после неверных кодов
+	next := &enrollment{Secret: secret, EnrolledAt: s.now()}
+	if user, ok := s.users[userID]; ok {
+		next.Failures, next.LockedUntil = user.Failures, user.LockedUntil
+	}
+	s.users[userID] = next
+	if err := s.save(); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Activate включает созданный секрет, если код из приложения верный
+func (s *Store) Activate(userID int64, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotEnrolled
+	}
+	if user.Active {
+		return ErrAlreadyEnrolled
+	}
+	if err := s.check(user, code); err != nil {
+		return err
+	}
+	user.Active = true
+	return s.save()
+}
+
+// Verify проверяет код пользователя с подключенным вторым фактором. Принятый код
+// и все более ранние коды больше не принимаются
+func (s *Store) Verify(userID int64, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || !user.Active {
+		return ErrNotEnrolled
+	}
+	if err := s.check(user, code); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Disable отключает второй фактор после проверки кода
+func (s *Store) Disable(userID int64, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || !user.Active {
+		return ErrNotEnrolled
+	}
+	if err := s.check(user, code); err != nil {
+		return err
+	}
+	delete(s.users, userID)
+	return s.save()
+}
+
+// Reset удаляет секрет пользователя без проверки кода, например если телефон потерян
+func (s *Store) Reset(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return ErrNotEnrolled
+	}
+	delete(s.users, userID)
+	return s.save()
+}
+
+// check проверяет код и запоминает его интервал; вызывается под s.mu. Неверные
+// коды считаются для пользователя, а не для отдельного запроса кода, и сохраняются
+// сразу, поэтому новый запуск релиза или перезапуск бота не дают новых попыток
+func (s *Store) check(user *enrollment, code string) error {
+	now := s.now()
+	if now.Before(user.LockedUntil) {
+		return &LockedError{Until: user.LockedUntil}
+	}
+
+	counter, ok := Validate(user.Secret, code, now)
+	if !ok {
+		user.Failures++
+		var err error = ErrInvalidCode
+		if user.Failures%MaxFailures == 0 {
+			user.LockedUntil = now.Add(lockout(user.Failures / MaxFailures))
+			err = &LockedError{Until: user.LockedUntil}
+		}
+		if saveErr := s.save(); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+	if counter <= user.LastCounter {
+		return ErrCodeReused
+	}
+	user.LastCounter = counter
+	user.Failures = 0
+	user.LockedUntil = time.Time{}
+	return nil
+}
+
+// lockout возвращает длительность n-й блокировки подряд
+func lockout(n int) time.Duration {
+	duration := LockoutBase
+	for i := 1; i < n && duration < LockoutMax; i++ {
+		duration *= 2
+	}
+	return min(duration, LockoutMax)
+}
+
+// save сохраняет секреты в хранилище; вызывается под s.mu
+func (s *Store) save() error {
+	if err := s.repo.Put(storeKey, s.users); err != nil {
+		return fmt.Errorf("ошибка сохранения секретов: %w", err)
+	}
+	return nil
+}
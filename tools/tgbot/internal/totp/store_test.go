@@ -0,0 +1,128 @@
+package totp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tgbot/internal/storage"
+)
+
+// enrolledStore возвращает хранилище с подключенным вторым фактором пользователя userID
+// и часами, которые тест может переводить
+func enrolledStore(t *testing.T, backend storage.Store, userID int64, now *time.Time) (*Store, string) {
+	t.Helper()
+	store := NewStore(backend)
+	store.now = func() time.Time { return *now }
+	secret, err := store.Enroll(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Activate(userID, mustCode(t, secret, *now)); err != nil {
+		t.Fatal(err)
+	}
+	return store, secret
+}
+
+func mustCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := Code(secret, Counter(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// wrongCode возвращает код, который не совпадает с кодом ни одного интервала вокруг at
+func wrongCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	for _, candidate := range []string{"000000", "111111", "222222", "333333"} {
+		if _, ok := Validate(secret, candidate, at); !ok {
+			return candidate
+		}
+	}
+	t.Fatal("не удалось подобрать неверный код")
+	return ""
+}
+
+func TestStoreLockoutSurvivesNewChallengesAndRestart(t *testing.T) {
+	const userID = 42
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	backend := storage.NewMemory()
+	store, secret := enrolledStore(t, backend, userID, &now)
+
+	// Каждый неверный код уменьшает число оставшихся попыток, независимо от того,
+	// сколько раз пользователь начинал запуск релиза
+	for i := 1; i < MaxFailures; i++ {
+		now = now.Add(Period)
+		if err := store.Verify(userID, wrongCode(t, secret, now)); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("попытка %d: ошибка %v, ожидается ErrInvalidCode", i, err)
+		}
+		if remaining := store.Remaining(userID); remaining != MaxFailures-i {
+			t.Fatalf("попытка %d: осталось %d попыток, ожидается %d", i, remaining, MaxFailures-i)
+		}
+	}
+
+	now = now.Add(Period)
+	var locked *LockedError
+	if err := store.Verify(userID, wrongCode(t, secret, now)); !errors.As(err, &locked) {
+		t.Fatalf("ошибка %v, ожидается LockedError", err)
+	}
+	if want := now.Add(LockoutBase); !locked.Until.Equal(want) {
+		t.Fatalf("блокировка до %v, ожидается %v", locked.Until, want)
+	}
+
+	// Блокировка сохраняется в хранилище и действует после перезапуска даже для верного кода
+	restarted := NewStore(backend)
+	restarted.now = func() time.Time { return now }
+	if err := restarted.Load(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(Period)
+	if err := restarted.Verify(userID, mustCode(t, secret, now)); !errors.As(err, &locked) {
+		t.Fatalf("после перезапуска: ошибка %v, ожидается LockedError", err)
+	}
+
+	// Следующая серия неверных кодов блокирует вдвое дольше
+	now = locked.Until
+	for i := 1; i <= MaxFailures; i++ {
+		now = now.Add(Period)
+		err := restarted.Verify(userID, wrongCode(t, secret, now))
+		if i < MaxFailures {
+			continue
+		}
+		if !errors.As(err, &locked) {
+			t.Fatalf("вторая серия: ошибка %v, ожидается LockedError", err)
+		}
+		if want := now.Add(2 * LockoutBase); !locked.Until.Equal(want) {
+			t.Fatalf("вторая блокировка до %v, ожидается %v", locked.Until, want)
+		}
+	}
+
+	// После окончания блокировки верный код принимается и сбрасывает счетчик
+	now = locked.Until.Add(Period)
+	if err := restarted.Verify(userID, mustCode(t, secret, now)); err != nil {
+		t.Fatalf("верный код после блокировки: %v", err)
+	}
+	if remaining := restarted.Remaining(userID); remaining != MaxFailures {
+		t.Fatalf("после верного кода осталось %d попыток, ожидается %d", remaining, MaxFailures)
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, LockoutBase},
+		{2, 2 * LockoutBase},
+		{3, 4 * LockoutBase},
+		{20, LockoutMax},
+		{1000, LockoutMax},
+	}
+	for _, tt := range tests {
+		if got := lockout(tt.n); got != tt.want {
+			t.Errorf("lockout(%d) = %v, ожидается %v", tt.n, got, tt.want)
+		}
+	}
+}
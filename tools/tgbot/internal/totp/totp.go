@@ -0,0 +1,117 @@
+// Package totp реализует одноразовые коды по времени (RFC 6238), совместимые с
+// Google Authenticator и другими приложениями-аутентификаторами
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period время действия одного кода
+	Period = 30 * time.Second
+	// Digits число цифр в коде
+	Digits = 6
+	// Skew сколько соседних интервалов принимается при расхождении часов
+	Skew = 1
+	// secretSize размер секрета в байтах (160 бит, как рекомендует RFC 4226)
+	secretSize = 20
+)
+
+// encoding base32 без выравнивания, который понимают приложения-аутентификаторы
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret создает случайный секрет в кодировке base32
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("ошибка генерации секрета: %w", err)
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// Counter возвращает номер интервала для момента t
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code возвращает код для секрета secret и интервала counter
+func Code(secret string, counter int64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, counter), nil
+}
+
+// Validate проверяет код на момент t с допуском Skew интервалов в обе стороны.
+// Возвращает номер интервала, которому соответствует код, чтобы вызывающий мог
+// запретить его повторное использование
+func Validate(secret, value string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if len(value) != Digits {
+		return 0, false
+	}
+
+	current := Counter(t)
+	for offset := int64(-Skew); offset <= Skew; offset++ {
+		counter := current + offset
+		if hmac.Equal([]byte(code(key, counter)), []byte(value)) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// URI возвращает ссылку otpauth:// для добавления секрета в приложение-аутентификатор
+func URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// code вычисляет код по алгоритму HOTP (RFC 4226) для интервала counter
+func code(key []byte, counter int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
+
+// decodeSecret декодирует секрет base32, допуская строчные буквы, пробелы и выравнивание
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования секрета: %w", err)
+	}
+	return key, nil
+}
@@ -1,6 +1,14 @@
 // Package types содержит общие типы данных, используемые в приложении
 package types
 
+import "context"
+
+// Режимы получения обновлений
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+)
+
 // BotConfig конфигурация бота
 type BotConfig struct {
 	AllowedUserIDs []int64 `json:"allowed_user_ids"`
@@ -9,16 +17,89 @@ type BotConfig struct {
 	GitHubToken    string  `json:"github_token"`
 	GitHubOwner    string  `json:"github_owner"`
 	GitHubRepo     string  `json:"github_repo"`
+
+	// Файлы, из которых читаются секреты, чтобы не хранить их в файле конфигурации
+	// (например, смонтированные секреты /run/secrets/...). Секрет задается либо
+	// значением, либо файлом
+	TgBotKeyFile       string `json:"bot_key_file"`
+	GitHubTokenFile    string `json:"github_token_file"`
+	WebhookSecretFile  string `json:"webhook_secret_file"`
+	CallbackSecretFile string `json:"callback_secret_file"`
+
+	// UpdateMode режим получения обновлений: polling (по умолчанию) или webhook
+	UpdateMode        string `json:"update_mode"`
+	WebhookURL        string `json:"webhook_url"`
+	WebhookListenAddr string `json:"webhook_listen_addr"`
+	WebhookSecret     string `json:"webhook_secret"`
+	WebhookCertFile   string `json:"webhook_cert_file"`
+	WebhookKeyFile    string `json:"webhook_key_file"`
+
+	// ShutdownTimeoutSeconds время ожидания завершения обработчиков при остановке
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+	// Workers число параллельно обрабатываемых обновлений (обновления одного чата обрабатываются по очереди)
+	Workers int `json:"workers"`
+	// QueueSize максимальное число обновлений, ожидающих обработки
+	QueueSize int `json:"queue_size"`
+
+	// StateFile файл смещения обновлений прежних версий бота; переносится в хранилище при первом запуске
+	StateFile string `json:"state_file"`
+	// SkipBacklog пропускать обновления, накопившиеся, пока бот был остановлен
+	SkipBacklog bool `json:"skip_backlog"`
+
+	// CallbackSecret секрет для подписи данных inline-кнопок. Если не задан, секрет
+	// генерируется при запуске и кнопки старых сообщений перестают работать после перезапуска
+	CallbackSecret string `json:"callback_secret"`
+	// CallbackTTLHours срок действия inline-кнопок в часах
+	CallbackTTLHours int `json:"callback_ttl_hours"`
+
+	// UserRatePerMinute сколько обновлений в минуту принимается от одного пользователя
+	UserRatePerMinute int `json:"user_rate_per_minute"`
+
+	// DefaultRole роль пользователей из allowed_user_ids, которым роль не назначена явно
+	DefaultRole string `json:"default_role"`
+	// Roles назначения ролей пользователям
+	Roles []RoleAssignment `json:"roles"`
+	// ReleaseApprovals сколько релиз-менеджеров должны одобрить запуск релиза; 0 — одобрение не требуется
+	ReleaseApprovals int `json:"release_approvals"`
+	// ReleaseChatID чат, в который отправляются запросы на одобрение релиза; 0 — чат инициатора
+	ReleaseChatID int64 `json:"release_chat_id"`
+	// ReleaseApprovalTimeoutMinutes время на сбор одобрений, после которого запрос отменяется
+	ReleaseApprovalTimeoutMinutes int `json:"release_approval_timeout_minutes"`
+	// ReleaseRequireTOTP запускать релизы могут только пользователи с подключенным вторым фактором
+	ReleaseRequireTOTP bool `json:"release_require_totp"`
+
+	// StorageFile файл хранилища состояния бота: смещение обновлений, изменения доступа,
+	// секреты второго фактора. Если не задан, состояние хранится в памяти до перезапуска
+	StorageFile string `json:"storage_file"`
+	// AccessFile файл изменений доступа прежних версий бота; переносится в хранилище при первом запуске
+	AccessFile string `json:"access_file"`
+	// TOTPFile файл секретов второго фактора прежних версий бота; переносится в хранилище при первом запуске
+	TOTPFile string `json:"totp_file"`
+	// AuditFile файл журнала аудита привилегированных действий
+	AuditFile string `json:"audit_file"`
+}
+
+// RoleAssignment назначение роли пользователю
+type RoleAssignment struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+	// ChatID если задан, роль действует только в этом чате
+	ChatID int64 `json:"chat_id,omitempty"`
 }
 
+// UpdateHandler обработчик обновлений. Контекст отменяется, если обработчик
+// не успел завершиться за отведенное при остановке бота время
+type UpdateHandler func(ctx context.Context, update Update)
+
 // BotAPI интерфейс для работы с API бота
 type BotAPI interface {
-	SendMessage(chatID int64, text string, buttons []InlineButton) error
-	HandleUpdates(handler func(update Update)) error
+	SendMessage(ctx context.Context, chatID int64, text string, buttons []InlineButton) error
+	HandleUpdates(ctx context.Context, handler UpdateHandler) error
 }
 
 // Update структура обновления от бота
 type Update struct {
+	UpdateID      int64          `json:"update_id"`
 	Message       *Message       `json:"message,omitempty"`
 	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
 }
@@ -31,6 +112,12 @@ type Message struct {
 	UserID    int64  `json:"user_id,omitempty"`
 	From      *User  `json:"from,omitempty"`
 	Chat      *Chat  `json:"chat"`
+	// ReplyTo сообщение, на которое отвечает это сообщение
+	ReplyTo *Message `json:"reply_to_message,omitempty"`
+	// Forwarded сообщение переслано из другого чата
+	Forwarded bool `json:"forwarded,omitempty"`
+	// ForwardFrom автор пересланного сообщения, если он не скрыл свой аккаунт
+	ForwardFrom *User `json:"forward_from,omitempty"`
 }
 
 // CallbackQuery представляет callback-запрос от встроенной клавиатуры
@@ -63,6 +150,12 @@ type InlineKeyboardButton struct {
 	URL          string `json:"url,omitempty"`
 }
 
+// BotCommand команда в меню бота Telegram
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
 // Asset представляет файл, прикрепленный к релизу
 type Asset struct {
 	Name        string `json:"name"`
@@ -79,26 +172,26 @@ type Response struct {
 
 // SendMessageRequest представляет запрос на отправку сообщения
 type SendMessageRequest struct {
-	ChatID      int64               `json:"chat_id"`
-	Text        string              `json:"text"`
-	ParseMode   string              `json:"parse_mode,omitempty"`
+	ChatID      int64                `json:"chat_id"`
+	Text        string               `json:"text"`
+	ParseMode   string               `json:"parse_mode,omitempty"`
 	ReplyMarkup InlineKeyboardMarkup `json:"reply_markup,omitempty"`
 }
 
 // EditMessageTextRequest представляет запрос на редактирование сообщения
 type EditMessageTextRequest struct {
-	ChatID      int64               `json:"chat_id"`
-	MessageID   int                 `json:"message_id"`
-	Text        string              `json:"text"`
-	ParseMode   string              `json:"parse_mode,omitempty"`
+	ChatID      int64                `json:"chat_id"`
+	MessageID   int                  `json:"message_id"`
+	Text        string               `json:"text"`
+	ParseMode   string               `json:"parse_mode,omitempty"`
 	ReplyMarkup InlineKeyboardMarkup `json:"reply_markup,omitempty"`
 }
 
 // AnswerCallbackQueryRequest представляет запрос на ответ callback-запроса
 type AnswerCallbackQueryRequest struct {
 	CallbackQueryID string `json:"callback_query_id"`
-	Text           string `json:"text,omitempty"`
-	ShowAlert      bool   `json:"show_alert,omitempty"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
 }
 
 // InlineKeyboardMarkup представляет разметку встроенной клавиатуры
@@ -109,6 +202,7 @@ type InlineKeyboardMarkup struct {
 // User представляет пользователя в Telegram
 type User struct {
 	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot,omitempty"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name,omitempty"`
 	Username  string `json:"username,omitempty"`
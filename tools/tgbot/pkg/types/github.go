@@ -1,11 +1,22 @@
 package types
 
+import "fmt"
+
 // Branch информация о ветке
 type Branch struct {
 	Name   string `json:"name"`
 	Commit struct {
 		SHA string `json:"sha"`
+		// Commit заполняется только при запросе отдельной ветки
+		Commit struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string `json:"name"`
+				Date string `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
 	} `json:"commit"`
+	Protected bool `json:"protected"`
 }
 
 // PullRequest информация о pull request
@@ -15,9 +26,16 @@ type PullRequest struct {
 	State     string `json:"state"`
 	HTMLURL   string `json:"html_url"`
 	CreatedAt string `json:"created_at"`
+	Draft     bool   `json:"draft"`
 	User      struct {
 		Login string `json:"login"`
 	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
 }
 
 // Release информация о релизе
@@ -31,9 +49,50 @@ type Release struct {
 	Assets      []Asset `json:"assets"`
 }
 
+// Commit информация о коммите
+type Commit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string `json:"name"`
+			Date string `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// Comparison результат сравнения двух веток
+type Comparison struct {
+	Status       string   `json:"status"`
+	AheadBy      int      `json:"ahead_by"`
+	BehindBy     int      `json:"behind_by"`
+	TotalCommits int      `json:"total_commits"`
+	HTMLURL      string   `json:"html_url"`
+	Commits      []Commit `json:"commits"`
+}
+
+// Version версия приложения из .github/version.json
+type Version struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// String возвращает версию в виде major.minor.patch
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
 // GitHubAPI интерфейс для работы с GitHub API
 type GitHubAPI interface {
 	GetBranches() ([]Branch, error)
 	GetPullRequests() ([]PullRequest, error)
+	GetBranch(name string) (*Branch, error)
+	GetPullRequest(number int) (*PullRequest, error)
+	GetReleases() ([]Release, error)
+	GetReleaseByTag(tag string) (*Release, error)
 	GetLatestRelease() (*Release, error)
+	CompareBranches(base, head string) (*Comparison, error)
+	GetVersion(ref string) (*Version, error)
 }